@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"ride-sharing/services/api-gateway/grpc_clients"
+	"ride-sharing/shared/auth"
+	"ride-sharing/shared/contracts"
+	pb "ride-sharing/shared/proto/auth"
+	"time"
+)
+
+// how often the gateway retries fetching the JWKS while it has none
+const jwksRetryInterval = 5 * time.Second
+
+func handleSignup(w http.ResponseWriter, r *http.Request, authService *grpc_clients.AuthServiceClient) {
+	var request signupRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+
+	resp, err := authService.Client.Signup(r.Context(), request.ToProto())
+	if err != nil {
+		writeBackendError(w, "failed to sign up", err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, contracts.APIResponse{Data: resp})
+}
+
+func handleLogin(w http.ResponseWriter, r *http.Request, authService *grpc_clients.AuthServiceClient) {
+	var request loginRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+
+	resp, err := authService.Client.Login(r.Context(), request.ToProto())
+	if err != nil {
+		writeBackendError(w, "failed to log in", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, contracts.APIResponse{Data: resp})
+}
+
+func handleRefreshToken(w http.ResponseWriter, r *http.Request, authService *grpc_clients.AuthServiceClient) {
+	var request refreshTokenRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+
+	resp, err := authService.Client.RefreshToken(r.Context(), request.ToProto())
+	if err != nil {
+		writeBackendError(w, "failed to refresh token", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, contracts.APIResponse{Data: resp})
+}
+
+func handleLogout(w http.ResponseWriter, r *http.Request, authService *grpc_clients.AuthServiceClient) {
+	var request logoutRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+
+	if _, err := authService.Client.RevokeToken(r.Context(), request.ToProto()); err != nil {
+		writeBackendError(w, "failed to log out", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleJWKS serves the public keys of the auth service to other verifiers.
+func handleJWKS(w http.ResponseWriter, r *http.Request, authService *grpc_clients.AuthServiceClient) {
+	resp, err := authService.Client.GetJWKS(r.Context(), &pb.GetJWKSRequest{})
+	if err != nil {
+		writeBackendError(w, "failed to get the key set", err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	writeJSON(w, http.StatusOK, jwksFromProto(resp))
+}
+
+// refreshJWKS keeps the RS256 keys of the key set in sync with the auth
+// service, so tokens signed with a rotated key are accepted without restart.
+func refreshJWKS(ctx context.Context, keys *auth.KeySet, client pb.AuthServiceClient, interval time.Duration) {
+	for {
+		wait := interval
+
+		resp, err := client.GetJWKS(ctx, &pb.GetJWKSRequest{})
+		if err == nil {
+			err = keys.SetJWKS(jwksFromProto(resp))
+		}
+		if err != nil {
+			log.Printf("failed to refresh the JWKS: %v", err)
+			wait = jwksRetryInterval
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+func jwksFromProto(resp *pb.JWKSResponse) auth.JWKS {
+	jwks := auth.JWKS{Keys: make([]auth.JWK, 0, len(resp.GetKeys()))}
+	for _, key := range resp.GetKeys() {
+		jwks.Keys = append(jwks.Keys, auth.JWK{
+			Kty: key.GetKty(),
+			Use: key.GetUse(),
+			Alg: key.GetAlg(),
+			Kid: key.GetKid(),
+			N:   key.GetN(),
+			E:   key.GetE(),
+		})
+	}
+
+	return jwks
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"ride-sharing/shared/contracts"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	roleRider  = "rider"
+	roleDriver = "driver"
+
+	// time allowed to write a message to the peer
+	wsWriteWait = 10 * time.Second
+	// time allowed to read the next pong message from the peer
+	wsPongWait = 60 * time.Second
+	// send pings to the peer with this period, must be less than wsPongWait
+	wsPingPeriod = (wsPongWait * 9) / 10
+)
+
+var ErrConnectionNotFound = errors.New("no websocket connection for user")
+
+// wsConnection is a websocket of a connected user. Writes are serialized, since
+// gorilla/websocket supports only one concurrent writer per connection.
+type wsConnection struct {
+	conn   *websocket.Conn
+	userID string
+	role   string
+
+	writeMu sync.Mutex
+	done    chan struct{}
+}
+
+func (c *wsConnection) WriteJSON(v any) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return c.conn.WriteJSON(v)
+}
+
+func (c *wsConnection) ReadMessage() (int, []byte, error) {
+	return c.conn.ReadMessage()
+}
+
+func (c *wsConnection) Close() error {
+	return c.conn.Close()
+}
+
+// keepAlive pings the peer until the connection is removed from the manager.
+// A peer that stops answering hits the read deadline and its read loop fails.
+func (c *wsConnection) keepAlive() {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				log.Printf("failed to ping %s %s: %v", c.role, c.userID, err)
+				return
+			}
+		}
+	}
+}
+
+// ConnectionManager keeps track of the websockets connected to this gateway,
+// keyed by user and role, so other components can push messages to users.
+type ConnectionManager struct {
+	mu          sync.RWMutex
+	connections map[string]map[string]*wsConnection
+}
+
+func NewConnectionManager() *ConnectionManager {
+	return &ConnectionManager{
+		connections: make(map[string]map[string]*wsConnection),
+	}
+}
+
+// Add registers the websocket of a user and starts its keepalive. A previous
+// connection of the same user and role is closed and replaced.
+func (m *ConnectionManager) Add(userID, role string, conn *websocket.Conn) *wsConnection {
+	c := &wsConnection{
+		conn:   conn,
+		userID: userID,
+		role:   role,
+		done:   make(chan struct{}),
+	}
+
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	m.mu.Lock()
+	if m.connections[userID] == nil {
+		m.connections[userID] = make(map[string]*wsConnection)
+	}
+	previous := m.connections[userID][role]
+	m.connections[userID][role] = c
+	m.mu.Unlock()
+
+	if previous != nil {
+		log.Printf("replacing %s connection of user %s", role, userID)
+		close(previous.done)
+		previous.Close()
+	}
+
+	go c.keepAlive()
+	return c
+}
+
+// Remove unregisters the connection, unless it was already replaced by a newer
+// connection of the same user and role. It reports whether c was the live
+// connection of its user and role.
+func (m *ConnectionManager) Remove(c *wsConnection) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	roles := m.connections[c.userID]
+	if roles[c.role] != c {
+		return false
+	}
+
+	close(c.done)
+	delete(roles, c.role)
+	if len(roles) == 0 {
+		delete(m.connections, c.userID)
+	}
+
+	return true
+}
+
+// SendToUser writes the message to every websocket of the user.
+func (m *ConnectionManager) SendToUser(userID string, msg contracts.WSMessage) error {
+	m.mu.RLock()
+	conns := make([]*wsConnection, 0, len(m.connections[userID]))
+	for _, c := range m.connections[userID] {
+		conns = append(conns, c)
+	}
+	m.mu.RUnlock()
+
+	if len(conns) == 0 {
+		return ErrConnectionNotFound
+	}
+
+	var errs []error
+	for _, c := range conns {
+		if err := c.WriteJSON(msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
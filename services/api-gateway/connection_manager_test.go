@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"ride-sharing/shared/contracts"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// connected reports whether the user has a registered websocket for role.
+func connected(m *ConnectionManager, userID, role string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.connections[userID][role] != nil
+}
+
+// socketPair opens a websocket to a test server and returns its server side
+// and the client side.
+func socketPair(t *testing.T) (server, client *websocket.Conn) {
+	t.Helper()
+
+	accepted := make(chan *websocket.Conn, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		accepted <- conn
+	}))
+	t.Cleanup(srv.Close)
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	server = <-accepted
+	t.Cleanup(func() { server.Close() })
+
+	return server, client
+}
+
+// TestConnectionManagerReplace reconnects a user, as after a network blip, and
+// checks the old socket is closed and its late removal keeps the new one.
+func TestConnectionManagerReplace(t *testing.T) {
+	m := NewConnectionManager()
+
+	oldServer, oldClient := socketPair(t)
+	newServer, newClient := socketPair(t)
+	driverServer, _ := socketPair(t)
+
+	old := m.Add("user-1", roleRider, oldServer)
+	live := m.Add("user-1", roleRider, newServer)
+	driver := m.Add("user-1", roleDriver, driverServer)
+
+	oldClient.SetReadDeadline(time.Now().Add(time.Second))
+	if _, _, err := oldClient.ReadMessage(); err == nil {
+		t.Fatal("replaced socket still open")
+	}
+	select {
+	case <-old.done:
+	default:
+		t.Error("keepalive of the replaced socket not stopped")
+	}
+
+	// the handler of the old socket returns after the replacement
+	if m.Remove(old) {
+		t.Error("Remove(replaced) = true, want false")
+	}
+	if !connected(m, "user-1", roleRider) || !connected(m, "user-1", roleDriver) {
+		t.Fatal("removing the replaced socket unregistered the user")
+	}
+
+	msg := contracts.WSMessage{Type: contracts.TripEventNoDriversFound}
+	if err := m.SendToUser("user-1", msg); err != nil {
+		t.Fatalf("SendToUser() error = %v", err)
+	}
+	var got contracts.WSMessage
+	newClient.SetReadDeadline(time.Now().Add(time.Second))
+	if err := newClient.ReadJSON(&got); err != nil || got.Type != msg.Type {
+		t.Fatalf("new socket received %v, %v, want %s", got.Type, err, msg.Type)
+	}
+
+	if !m.Remove(live) {
+		t.Error("Remove(live) = false, want true")
+	}
+	if connected(m, "user-1", roleRider) {
+		t.Error("rider socket still registered after its removal")
+	}
+	if !m.Remove(driver) {
+		t.Error("Remove(driver) = false, want true")
+	}
+	if err := m.SendToUser("user-1", msg); !errors.Is(err, ErrConnectionNotFound) {
+		t.Errorf("SendToUser() after the removals error = %v, want %v", err, ErrConnectionNotFound)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+
+	"google.golang.org/grpc/codes"
+)
+
+// publishDriverCommand validates a trip response or location command sent by
+// a driver and publishes it to the trip exchange with the driver as owner.
+func publishDriverCommand(ctx context.Context, rabbitmq messaging.Broker, driverID string, message contracts.WSDriverMessage) error {
+	var data any
+
+	switch message.Type {
+	case contracts.DriverCmdTripAccept, contracts.DriverCmdTripDecline, contracts.DriverCmdTripCancel:
+		var payload messaging.DriverTripResponseData
+		if err := json.Unmarshal(message.Data, &payload); err != nil {
+			return invalidDriverCommand("fail to parse trip response data")
+		}
+		if err := validateTripResponse(driverID, payload); err != nil {
+			return err
+		}
+		data = payload
+
+	case contracts.DriverCmdLocation:
+		var payload messaging.DriverLocationData
+		if err := json.Unmarshal(message.Data, &payload); err != nil {
+			return invalidDriverCommand("fail to parse location data")
+		}
+		if err := validateLocation(payload); err != nil {
+			return err
+		}
+		data = payload
+
+	default:
+		return invalidDriverCommand(fmt.Sprintf("unknown message type %q", message.Type))
+	}
+
+	return messaging.Publish(ctx, rabbitmq, message.Type, driverID, data)
+}
+
+func validateTripResponse(driverID string, payload messaging.DriverTripResponseData) error {
+	var errs fieldErrors
+	errs.required("tripID", payload.TripID)
+	errs.required("riderID", payload.RiderID)
+	if payload.Driver == nil {
+		errs.add("driver", "is required")
+	}
+	if len(errs) > 0 {
+		return invalidDriverFields(errs)
+	}
+
+	if payload.Driver.Id != driverID {
+		return &driverCommandError{code: codes.PermissionDenied, message: "driver does not match the connected driver"}
+	}
+
+	return nil
+}
+
+func validateLocation(payload messaging.DriverLocationData) error {
+	var errs fieldErrors
+	errs.coordinate("location", payload.Location.Latitude, payload.Location.Longitude)
+	if len(errs) > 0 {
+		return invalidDriverFields(errs)
+	}
+
+	return nil
+}
+
+// driverCommandError is a rejected driver command, reported back to the driver
+// as an error frame.
+type driverCommandError struct {
+	code    codes.Code
+	message string
+	details []contracts.FieldError
+}
+
+func (e *driverCommandError) Error() string {
+	return e.message
+}
+
+func invalidDriverCommand(message string) error {
+	return &driverCommandError{code: codes.InvalidArgument, message: message}
+}
+
+func invalidDriverFields(details []contracts.FieldError) error {
+	return &driverCommandError{code: codes.InvalidArgument, message: "command validation failed", details: details}
+}
+
+// handleDriverCommand forwards the command and answers bad input with an
+// error frame instead of dropping the connection.
+func handleDriverCommand(ctx context.Context, conn *wsConnection, rabbitmq messaging.Broker, driverID string, message contracts.WSDriverMessage) {
+	err := publishDriverCommand(ctx, rabbitmq, driverID, message)
+	if err == nil {
+		return
+	}
+
+	var cmdErr *driverCommandError
+	if errors.As(err, &cmdErr) {
+		writeWSError(conn, cmdErr.code, cmdErr.message, cmdErr.details...)
+		return
+	}
+
+	log.Printf("failed to publish %s for driver %s: %v", message.Type, driverID, err)
+	writeWSError(conn, codes.Unavailable, "failed to forward the message")
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"ride-sharing/shared/contracts"
+
+	"google.golang.org/grpc/status"
+)
+
+// writeError answers with an APIResponse carrying only the error.
+func writeError(w http.ResponseWriter, httpStatus int, code, message string, details ...contracts.FieldError) {
+	writeJSON(w, httpStatus, contracts.APIResponse{
+		Error: &contracts.APIError{
+			Code:    code,
+			Message: message,
+			Details: details,
+		},
+	})
+}
+
+// writeBackendError reports a failed gRPC call. Errors the caller can act on
+// keep the message of the service, the others are logged and replaced by
+// message.
+func writeBackendError(w http.ResponseWriter, message string, err error) {
+	st := status.Convert(err)
+	httpStatus, code := contracts.HTTPError(st.Code())
+
+	if !contracts.IsClientError(st.Code()) {
+		log.Printf("%s: %v", message, err)
+		writeError(w, httpStatus, code, message)
+		return
+	}
+
+	writeError(w, httpStatus, code, st.Message(), contracts.FieldErrors(st)...)
+}
+
+func writeValidationError(w http.ResponseWriter, fields []contracts.FieldError) {
+	writeError(w, http.StatusBadRequest, contracts.ErrCodeInvalidArgument, "request validation failed", fields...)
+}
+
+// decodeJSON reads the request body into v, answering malformed bodies itself.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	defer r.Body.Close()
+
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, contracts.ErrCodeMalformedRequest, "fail to parse JSON data")
+		return false
+	}
+
+	return true
+}
+
+// fieldErrors collects the validation failures of a request.
+type fieldErrors []contracts.FieldError
+
+func (f *fieldErrors) add(field, message string) {
+	*f = append(*f, contracts.FieldError{Field: field, Message: message})
+}
+
+func (f *fieldErrors) required(field, value string) {
+	if value == "" {
+		f.add(field, "is required")
+	}
+}
+
+func (f *fieldErrors) coordinate(field string, latitude, longitude float64) {
+	if latitude < -90 || latitude > 90 {
+		f.add(field+".latitude", "must be between -90 and 90")
+	}
+	if longitude < -180 || longitude > 180 {
+		f.add(field+".longitude", "must be between -180 and 180")
+	}
+}
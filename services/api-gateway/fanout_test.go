@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"ride-sharing/shared/auth"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	"ride-sharing/shared/ratelimit"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestFanOut runs two gateway replicas on one broker, each holding the
+// websocket of a rider, and checks every notification of the shared queue
+// reaches its rider whatever replica consumes it.
+func TestFanOut(t *testing.T) {
+	const messages = 20
+
+	broker := messaging.NewMemoryBroker(messaging.APIGatewayService)
+	defer broker.Close()
+
+	var riders []*websocket.Conn
+	for i := range 2 {
+		replica := startReplica(t, broker)
+		riders = append(riders, connectRider(t, replica, fmt.Sprintf("rider-%d", i)))
+	}
+
+	ctx := context.Background()
+	for range messages {
+		for i := range riders {
+			err := broker.PublishMessage(ctx, contracts.TripEventNoDriversFound, contracts.AmqpMessage{OwnerID: fmt.Sprintf("rider-%d", i)})
+			if err != nil {
+				t.Fatalf("PublishMessage() error = %v", err)
+			}
+		}
+	}
+
+	for i, conn := range riders {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		for received := range messages {
+			var msg contracts.WSMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				t.Fatalf("rider-%d received %d/%d notifications: %v", i, received, messages, err)
+			}
+			if msg.Type != contracts.TripEventNoDriversFound {
+				t.Fatalf("rider-%d received %s, want %s", i, msg.Type, contracts.TripEventNoDriversFound)
+			}
+		}
+	}
+
+	if err := broker.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+}
+
+type gatewayReplica struct {
+	connManager *ConnectionManager
+	server      *httptest.Server
+}
+
+// startReplica consumes the shared notification queue and a broadcast queue of
+// its own as main does, and serves rider websockets for the user named by
+// ?user, standing in for the authentication.
+func startReplica(t *testing.T, broker messaging.Broker) *gatewayReplica {
+	t.Helper()
+
+	limiter, err := newRateLimiter(ratelimit.NewMemoryStore(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	connManager := NewConnectionManager()
+	if err := NewQueueConsumer(broker, connManager, messaging.NotifyDriverNoDriversFoundQueue).Start(); err != nil {
+		t.Fatalf("consume %s: %v", messaging.NotifyDriverNoDriversFoundQueue, err)
+	}
+
+	broadcastQueue, err := broker.DeclareBroadcastQueue()
+	if err != nil {
+		t.Fatalf("DeclareBroadcastQueue() error = %v", err)
+	}
+	if err := NewBroadcastConsumer(broker, connManager, broadcastQueue).Start(); err != nil {
+		t.Fatalf("consume %s: %v", broadcastQueue, err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity := auth.Identity{UserID: r.URL.Query().Get("user"), Role: auth.RoleRider}
+		handleRiderWs(w, r.WithContext(auth.NewContext(r.Context(), identity)), connManager, limiter)
+	}))
+	t.Cleanup(server.Close)
+
+	return &gatewayReplica{connManager: connManager, server: server}
+}
+
+func connectRider(t *testing.T, replica *gatewayReplica, riderID string) *websocket.Conn {
+	t.Helper()
+
+	url := "ws" + strings.TrimPrefix(replica.server.URL, "http") + "/ws/riders?user=" + riderID
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("connect %s: %v", riderID, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	// the socket is registered right after the upgrade
+	for deadline := time.Now().Add(time.Second); !connected(replica.connManager, riderID, roleRider); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s never registered", riderID)
+		}
+	}
+
+	return conn
+}
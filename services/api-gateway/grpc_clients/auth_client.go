@@ -0,0 +1,42 @@
+package grpc_clients
+
+import (
+	"os"
+	pb "ride-sharing/shared/proto/auth"
+
+	"google.golang.org/grpc"
+)
+
+type AuthServiceClient struct {
+	Client pb.AuthServiceClient
+	conn   *grpc.ClientConn
+}
+
+func NewAuthServiceClient() (*AuthServiceClient, error) {
+	authServiceURL := os.Getenv("AUTH_SERVICE_URL")
+	if authServiceURL == "" {
+		authServiceURL = "auth-service:9094"
+	}
+
+	// signups, logins and refreshes must not be repeated, a refresh token
+	// presented twice logs the user out
+	conn, err := grpc.NewClient(authServiceURL, dialOptions(pb.AuthService_ServiceDesc.ServiceName, "GetJWKS")...)
+	if err != nil {
+		return nil, err
+	}
+
+	client := pb.NewAuthServiceClient(conn)
+
+	return &AuthServiceClient{
+		Client: client,
+		conn:   conn,
+	}, nil
+}
+
+func (c *AuthServiceClient) Close() {
+	if c.conn != nil {
+		if err := c.conn.Close(); err != nil {
+			return
+		}
+	}
+}
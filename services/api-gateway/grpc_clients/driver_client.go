@@ -5,37 +5,34 @@ import (
 	pb "ride-sharing/shared/proto/driver"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
-type driverServiceClient struct {
+type DriverServiceClient struct {
 	Client pb.DriverServiceClient
 	Conn   *grpc.ClientConn
 }
 
-func NewDriverServiceClient() (*driverServiceClient, error) {
+func NewDriverServiceClient() (*DriverServiceClient, error) {
 	driverServiceURL := os.Getenv("DRIVER_SERVICE_URL")
 	if driverServiceURL == "" {
 		driverServiceURL = "driver-service:9092"
 	}
 
-	conn, err := grpc.NewClient(
-		driverServiceURL,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
+	// lookups and heartbeats can be repeated, registrations must not be
+	conn, err := grpc.NewClient(driverServiceURL, dialOptions(pb.DriverService_ServiceDesc.ServiceName, "GetDriverProfile", "GetDriverStats", "Heartbeat")...)
 	if err != nil {
 		return nil, err
 	}
 
 	client := pb.NewDriverServiceClient(conn)
 
-	return &driverServiceClient{
+	return &DriverServiceClient{
 		Client: client,
 		Conn:   conn,
 	}, nil
 }
 
-func (c *driverServiceClient) Close() {
+func (c *DriverServiceClient) Close() {
 	if c.Conn != nil {
 		if err := c.Conn.Close(); err != nil {
 			return
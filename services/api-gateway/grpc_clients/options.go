@@ -0,0 +1,81 @@
+package grpc_clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"ride-sharing/shared/auth"
+	"ride-sharing/shared/env"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	_ "google.golang.org/grpc/health" // enables client-side health checking
+	"google.golang.org/grpc/keepalive"
+)
+
+var (
+	// deadline applied to every call that does not carry a shorter one
+	callTimeout = env.GetDuration("GRPC_CALL_TIMEOUT", 5*time.Second)
+	// attempts per call, including the first one, for calls failing with UNAVAILABLE
+	maxCallAttempts = env.GetInt("GRPC_MAX_CALL_ATTEMPTS", 3)
+)
+
+// serviceConfig balances calls over the healthy backends of the service and
+// sets the per-call deadline. Only the retryable methods, which are safe to call
+// twice, are retried when the backend could not process them: a call failing
+// with UNAVAILABLE may still have been handled.
+func serviceConfig(service string, retryable []string) string {
+	methodConfig := []map[string]any{{
+		"name":    []map[string]string{{"service": service}},
+		"timeout": fmt.Sprintf("%.3fs", callTimeout.Seconds()),
+	}}
+
+	if len(retryable) > 0 {
+		names := make([]map[string]string, len(retryable))
+		for i, method := range retryable {
+			names[i] = map[string]string{"service": service, "method": method}
+		}
+
+		methodConfig = append(methodConfig, map[string]any{
+			"name":    names,
+			"timeout": fmt.Sprintf("%.3fs", callTimeout.Seconds()),
+			"retryPolicy": map[string]any{
+				"maxAttempts":          maxCallAttempts,
+				"initialBackoff":       "0.1s",
+				"maxBackoff":           "1s",
+				"backoffMultiplier":    2,
+				"retryableStatusCodes": []string{"UNAVAILABLE"},
+			},
+		})
+	}
+
+	config, err := json.Marshal(map[string]any{
+		"loadBalancingConfig": []map[string]any{{"round_robin": map[string]any{}}},
+		"healthCheckConfig":   map[string]string{"serviceName": ""},
+		"methodConfig":        methodConfig,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return string(config)
+}
+
+// dialOptions are shared by the long-lived clients of the gateway, retryable
+// lists the methods of the service that are retried.
+func dialOptions(service string, retryable ...string) []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultServiceConfig(serviceConfig(service, retryable)),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                30 * time.Second,
+			Timeout:             10 * time.Second,
+			PermitWithoutStream: true,
+		}),
+		// pass the caller's identity on to the services
+		grpc.WithUnaryInterceptor(auth.UnaryClientInterceptor()),
+		// and the trace context of the request
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	}
+}
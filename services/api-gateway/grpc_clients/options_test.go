@@ -0,0 +1,69 @@
+package grpc_clients
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	pb "ride-sharing/shared/proto/trip"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// unavailableTripServer fails every call as if it was shutting down.
+type unavailableTripServer struct {
+	pb.UnimplementedTripServiceServer
+	previews atomic.Int32
+	creates  atomic.Int32
+}
+
+func (s *unavailableTripServer) PreviewTrip(context.Context, *pb.PreviewTripRequest) (*pb.PreviewTripResponse, error) {
+	s.previews.Add(1)
+	return nil, status.Error(codes.Unavailable, "shutting down")
+}
+
+func (s *unavailableTripServer) CreateTrip(context.Context, *pb.CreateTripRequest) (*pb.CreateTripResponse, error) {
+	s.creates.Add(1)
+	return nil, status.Error(codes.Unavailable, "shutting down")
+}
+
+func TestOnlyRetryableMethodsAreRetried(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend := &unavailableTripServer{}
+	server := grpc.NewServer()
+	pb.RegisterTripServiceServer(server, backend)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(lis)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), dialOptions(pb.TripService_ServiceDesc.ServiceName, "PreviewTrip")...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := pb.NewTripServiceClient(conn)
+
+	ctx := context.Background()
+	if _, err := client.PreviewTrip(ctx, &pb.PreviewTripRequest{}); status.Code(err) != codes.Unavailable {
+		t.Fatalf("PreviewTrip() error = %v, want UNAVAILABLE", err)
+	}
+	if _, err := client.CreateTrip(ctx, &pb.CreateTripRequest{}); status.Code(err) != codes.Unavailable {
+		t.Fatalf("CreateTrip() error = %v, want UNAVAILABLE", err)
+	}
+
+	if got := backend.previews.Load(); got != int32(maxCallAttempts) {
+		t.Errorf("PreviewTrip attempts = %d, want %d", got, maxCallAttempts)
+	}
+	if got := backend.creates.Load(); got != 1 {
+		t.Errorf("CreateTrip attempts = %d, want 1", got)
+	}
+}
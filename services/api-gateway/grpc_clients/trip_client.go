@@ -5,37 +5,34 @@ import (
 	pb "ride-sharing/shared/proto/trip"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
-type tripServiceClient struct {
+type TripServiceClient struct {
 	Client pb.TripServiceClient
 	conn   *grpc.ClientConn
 }
 
-func NewTripServiceClient() (*tripServiceClient, error) {
+func NewTripServiceClient() (*TripServiceClient, error) {
 	tripServiceURL := os.Getenv("TRIP_SERVICE_URL")
 	if tripServiceURL == "" {
 		tripServiceURL = "trip-service:9093"
 	}
 
-	conn, err := grpc.NewClient(
-		tripServiceURL,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
+	// a preview can be computed twice, a trip must not be created twice
+	conn, err := grpc.NewClient(tripServiceURL, dialOptions(pb.TripService_ServiceDesc.ServiceName, "PreviewTrip")...)
 	if err != nil {
 		return nil, err
 	}
 
 	client := pb.NewTripServiceClient(conn)
 
-	return &tripServiceClient{
+	return &TripServiceClient{
 		Client: client,
 		conn:   conn,
 	}, nil
 }
 
-func (c *tripServiceClient) Close() {
+func (c *TripServiceClient) Close() {
 	if c.conn != nil {
 		if err := c.conn.Close(); err != nil {
 			return
@@ -1,36 +1,25 @@
 package main
 
 import (
-	"encoding/json"
-	"log"
 	"net/http"
 	"ride-sharing/services/api-gateway/grpc_clients"
+	"ride-sharing/shared/auth"
 	"ride-sharing/shared/contracts"
 )
 
-func handleTripPreview(w http.ResponseWriter, r *http.Request) {
-	var request previewTripRequest
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "fail to parse JSON data", http.StatusBadRequest)
-		return
-	}
-	defer r.Body.Close()
+func handleTripPreview(w http.ResponseWriter, r *http.Request, tripService *grpc_clients.TripServiceClient) {
+	identity, _ := auth.FromContext(r.Context())
 
-	// validation
-	if request.UserID == "" {
-		http.Error(w, "userID is required", http.StatusBadRequest)
+	var request previewTripRequest
+	if !decodeJSON(w, r, &request) {
 		return
 	}
 
-	tripService, err := grpc_clients.NewTripServiceClient()
-	if err != nil {
-		log.Fatal(err)
-	}
+	request.UserID = identity.UserID
 
 	resp, err := tripService.Client.PreviewTrip(r.Context(), request.ToProto())
 	if err != nil {
-		log.Printf("failed to preview a trip: %v", err)
-		http.Error(w, "failed to preview trip", http.StatusInternalServerError)
+		writeBackendError(w, "failed to preview trip", err)
 		return
 	}
 
@@ -38,23 +27,19 @@ func handleTripPreview(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusCreated, response)
 }
 
-func createTrip(w http.ResponseWriter, r *http.Request) {
+func createTrip(w http.ResponseWriter, r *http.Request, tripService *grpc_clients.TripServiceClient) {
+	identity, _ := auth.FromContext(r.Context())
+
 	var request startTripRequest
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "fail to parse JSON data", http.StatusBadRequest)
+	if !decodeJSON(w, r, &request) {
+		return
 	}
-	defer r.Body.Close()
 
-	c, err := grpc_clients.NewTripServiceClient()
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer c.Close()
+	request.UserID = identity.UserID
 
-	resp, err := c.Client.CreateTrip(r.Context(), request.ToProto())
+	resp, err := tripService.Client.CreateTrip(r.Context(), request.ToProto())
 	if err != nil {
-		log.Printf("failed to create a trip: %v", err)
-		http.Error(w, "failad to create a trip", http.StatusInternalServerError)
+		writeBackendError(w, "failed to create a trip", err)
 		return
 	}
 
@@ -1,12 +1,20 @@
 package main
 
-import "net/http"
+import (
+	"log"
+	"net/http"
+	"ride-sharing/shared/auth"
+	"ride-sharing/shared/contracts"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
 
 func enableCors(handler http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Method", "GET, POST, PUT, DELETE, PATCH, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Header", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, PATCH, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
 		// allow preflight request from the browser
 		if r.Method == "OPTIONS" {
@@ -17,3 +25,56 @@ func enableCors(handler http.HandlerFunc) http.HandlerFunc {
 		handler(w, r)
 	}
 }
+
+// authenticate verifies the bearer token of the request and only lets callers
+// holding one of roles through, with their identity in the request context.
+func authenticate(keys *auth.KeySet, roles []string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		identity, err := verifyRequest(keys, r)
+		if err != nil {
+			log.Printf("rejected %s %s: %v", r.Method, r.URL.Path, err)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="ride-sharing"`)
+			writeError(w, http.StatusUnauthorized, contracts.ErrCodeUnauthenticated, "invalid or missing token")
+			return
+		}
+
+		if !identity.HasRole(roles...) {
+			writeError(w, http.StatusForbidden, contracts.ErrCodePermissionDenied, auth.ErrForbidden.Error())
+			return
+		}
+
+		handler(w, r.WithContext(auth.NewContext(r.Context(), identity)))
+	}
+}
+
+func verifyRequest(keys *auth.KeySet, r *http.Request) (auth.Identity, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return auth.Identity{}, auth.ErrMissingToken
+	}
+
+	identity, err := keys.Verify(token)
+	if err != nil {
+		return auth.Identity{}, err
+	}
+
+	return identity, nil
+}
+
+// bearerToken reads the token from the Authorization header. Browsers cannot
+// set headers on websocket requests, so upgrades may pass it as ?token=.
+func bearerToken(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return ""
+		}
+		return strings.TrimSpace(token)
+	}
+
+	if websocket.IsWebSocketUpgrade(r) {
+		return r.URL.Query().Get("token")
+	}
+
+	return ""
+}
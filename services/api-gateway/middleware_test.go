@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"ride-sharing/shared/auth"
+	"ride-sharing/shared/contracts"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestAuthenticate(t *testing.T) {
+	secret := []byte("test-secret")
+	keys := auth.NewKeySet("")
+	keys.AddHMAC("", secret)
+
+	token := func(role string, expiresIn time.Duration) string {
+		claims := auth.Claims{
+			Role: role,
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "user-1",
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			},
+		}
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	rider := token(auth.RoleRider, time.Hour)
+
+	tests := []struct {
+		name       string
+		header     string
+		query      string
+		websocket  bool
+		wantStatus int
+		wantCode   string
+	}{
+		{"bearer token", "Bearer " + rider, "", false, http.StatusOK, ""},
+		{"lowercase scheme", "bearer " + rider, "", false, http.StatusOK, ""},
+		{"websocket token parameter", "", "?token=" + rider, true, http.StatusOK, ""},
+
+		{"no token", "", "", false, http.StatusUnauthorized, contracts.ErrCodeUnauthenticated},
+		{"other scheme", "Basic " + rider, "", false, http.StatusUnauthorized, contracts.ErrCodeUnauthenticated},
+		{"token parameter without upgrade", "", "?token=" + rider, false, http.StatusUnauthorized, contracts.ErrCodeUnauthenticated},
+		{"expired token", "Bearer " + token(auth.RoleRider, -time.Minute), "", false, http.StatusUnauthorized, contracts.ErrCodeUnauthenticated},
+		{"forged token", "Bearer " + rider + "x", "", false, http.StatusUnauthorized, contracts.ErrCodeUnauthenticated},
+		{"role not allowed", "Bearer " + token(auth.RoleDriver, time.Hour), "", false, http.StatusForbidden, contracts.ErrCodePermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got auth.Identity
+			handler := authenticate(keys, []string{auth.RoleRider, auth.RoleAdmin}, func(w http.ResponseWriter, r *http.Request) {
+				got, _ = auth.FromContext(r.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/trip/preview"+tt.query, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.websocket {
+				req.Header.Set("Connection", "Upgrade")
+				req.Header.Set("Upgrade", "websocket")
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK {
+				if want := (auth.Identity{UserID: "user-1", Role: auth.RoleRider}); got != want {
+					t.Errorf("handler identity = %+v, want %+v", got, want)
+				}
+				return
+			}
+
+			var resp contracts.APIResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.Error == nil || resp.Error.Code != tt.wantCode {
+				t.Errorf("body = %+v, %v, want error code %s", resp, err, tt.wantCode)
+			}
+			if (tt.wantStatus == http.StatusUnauthorized) != (rec.Header().Get("WWW-Authenticate") != "") {
+				t.Errorf("WWW-Authenticate = %q on a %d", rec.Header().Get("WWW-Authenticate"), rec.Code)
+			}
+		})
+	}
+}
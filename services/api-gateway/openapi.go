@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"ride-sharing/shared/contracts"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// largest request body the gateway reads, every documented body is far smaller
+const maxRequestBodyBytes = 1 << 20
+
+// openAPIDocument describes every gateway route, it is served as is and
+// request bodies are validated against its schemas.
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+type openAPI struct {
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components struct {
+		Schemas map[string]*schema `json:"schemas"`
+	} `json:"components"`
+}
+
+type operation struct {
+	RequestBody *struct {
+		Content map[string]struct {
+			Schema *schema `json:"schema"`
+		} `json:"content"`
+	} `json:"requestBody"`
+}
+
+// schema is the subset of the OpenAPI schema object the gateway validates.
+// additionalProperties only supports false, anything else allows unknown
+// properties.
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Properties           map[string]*schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *schema            `json:"items"`
+	Enum                 []any              `json:"enum"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+
+	// schema $ref points to, set when the document is loaded
+	resolved *schema
+}
+
+func loadOpenAPI() (*openAPI, error) {
+	var doc openAPI
+	if err := json.Unmarshal(openAPIDocument, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse the OpenAPI document: %v", err)
+	}
+
+	visited := make(map[*schema]bool)
+	for name, s := range doc.Components.Schemas {
+		if err := doc.resolve(s, visited); err != nil {
+			return nil, fmt.Errorf("schema %s: %v", name, err)
+		}
+	}
+	for path, methods := range doc.Paths {
+		for method, op := range methods {
+			if op.RequestBody == nil {
+				continue
+			}
+			for _, content := range op.RequestBody.Content {
+				if err := doc.resolve(content.Schema, visited); err != nil {
+					return nil, fmt.Errorf("%s %s: %v", method, path, err)
+				}
+			}
+		}
+	}
+
+	return &doc, nil
+}
+
+// resolve links every $ref under s to the component schema it names.
+func (o *openAPI) resolve(s *schema, visited map[*schema]bool) error {
+	if s == nil || visited[s] {
+		return nil
+	}
+	visited[s] = true
+
+	if s.Ref != "" {
+		name, ok := strings.CutPrefix(s.Ref, "#/components/schemas/")
+		if !ok || o.Components.Schemas[name] == nil {
+			return fmt.Errorf("unknown $ref %s", s.Ref)
+		}
+		s.resolved = o.Components.Schemas[name]
+		return o.resolve(s.resolved, visited)
+	}
+
+	for _, property := range s.Properties {
+		if err := o.resolve(property, visited); err != nil {
+			return err
+		}
+	}
+
+	return o.resolve(s.Items, visited)
+}
+
+// validate rejects the requests whose JSON body does not match the schema the
+// document gives the operation, before they reach handler and the backends.
+// The body is restored for handler to decode. Operations the document gives no
+// JSON body go to handler as they are.
+func (o *openAPI) validate(method, path string, handler http.HandlerFunc) http.HandlerFunc {
+	op := o.Paths[path][strings.ToLower(method)]
+	if op == nil || op.RequestBody == nil || op.RequestBody.Content["application/json"].Schema == nil {
+		log.Printf("the OpenAPI document has no JSON request body for %s %s, not validating it", method, path)
+		return handler
+	}
+	bodySchema := op.RequestBody.Content["application/json"].Schema
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes))
+		r.Body.Close()
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeError(w, http.StatusRequestEntityTooLarge, contracts.ErrCodeMalformedRequest, "request body too large")
+				return
+			}
+			writeError(w, http.StatusBadRequest, contracts.ErrCodeMalformedRequest, "fail to read the request body")
+			return
+		}
+
+		var value any
+		if err := json.Unmarshal(body, &value); err != nil {
+			writeError(w, http.StatusBadRequest, contracts.ErrCodeMalformedRequest, "fail to parse JSON data")
+			return
+		}
+
+		var errs fieldErrors
+		bodySchema.validate("", value, &errs)
+		if len(errs) > 0 {
+			writeValidationError(w, errs)
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		handler(w, r)
+	}
+}
+
+func handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPIDocument)
+}
+
+func (s *schema) validate(field string, value any, errs *fieldErrors) {
+	if s.resolved != nil {
+		s.resolved.validate(field, value, errs)
+		return
+	}
+
+	name := field
+	if name == "" {
+		name = "body"
+	}
+
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			errs.add(name, "must be an object")
+			return
+		}
+		s.validateObject(field, object, errs)
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			errs.add(name, "must be an array")
+			return
+		}
+		if s.Items != nil {
+			for i, item := range items {
+				s.Items.validate(fmt.Sprintf("%s[%d]", name, i), item, errs)
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			errs.add(name, "must be a string")
+			return
+		}
+		length := utf8.RuneCountInString(str)
+		if s.MinLength != nil && length < *s.MinLength {
+			if *s.MinLength == 1 {
+				errs.add(name, "must not be empty")
+			} else {
+				errs.add(name, fmt.Sprintf("must be at least %d characters", *s.MinLength))
+			}
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			errs.add(name, fmt.Sprintf("must be at most %d characters", *s.MaxLength))
+		}
+	case "number", "integer":
+		number, ok := value.(float64)
+		if !ok {
+			errs.add(name, "must be a number")
+			return
+		}
+		if s.Type == "integer" && number != math.Trunc(number) {
+			errs.add(name, "must be an integer")
+			return
+		}
+		s.validateRange(name, number, errs)
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			errs.add(name, "must be a boolean")
+			return
+		}
+	}
+
+	if len(s.Enum) > 0 && !s.allows(value) {
+		values := make([]string, len(s.Enum))
+		for i, v := range s.Enum {
+			values[i] = fmt.Sprint(v)
+		}
+		errs.add(name, "must be one of "+strings.Join(values, ", "))
+	}
+}
+
+func (s *schema) validateObject(field string, object map[string]any, errs *fieldErrors) {
+	for _, required := range s.Required {
+		if _, ok := object[required]; !ok {
+			errs.add(joinField(field, required), "is required")
+		}
+	}
+
+	// sorted so that the same body always gets the same errors
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		property, ok := s.Properties[key]
+		if !ok {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				errs.add(joinField(field, key), "is not allowed")
+			}
+			continue
+		}
+		property.validate(joinField(field, key), object[key], errs)
+	}
+}
+
+func (s *schema) validateRange(field string, number float64, errs *fieldErrors) {
+	tooLow := s.Minimum != nil && number < *s.Minimum
+	tooHigh := s.Maximum != nil && number > *s.Maximum
+
+	switch {
+	case s.Minimum != nil && s.Maximum != nil && (tooLow || tooHigh):
+		errs.add(field, fmt.Sprintf("must be between %g and %g", *s.Minimum, *s.Maximum))
+	case tooLow:
+		errs.add(field, fmt.Sprintf("must be at least %g", *s.Minimum))
+	case tooHigh:
+		errs.add(field, fmt.Sprintf("must be at most %g", *s.Maximum))
+	}
+}
+
+func (s *schema) allows(value any) bool {
+	for _, v := range s.Enum {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func joinField(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"ride-sharing/shared/contracts"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPIValidate(t *testing.T) {
+	spec, err := loadOpenAPI()
+	if err != nil {
+		t.Fatalf("loadOpenAPI() error = %v", err)
+	}
+
+	const preview = `{"pickup": {"latitude": 52.52, "longitude": 13.40}, "destination": {"latitude": 52.50, "longitude": 13.45}}`
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantCode   string
+		wantFields []contracts.FieldError
+	}{
+		{
+			name: "valid body", method: "POST", path: "/trip/preview", body: preview,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "missing required field", method: "POST", path: "/trip/preview",
+			body:       `{"pickup": {"latitude": 52.52, "longitude": 13.40}}`,
+			wantStatus: http.StatusBadRequest, wantCode: contracts.ErrCodeInvalidArgument,
+			wantFields: []contracts.FieldError{{Field: "destination", Message: "is required"}},
+		},
+		{
+			name: "missing nested field", method: "POST", path: "/trip/preview",
+			body:       `{"pickup": {"latitude": 52.52}, "destination": {"latitude": 52.50, "longitude": 13.45}}`,
+			wantStatus: http.StatusBadRequest, wantCode: contracts.ErrCodeInvalidArgument,
+			wantFields: []contracts.FieldError{{Field: "pickup.longitude", Message: "is required"}},
+		},
+		{
+			name: "wrong type", method: "POST", path: "/trip/preview",
+			body:       `{"pickup": {"latitude": "52.52", "longitude": 13.40}, "destination": []}`,
+			wantStatus: http.StatusBadRequest, wantCode: contracts.ErrCodeInvalidArgument,
+			wantFields: []contracts.FieldError{
+				{Field: "destination", Message: "must be an object"},
+				{Field: "pickup.latitude", Message: "must be a number"},
+			},
+		},
+		{
+			name: "out of range", method: "POST", path: "/trip/preview",
+			body:       `{"pickup": {"latitude": 91, "longitude": 13.40}, "destination": {"latitude": 52.50, "longitude": 13.45}}`,
+			wantStatus: http.StatusBadRequest, wantCode: contracts.ErrCodeInvalidArgument,
+			wantFields: []contracts.FieldError{{Field: "pickup.latitude", Message: "must be between -90 and 90"}},
+		},
+		{
+			name: "unknown field", method: "POST", path: "/trip/preview",
+			body:       `{"pickup": {"latitude": 52.52, "longitude": 13.40}, "destination": {"latitude": 52.50, "longitude": 13.45}, "fare": 0}`,
+			wantStatus: http.StatusBadRequest, wantCode: contracts.ErrCodeInvalidArgument,
+			wantFields: []contracts.FieldError{{Field: "fare", Message: "is not allowed"}},
+		},
+		{
+			name: "not JSON", method: "POST", path: "/trip/preview", body: `{"pickup":`,
+			wantStatus: http.StatusBadRequest, wantCode: contracts.ErrCodeMalformedRequest,
+		},
+		{
+			name: "too large", method: "POST", path: "/trip/preview", body: strings.Repeat(" ", maxRequestBodyBytes+1),
+			wantStatus: http.StatusRequestEntityTooLarge, wantCode: contracts.ErrCodeMalformedRequest,
+		},
+		// the document gives these no JSON body, whatever is sent passes
+		{
+			name: "operation without body", method: "GET", path: "/ws/riders", body: `{"pickup": 1}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "unknown route", method: "POST", path: "/trip/cancel", body: `not JSON`,
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var handled string
+			handler := spec.validate(tt.method, tt.path, func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				handled = string(body)
+			})
+
+			rec := httptest.NewRecorder()
+			handler(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK {
+				if handled != tt.body {
+					t.Errorf("handler read %q, want the body %q", handled, tt.body)
+				}
+				return
+			}
+
+			var resp contracts.APIResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.Error == nil {
+				t.Fatalf("body = %+v, %v, want an error", resp, err)
+			}
+			if resp.Error.Code != tt.wantCode || !reflect.DeepEqual(resp.Error.Details, tt.wantFields) {
+				t.Errorf("error = %s %v, want %s %v", resp.Error.Code, resp.Error.Details, tt.wantCode, tt.wantFields)
+			}
+			if handled != "" {
+				t.Errorf("handler called with %q for a rejected request", handled)
+			}
+		})
+	}
+}
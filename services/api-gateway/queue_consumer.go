@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// QueueConsumer pushes the messages of a queue to the websocket of their owner,
+// using the routing key as the websocket message type.
+//
+// Shared queues are consumed by every gateway replica in turn, so the owner may
+// be connected to another replica: such messages are broadcast to all replicas,
+// whose broadcast consumers deliver them if they hold the owner's websocket.
+type QueueConsumer struct {
+	rabbitmq    messaging.Broker
+	connManager *ConnectionManager
+	queueName   string
+	broadcast   bool
+}
+
+func NewQueueConsumer(rabbitmq messaging.Broker, connManager *ConnectionManager, queueName string) *QueueConsumer {
+	return &QueueConsumer{
+		rabbitmq:    rabbitmq,
+		connManager: connManager,
+		queueName:   queueName,
+	}
+}
+
+// NewBroadcastConsumer consumes this replica's broadcast queue, delivering only
+// to users connected here.
+func NewBroadcastConsumer(rabbitmq messaging.Broker, connManager *ConnectionManager, queueName string) *QueueConsumer {
+	return &QueueConsumer{
+		rabbitmq:    rabbitmq,
+		connManager: connManager,
+		queueName:   queueName,
+		broadcast:   true,
+	}
+}
+
+func (qc *QueueConsumer) Start() error {
+	return qc.rabbitmq.ConsumeMessages(qc.queueName, func(ctx context.Context, msg amqp091.Delivery) error {
+		msgBody, err := messaging.DecodeEnvelope(msg)
+		if err != nil {
+			log.Printf("failed to decode message: %v", err)
+			return err
+		}
+
+		data, err := wsPayload(msg.RoutingKey, msg.ContentType, msgBody.Data)
+		if err != nil {
+			log.Printf("failed to unmarshal message data: %v", err)
+			return err
+		}
+
+		clientMsg := contracts.WSMessage{
+			Type: msg.RoutingKey,
+			Data: data,
+		}
+
+		err = qc.connManager.SendToUser(msgBody.OwnerID, clientMsg)
+		switch {
+		case errors.Is(err, ErrConnectionNotFound) && qc.broadcast:
+			// the user is connected to another replica, or not at all
+			return nil
+		case errors.Is(err, ErrConnectionNotFound):
+			return qc.rabbitmq.BroadcastDelivery(ctx, msg)
+		case err != nil:
+			log.Printf("failed to send %s to user %s: %v", msg.RoutingKey, msgBody.OwnerID, err)
+		}
+
+		return nil
+	})
+}
+
+// wsPayload converts the AMQP message data into the websocket payload the web
+// client expects: trip events carry the trip itself, other JSON payloads are
+// passed as is.
+func wsPayload(routingKey, contentType string, data []byte) (any, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	switch routingKey {
+	case contracts.TripEventCreated, contracts.TripEventDriverAssigned, contracts.DriverCmdTripRequest:
+		var payload messaging.TripEventData
+		if err := messaging.DecodePayload(contentType, data, &payload); err != nil {
+			return nil, err
+		}
+
+		return payload.Trip, nil
+	}
+
+	if codec, err := messaging.CodecFor(contentType); err != nil || codec != messaging.JSONCodec {
+		return nil, fmt.Errorf("no websocket payload for %s data of %s", contentType, routingKey)
+	}
+
+	return json.RawMessage(data), nil
+}
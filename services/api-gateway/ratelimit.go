@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"ride-sharing/shared/auth"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/env"
+	"ride-sharing/shared/ratelimit"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rate limited routes, RATE_LIMIT_<ROUTE>_USER and RATE_LIMIT_<ROUTE>_IP
+// override their limits, e.g. RATE_LIMIT_TRIP_PREVIEW_USER=10/m:5
+const (
+	routeTripPreview      = "trip_preview"
+	routeTripStart        = "trip_start"
+	routeAuthSignup       = "auth_signup"
+	routeAuthLogin        = "auth_login"
+	routeAuthRefresh      = "auth_refresh"
+	routeAuthLogout       = "auth_logout"
+	routeWSConnect        = "ws_connect"
+	routeWSDriverMessages = "ws_driver_messages"
+	routeWSRiderMessages  = "ws_rider_messages"
+)
+
+type routeLimit struct {
+	PerUser ratelimit.Limit
+	PerIP   ratelimit.Limit
+}
+
+// previews call the routing API and store fares, they are the most expensive
+// requests; auth routes are limited per IP against credential stuffing
+var defaultRouteLimits = map[string]routeLimit{
+	routeTripPreview:      {PerUser: perMinute(10, 5), PerIP: perMinute(60, 20)},
+	routeTripStart:        {PerUser: perMinute(5, 3), PerIP: perMinute(30, 10)},
+	routeAuthSignup:       {PerIP: perMinute(5, 5)},
+	routeAuthLogin:        {PerIP: perMinute(10, 5)},
+	routeAuthRefresh:      {PerIP: perMinute(30, 10)},
+	routeAuthLogout:       {PerIP: perMinute(30, 10)},
+	routeWSConnect:        {PerUser: perMinute(10, 5), PerIP: perMinute(30, 10)},
+	routeWSDriverMessages: {PerUser: ratelimit.Limit{Rate: 10, Burst: 30}},
+	routeWSRiderMessages:  {PerUser: ratelimit.Limit{Rate: 2, Burst: 10}},
+}
+
+func perMinute(count, burst int) ratelimit.Limit {
+	return ratelimit.Limit{Rate: float64(count) / 60, Burst: burst}
+}
+
+// rateLimiter throttles requests per client IP and per authenticated user. The
+// buckets live in the store, a shared one applies the limits across replicas.
+type rateLimiter struct {
+	store  ratelimit.Store
+	limits map[string]routeLimit
+	// proxies in front of the gateway appending the address they received the
+	// request from to X-Forwarded-For, 0 when clients connect directly
+	trustedProxies int
+}
+
+func newRateLimiter(store ratelimit.Store, trustedProxies int) (*rateLimiter, error) {
+	limits := make(map[string]routeLimit, len(defaultRouteLimits))
+	for route, limit := range defaultRouteLimits {
+		var err error
+		if limit.PerUser, err = limitFromEnv(route, "USER", limit.PerUser); err != nil {
+			return nil, err
+		}
+		if limit.PerIP, err = limitFromEnv(route, "IP", limit.PerIP); err != nil {
+			return nil, err
+		}
+		limits[route] = limit
+	}
+
+	return &rateLimiter{
+		store:          store,
+		limits:         limits,
+		trustedProxies: trustedProxies,
+	}, nil
+}
+
+func limitFromEnv(route, scope string, fallback ratelimit.Limit) (ratelimit.Limit, error) {
+	key := fmt.Sprintf("RATE_LIMIT_%s_%s", strings.ToUpper(route), scope)
+
+	value := env.GetString(key, "")
+	if value == "" {
+		return fallback, nil
+	}
+
+	limit, err := ratelimit.ParseLimit(value)
+	if err != nil {
+		return ratelimit.Limit{}, fmt.Errorf("%s: %v", key, err)
+	}
+
+	return limit, nil
+}
+
+// limitIP rejects the request with 429 once the client IP ran out of tokens
+// for the route. It goes before authenticate, so that requests with invalid
+// tokens are limited too.
+func (l *rateLimiter) limitIP(route string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if ok, retryAfter := l.allow(r.Context(), route, "ip", l.clientIP(r), l.limits[route].PerIP); !ok {
+			writeRateLimited(w, retryAfter)
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+// limitUser rejects the request with 429 once the authenticated user ran out
+// of tokens for the route. Wrap it in authenticate.
+func (l *rateLimiter) limitUser(route string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if identity, ok := auth.FromContext(r.Context()); ok {
+			if ok, retryAfter := l.allow(r.Context(), route, "user", identity.UserID, l.limits[route].PerUser); !ok {
+				writeRateLimited(w, retryAfter)
+				return
+			}
+		}
+
+		handler(w, r)
+	}
+}
+
+// allowMessage applies the per user limit of route to a websocket message.
+func (l *rateLimiter) allowMessage(ctx context.Context, route, userID string) (bool, time.Duration) {
+	return l.allow(ctx, route, "user", userID, l.limits[route].PerUser)
+}
+
+func (l *rateLimiter) allow(ctx context.Context, route, scope, subject string, limit ratelimit.Limit) (bool, time.Duration) {
+	if !limit.Enabled() || subject == "" {
+		return true, 0
+	}
+
+	result, err := l.store.Take(ctx, route+":"+scope+":"+subject, limit, time.Now())
+	if err != nil {
+		// an unavailable store must not take the gateway down with it
+		log.Printf("rate limit store failed, letting %s through: %v", route, err)
+		return true, 0
+	}
+
+	return result.Allowed, result.RetryAfter
+}
+
+// clientIP is the address the outermost trusted proxy received the request
+// from. The entries on its left are set by the client and cannot be trusted.
+func (l *rateLimiter) clientIP(r *http.Request) string {
+	if l.trustedProxies > 0 {
+		var forwarded []string
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			for _, addr := range strings.Split(header, ",") {
+				forwarded = append(forwarded, strings.TrimSpace(addr))
+			}
+		}
+
+		// a request with fewer entries did not come through all the proxies
+		if len(forwarded) >= l.trustedProxies {
+			return forwarded[len(forwarded)-l.trustedProxies]
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+func writeRateLimited(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(retryAfter)))
+	writeError(w, http.StatusTooManyRequests, contracts.ErrCodeResourceExhausted, "rate limit exceeded")
+}
+
+// retryAfterSeconds rounds up, a client retrying early would be rejected again.
+func retryAfterSeconds(retryAfter time.Duration) int {
+	return max(1, int(math.Ceil(retryAfter.Seconds())))
+}
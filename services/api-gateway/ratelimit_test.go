@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ride-sharing/shared/auth"
+	"ride-sharing/shared/ratelimit"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies int
+		forwardedFor   []string
+		want           string
+	}{
+		{"direct client", 0, nil, "203.0.113.9"},
+		{"forwarded header ignored without proxies", 0, []string{"198.51.100.1"}, "203.0.113.9"},
+		{"one proxy", 1, []string{"198.51.100.1"}, "198.51.100.1"},
+		{"one proxy, spoofed entries", 1, []string{"10.0.0.1, 10.0.0.2, 198.51.100.1"}, "198.51.100.1"},
+		{"two proxies", 2, []string{"10.0.0.1, 198.51.100.1, 192.0.2.7"}, "198.51.100.1"},
+		{"two proxies, one header each", 2, []string{"10.0.0.1, 198.51.100.1", "192.0.2.7"}, "198.51.100.1"},
+		{"bypassed the proxies", 2, []string{"198.51.100.1"}, "203.0.113.9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter, err := newRateLimiter(ratelimit.NewMemoryStore(), tt.trustedProxies)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := httptest.NewRequest(http.MethodPost, "/trip/preview", nil)
+			r.RemoteAddr = "203.0.113.9:51234"
+			for _, header := range tt.forwardedFor {
+				r.Header.Add("X-Forwarded-For", header)
+			}
+
+			if got := limiter.clientIP(r); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIPLimitBeforeAuthentication(t *testing.T) {
+	limiter, err := newRateLimiter(ratelimit.NewMemoryStore(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter.limits[routeTripPreview] = routeLimit{PerIP: ratelimit.Limit{Rate: 0.001, Burst: 2}}
+
+	keys := auth.NewKeySet("")
+	keys.AddHMAC("test", []byte("test-secret"))
+
+	handler := limiter.limitIP(routeTripPreview, authenticate(keys, []string{auth.RoleRider}, limiter.limitUser(routeTripPreview, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler called with an invalid token")
+	})))
+
+	want := []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}
+	for i, status := range want {
+		r := httptest.NewRequest(http.MethodPost, "/trip/preview", nil)
+		r.Header.Set("Authorization", "Bearer not-a-token")
+		w := httptest.NewRecorder()
+
+		handler(w, r)
+
+		if w.Code != status {
+			t.Errorf("request %d: status = %d, want %d", i+1, w.Code, status)
+		}
+	}
+}
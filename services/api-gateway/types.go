@@ -1,12 +1,15 @@
 package main
 
 import (
+	pba "ride-sharing/shared/proto/auth"
+	pbd "ride-sharing/shared/proto/driver"
 	pb "ride-sharing/shared/proto/trip"
 	"ride-sharing/shared/types"
 )
 
 type previewTripRequest struct {
-	UserID      string           `json:"userID"`
+	// set from the caller's token, never from the body
+	UserID      string           `json:"-"`
 	Pickup      types.Coordinate `json:"pickup"`
 	Destination types.Coordinate `json:"destination"`
 }
@@ -27,7 +30,8 @@ func (p *previewTripRequest) ToProto() *pb.PreviewTripRequest {
 
 type startTripRequest struct {
 	RideFareID string `json:"rideFareID"`
-	UserID     string `json:"userID"`
+	// set from the caller's token, never from the body
+	UserID string `json:"-"`
 }
 
 func (c *startTripRequest) ToProto() *pb.CreateTripRequest {
@@ -36,3 +40,69 @@ func (c *startTripRequest) ToProto() *pb.CreateTripRequest {
 		UserID:     c.UserID,
 	}
 }
+
+type driverDestinationRequest struct {
+	Enabled     bool             `json:"enabled"`
+	Destination types.Coordinate `json:"destination"`
+	MaxDetourKm float64          `json:"maxDetourKm"`
+}
+
+func (d *driverDestinationRequest) ToProto(driverID string) *pbd.DestinationPreferenceRequest {
+	return &pbd.DestinationPreferenceRequest{
+		DriverID: driverID,
+		Enabled:  d.Enabled,
+		Destination: &pbd.Location{
+			Latitude:  d.Destination.Latitude,
+			Longitude: d.Destination.Longitude,
+		},
+		MaxDetourKm: d.MaxDetourKm,
+	}
+}
+
+type signupRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Name     string `json:"name"`
+	Role     string `json:"role"`
+}
+
+func (s *signupRequest) ToProto() *pba.SignupRequest {
+	return &pba.SignupRequest{
+		Email:    s.Email,
+		Password: s.Password,
+		Name:     s.Name,
+		Role:     s.Role,
+	}
+}
+
+type loginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+func (l *loginRequest) ToProto() *pba.LoginRequest {
+	return &pba.LoginRequest{
+		Email:    l.Email,
+		Password: l.Password,
+	}
+}
+
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+func (r *refreshTokenRequest) ToProto() *pba.RefreshTokenRequest {
+	return &pba.RefreshTokenRequest{RefreshToken: r.RefreshToken}
+}
+
+type logoutRequest struct {
+	RefreshToken string `json:"refreshToken"`
+	AllSessions  bool   `json:"allSessions"`
+}
+
+func (l *logoutRequest) ToProto() *pba.RevokeTokenRequest {
+	return &pba.RevokeTokenRequest{
+		RefreshToken: l.RefreshToken,
+		AllSessions:  l.AllSessions,
+	}
+}
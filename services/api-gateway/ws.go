@@ -1,13 +1,27 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"ride-sharing/services/api-gateway/grpc_clients"
+	"ride-sharing/shared/auth"
 	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/env"
+	"ride-sharing/shared/messaging"
 	pb "ride-sharing/shared/proto/driver"
+	"time"
 
 	"github.com/gorilla/websocket"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// how often the gateway reports a connected driver alive to the driver service
+	driverHeartbeatInterval = env.GetDuration("DRIVER_HEARTBEAT_INTERVAL", 10*time.Second)
 )
 
 var upgrader = websocket.Upgrader{
@@ -16,43 +30,27 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-func handleDriverWs(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+func handleDriverWs(w http.ResponseWriter, r *http.Request, connManager *ConnectionManager, rabbitmq messaging.Broker, driverService *grpc_clients.DriverServiceClient, limiter *rateLimiter) {
+	// the socket belongs to the authenticated driver, whatever ?userID says
+	identity, _ := auth.FromContext(r.Context())
+	userID := identity.UserID
+
+	wsConn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("websocket upgrade failed: %v", err)
 		return
 	}
-	defer conn.Close()
-
-	userID := r.URL.Query().Get("userID")
-	if userID == "" {
-		log.Println("userID is required")
-		return
-	}
+	defer wsConn.Close()
 
 	packageSlug := r.URL.Query().Get("packageSlug")
-	if userID == "" {
+	if packageSlug == "" {
 		log.Println("packageSlug is required")
 		return
 	}
 
-	c, err := grpc_clients.NewDriverServiceClient()
-	if err != nil {
-		log.Fatal(err)
-	}
-
 	ctx := r.Context()
 
-	defer func() {
-		c.Client.UnregisterDriver(ctx, &pb.RegisterDriverRequest{
-			DriverID:    userID,
-			PackageSlug: packageSlug,
-		})
-		c.Close()
-		log.Printf("Driver Unregisterd ID: %v", userID)
-	}()
-
-	driver, err := c.Client.RegisterDriver(
+	driver, err := driverService.Client.RegisterDriver(
 		ctx,
 		&pb.RegisterDriverRequest{
 			DriverID:    userID,
@@ -61,10 +59,29 @@ func handleDriverWs(w http.ResponseWriter, r *http.Request) {
 	)
 	if err != nil {
 		log.Printf("failed to register driver: %v", err)
-		http.Error(w, "failed to register driver", http.StatusInternalServerError)
+		_, code := contracts.HTTPError(status.Code(err))
+		wsConn.WriteJSON(contracts.WSMessage{
+			Type: contracts.WSMessageTypeError,
+			Data: contracts.APIError{Code: code, Message: "failed to register driver"},
+		})
 		return
 	}
 
+	conn := connManager.Add(userID, roleDriver, wsConn)
+	defer func() {
+		// a driver that reconnected registered again from the socket that
+		// replaced this one, its registration must outlive this socket
+		if !connManager.Remove(conn) {
+			return
+		}
+
+		driverService.Client.UnregisterDriver(ctx, &pb.RegisterDriverRequest{
+			DriverID:    userID,
+			PackageSlug: packageSlug,
+		})
+		log.Printf("Driver Unregisterd ID: %v", userID)
+	}()
+
 	msg := contracts.WSMessage{
 		Type: "driver.cmd.register",
 		Data: driver.Driver,
@@ -74,21 +91,116 @@ func handleDriverWs(w http.ResponseWriter, r *http.Request) {
 		log.Printf("error sending message in websocket: %v", err)
 		return
 	}
+
+	heartbeatCtx, stopHeartbeat := context.WithCancel(ctx)
+	defer stopHeartbeat()
+	go sendDriverHeartbeats(heartbeatCtx, conn, driverService.Client, userID)
+
+	for {
+		_, rawMessage, err := conn.ReadMessage()
+		if err != nil {
+			log.Printf("error reading message in websocket: %v", err)
+			break
+		}
+
+		if ok, retryAfter := limiter.allowMessage(ctx, routeWSDriverMessages, userID); !ok {
+			writeWSRateLimited(conn, retryAfter)
+			continue
+		}
+
+		var message contracts.WSDriverMessage
+		if err := json.Unmarshal(rawMessage, &message); err != nil {
+			writeWSError(conn, codes.InvalidArgument, "fail to parse message")
+			continue
+		}
+
+		switch message.Type {
+		case contracts.DriverCmdDestination:
+			handleDriverDestination(ctx, conn, driverService.Client, userID, message.Data)
+		default:
+			handleDriverCommand(ctx, conn, rabbitmq, userID, message)
+		}
+	}
 }
 
-func handleRiderWs(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+// handleDriverDestination turns the driver's "heading home" filter on or off
+// and answers with the resulting preference.
+func handleDriverDestination(ctx context.Context, conn *wsConnection, client pb.DriverServiceClient, driverID string, data json.RawMessage) {
+	var request driverDestinationRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		writeWSError(conn, codes.InvalidArgument, "fail to parse destination data")
+		return
+	}
+
+	resp, err := client.SetDestinationPreference(ctx, request.ToProto(driverID))
 	if err != nil {
-		log.Printf("websocket upgrade failed: %v", err)
+		log.Printf("failed to set destination for driver %s: %v", driverID, err)
+		writeWSError(conn, status.Code(err), status.Convert(err).Message())
 		return
 	}
-	defer conn.Close()
 
-	userID := r.URL.Query().Get("userID")
-	if userID == "" {
-		log.Println("userID is required")
+	if err := conn.WriteJSON(contracts.WSMessage{Type: contracts.DriverCmdDestination, Data: resp}); err != nil {
+		log.Printf("error sending message in websocket: %v", err)
+	}
+}
+
+// writeWSError sends an error frame carrying the same error codes as the HTTP
+// API.
+func writeWSError(conn *wsConnection, code codes.Code, message string, details ...contracts.FieldError) {
+	_, apiCode := contracts.HTTPError(code)
+	msg := contracts.WSMessage{
+		Type: contracts.WSMessageTypeError,
+		Data: contracts.APIError{Code: apiCode, Message: message, Details: details},
+	}
+
+	if err := conn.WriteJSON(msg); err != nil {
+		log.Printf("error sending message in websocket: %v", err)
+	}
+}
+
+// writeWSRateLimited tells the client its message was dropped and when to send
+// again, the socket stays open.
+func writeWSRateLimited(conn *wsConnection, retryAfter time.Duration) {
+	writeWSError(conn, codes.ResourceExhausted, fmt.Sprintf("rate limit exceeded, retry in %ds", retryAfterSeconds(retryAfter)))
+}
+
+// sendDriverHeartbeats tells the driver service the driver is still connected,
+// until ctx is cancelled or the driver is gone.
+func sendDriverHeartbeats(ctx context.Context, conn *wsConnection, client pb.DriverServiceClient, driverID string) {
+	ticker := time.NewTicker(driverHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := client.Heartbeat(ctx, &pb.HeartbeatRequest{DriverID: driverID})
+			if status.Code(err) == codes.NotFound {
+				log.Printf("driver %s is no longer registered, closing socket", driverID)
+				conn.Close()
+				return
+			}
+			if err != nil {
+				log.Printf("failed to send heartbeat for driver %s: %v", driverID, err)
+			}
+		}
+	}
+}
+
+func handleRiderWs(w http.ResponseWriter, r *http.Request, connManager *ConnectionManager, limiter *rateLimiter) {
+	identity, _ := auth.FromContext(r.Context())
+	userID := identity.UserID
+
+	wsConn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("websocket upgrade failed: %v", err)
 		return
 	}
+	defer wsConn.Close()
+
+	conn := connManager.Add(userID, roleRider, wsConn)
+	defer connManager.Remove(conn)
 
 	for {
 		_, message, err := conn.ReadMessage()
@@ -97,6 +209,11 @@ func handleRiderWs(w http.ResponseWriter, r *http.Request) {
 			break
 		}
 
+		if ok, retryAfter := limiter.allowMessage(r.Context(), routeWSRiderMessages, userID); !ok {
+			writeWSRateLimited(conn, retryAfter)
+			continue
+		}
+
 		log.Printf("received message: %s", message)
 	}
 }
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"ride-sharing/services/api-gateway/grpc_clients"
+	"ride-sharing/shared/auth"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	pb "ride-sharing/shared/proto/driver"
+	"ride-sharing/shared/ratelimit"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeDriverService keeps the registrations like the driver service: a driver
+// registering again replaces its registration.
+type fakeDriverService struct {
+	pb.DriverServiceClient
+
+	mu           sync.Mutex
+	registered   map[string]bool
+	unregistered int
+}
+
+func (f *fakeDriverService) RegisterDriver(ctx context.Context, in *pb.RegisterDriverRequest, opts ...grpc.CallOption) (*pb.RegisterDriverResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.registered[in.DriverID] = true
+	return &pb.RegisterDriverResponse{Driver: &pb.Driver{Id: in.DriverID, PackageSlug: in.PackageSlug}}, nil
+}
+
+func (f *fakeDriverService) UnregisterDriver(ctx context.Context, in *pb.RegisterDriverRequest, opts ...grpc.CallOption) (*pb.RegisterDriverResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.registered, in.DriverID)
+	f.unregistered++
+	return &pb.RegisterDriverResponse{Driver: &pb.Driver{Id: in.DriverID}}, nil
+}
+
+func (f *fakeDriverService) Heartbeat(ctx context.Context, in *pb.HeartbeatRequest, opts ...grpc.CallOption) (*pb.HeartbeatResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.registered[in.DriverID] {
+		return nil, status.Error(codes.NotFound, "driver is not registered")
+	}
+	return &pb.HeartbeatResponse{LastSeenAt: time.Now().UnixMilli()}, nil
+}
+
+func (f *fakeDriverService) state() (registered bool, unregistered int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.registered["driver-1"], f.unregistered
+}
+
+// TestDriverReconnect connects a driver, reconnects it as after a network blip
+// and checks the closing of the old socket leaves the new one registered.
+func TestDriverReconnect(t *testing.T) {
+	broker := messaging.NewMemoryBroker(messaging.APIGatewayService)
+	defer broker.Close()
+	limiter, err := newRateLimiter(ratelimit.NewMemoryStore(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	drivers := &fakeDriverService{registered: make(map[string]bool)}
+	driverService := &grpc_clients.DriverServiceClient{Client: drivers}
+	connManager := NewConnectionManager()
+
+	var handlers atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.Add(1)
+		defer handlers.Add(-1)
+
+		identity := auth.Identity{UserID: "driver-1", Role: auth.RoleDriver}
+		handleDriverWs(w, r.WithContext(auth.NewContext(r.Context(), identity)), connManager, broker, driverService, limiter)
+	}))
+	defer server.Close()
+
+	first := connectDriver(t, server)
+	second := connectDriver(t, server)
+
+	// the new socket replaced the old one, whose handler returns
+	first.SetReadDeadline(time.Now().Add(time.Second))
+	if _, _, err := first.ReadMessage(); err == nil {
+		t.Fatal("old socket still open after the driver reconnected")
+	}
+	waitHandlers(t, &handlers, 1)
+
+	if registered, unregistered := drivers.state(); !registered || unregistered != 0 {
+		t.Fatalf("after the old socket closed: registered = %v with %d unregistrations, want registered", registered, unregistered)
+	}
+	if _, err := drivers.Heartbeat(context.Background(), &pb.HeartbeatRequest{DriverID: "driver-1"}); err != nil {
+		t.Fatalf("heartbeat of the new socket error = %v", err)
+	}
+
+	// the driver leaves for good
+	second.Close()
+	waitHandlers(t, &handlers, 0)
+
+	if registered, unregistered := drivers.state(); registered || unregistered != 1 {
+		t.Errorf("after the new socket closed: registered = %v with %d unregistrations, want unregistered once", registered, unregistered)
+	}
+}
+
+func waitHandlers(t *testing.T, handlers *atomic.Int32, n int32) {
+	t.Helper()
+
+	for deadline := time.Now().Add(time.Second); handlers.Load() != n; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d socket handlers running, want %d", handlers.Load(), n)
+		}
+	}
+}
+
+// connectDriver opens a driver socket and waits for its registration.
+func connectDriver(t *testing.T, server *httptest.Server) *websocket.Conn {
+	t.Helper()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/drivers?packageSlug=sedan"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("connect driver: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	var msg contracts.WSMessage
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if err := conn.ReadJSON(&msg); err != nil || msg.Type != "driver.cmd.register" {
+		t.Fatalf("driver received %v, %v, want its registration", msg.Type, err)
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	return conn
+}
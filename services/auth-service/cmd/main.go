@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"ride-sharing/services/auth-service/internal/domain"
+	"ride-sharing/services/auth-service/internal/infrastructure/grpc"
+	"ride-sharing/services/auth-service/internal/infrastructure/repository"
+	"ride-sharing/services/auth-service/internal/service"
+	"ride-sharing/shared/env"
+	"syscall"
+	"time"
+
+	grpcserver "google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+)
+
+var (
+	GrpcAddr = ":9094"
+
+	// set JWT_ISSUER of the gateway to the same value to pin the issuer
+	issuer = env.GetString("AUTH_ISSUER", "ride-sharing-auth")
+
+	// access tokens cannot be revoked, keep them short-lived
+	accessTokenTTL  = env.GetDuration("AUTH_ACCESS_TOKEN_TTL", 15*time.Minute)
+	refreshTokenTTL = env.GetDuration("AUTH_REFRESH_TOKEN_TTL", 30*24*time.Hour)
+
+	// PEM encoded RSA private key, a key is generated on startup when unset
+	signingKeyFile = env.GetString("AUTH_SIGNING_KEY_FILE", "")
+)
+
+func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		signalCh := make(chan os.Signal, 1)
+		signal.Notify(signalCh, syscall.SIGTERM)
+		<-signalCh
+		cancel()
+	}()
+
+	lis, err := net.Listen("tcp", GrpcAddr)
+	if err != nil {
+		log.Fatalf("failed to listen: %v", err)
+	}
+
+	// users are kept in memory unless a MongoDB is configured
+	var store domain.AuthRepository = repository.NewMemoryRepository()
+	if mongoURI := env.GetString("MONGODB_URI", ""); mongoURI != "" {
+		mongoClient, err := repository.Connect(ctx, mongoURI)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer mongoClient.Disconnect(context.Background())
+
+		store, err = repository.NewMongoRepository(ctx, mongoClient.Database(env.GetString("MONGODB_DATABASE", "ride-sharing")))
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	signingKey, err := service.LoadSigningKey(signingKeyFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if signingKeyFile == "" {
+		log.Println("no AUTH_SIGNING_KEY_FILE set, signing tokens with a generated key")
+	}
+
+	authService, err := service.NewAuthServiceImpl(store, service.NewTokenIssuer(signingKey, issuer, accessTokenTTL), refreshTokenTTL)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// starting the grpc server
+	grpcserver := grpcserver.NewServer(
+		// accept the keepalive pings of the api-gateway clients
+		grpcserver.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             10 * time.Second,
+			PermitWithoutStream: true,
+		}),
+	)
+	grpc.NewGRPCHandler(grpcserver, authService)
+
+	// report the serving status for client-side health checking
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcserver, healthServer)
+
+	log.Printf("starting grpc server Auth Service on port %s", lis.Addr().String())
+
+	go func() {
+		if err := grpcserver.Serve(lis); err != nil {
+			log.Printf("failed to serve: %v", err)
+			cancel()
+		}
+	}()
+
+	// wait for the shutdown signal
+	<-ctx.Done()
+	log.Println("shutting down gRPC server ...")
+	healthServer.Shutdown()
+	grpcserver.GracefulStop()
+}
@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pb "ride-sharing/shared/proto/auth"
+)
+
+var (
+	ErrUserExists          = errors.New("user already exists")
+	ErrUserNotFound        = errors.New("user not found")
+	ErrInvalidSignup       = errors.New("invalid signup")
+	ErrInvalidCredentials  = errors.New("invalid email or password")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+)
+
+// SignupError is a signup rejected because of one of its fields.
+type SignupError struct {
+	Field   string
+	Message string
+}
+
+func (e *SignupError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+func (e *SignupError) Unwrap() error {
+	return ErrInvalidSignup
+}
+
+type UserModel struct {
+	ID           string    `bson:"_id"`
+	Email        string    `bson:"email"`
+	Name         string    `bson:"name"`
+	Role         string    `bson:"role"`
+	PasswordHash []byte    `bson:"passwordHash"`
+	CreatedAt    time.Time `bson:"createdAt"`
+}
+
+func (u *UserModel) ToProto() *pb.User {
+	return &pb.User{
+		Id:    u.ID,
+		Email: u.Email,
+		Name:  u.Name,
+		Role:  u.Role,
+	}
+}
+
+// RefreshTokenModel is a refresh token handed to a user. Only the hash of the
+// token is stored, the token itself is never persisted.
+type RefreshTokenModel struct {
+	Hash      string     `bson:"_id"`
+	UserID    string     `bson:"userID"`
+	ExpiresAt time.Time  `bson:"expiresAt"`
+	RevokedAt *time.Time `bson:"revokedAt,omitempty"`
+	CreatedAt time.Time  `bson:"createdAt"`
+}
+
+func (t *RefreshTokenModel) Revoked() bool {
+	return t.RevokedAt != nil
+}
+
+// TokenPair is what a user receives on signup, login and refresh.
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
+	User         *UserModel
+}
+
+func (t *TokenPair) ToProto() *pb.TokenResponse {
+	return &pb.TokenResponse{
+		AccessToken:  t.AccessToken,
+		RefreshToken: t.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(t.ExpiresIn.Seconds()),
+		User:         t.User.ToProto(),
+	}
+}
+
+type AuthRepository interface {
+	CreateUser(ctx context.Context, user *UserModel) error
+	GetUserByEmail(ctx context.Context, email string) (*UserModel, error)
+	GetUserByID(ctx context.Context, id string) (*UserModel, error)
+
+	SaveRefreshToken(ctx context.Context, token *RefreshTokenModel) error
+	GetRefreshToken(ctx context.Context, hash string) (*RefreshTokenModel, error)
+	// RevokeRefreshToken fails with ErrInvalidRefreshToken when the token is
+	// unknown or revoked already, so that only one caller can revoke it.
+	RevokeRefreshToken(ctx context.Context, hash string, at time.Time) error
+	RevokeUserRefreshTokens(ctx context.Context, userID string, at time.Time) error
+}
+
+type AuthService interface {
+	Signup(ctx context.Context, email, password, name, role string) (*TokenPair, error)
+	Login(ctx context.Context, email, password string) (*TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (*TokenPair, error)
+	Revoke(ctx context.Context, refreshToken string, allSessions bool) error
+	JWKS() []*pb.JWK
+}
@@ -0,0 +1,87 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"log"
+	"ride-sharing/services/auth-service/internal/domain"
+	"ride-sharing/shared/contracts"
+	pb "ride-sharing/shared/proto/auth"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type gRPCHandler struct {
+	pb.UnimplementedAuthServiceServer
+	service domain.AuthService
+}
+
+func NewGRPCHandler(server *grpc.Server, service domain.AuthService) *gRPCHandler {
+	handler := &gRPCHandler{
+		service: service,
+	}
+
+	pb.RegisterAuthServiceServer(server, handler)
+	return handler
+}
+
+func (h *gRPCHandler) Signup(ctx context.Context, req *pb.SignupRequest) (*pb.TokenResponse, error) {
+	tokens, err := h.service.Signup(ctx, req.GetEmail(), req.GetPassword(), req.GetName(), req.GetRole())
+	if err != nil {
+		return nil, authError("failed to sign up", err)
+	}
+
+	return tokens.ToProto(), nil
+}
+
+func (h *gRPCHandler) Login(ctx context.Context, req *pb.LoginRequest) (*pb.TokenResponse, error) {
+	tokens, err := h.service.Login(ctx, req.GetEmail(), req.GetPassword())
+	if err != nil {
+		return nil, authError("failed to log in", err)
+	}
+
+	return tokens.ToProto(), nil
+}
+
+func (h *gRPCHandler) RefreshToken(ctx context.Context, req *pb.RefreshTokenRequest) (*pb.TokenResponse, error) {
+	tokens, err := h.service.Refresh(ctx, req.GetRefreshToken())
+	if err != nil {
+		return nil, authError("failed to refresh token", err)
+	}
+
+	return tokens.ToProto(), nil
+}
+
+func (h *gRPCHandler) RevokeToken(ctx context.Context, req *pb.RevokeTokenRequest) (*pb.RevokeTokenResponse, error) {
+	if err := h.service.Revoke(ctx, req.GetRefreshToken(), req.GetAllSessions()); err != nil {
+		return nil, authError("failed to revoke token", err)
+	}
+
+	return &pb.RevokeTokenResponse{}, nil
+}
+
+func (h *gRPCHandler) GetJWKS(ctx context.Context, req *pb.GetJWKSRequest) (*pb.JWKSResponse, error) {
+	return &pb.JWKSResponse{Keys: h.service.JWKS()}, nil
+}
+
+// authError maps the domain errors to their status code, anything else is an
+// internal error whose details are only logged.
+func authError(message string, err error) error {
+	var signupErr *domain.SignupError
+
+	switch {
+	case errors.As(err, &signupErr):
+		return contracts.InvalidArgumentError(message, contracts.FieldError{Field: signupErr.Field, Message: signupErr.Message})
+	case errors.Is(err, domain.ErrInvalidSignup):
+		return status.Errorf(codes.InvalidArgument, "%s: %v", message, err)
+	case errors.Is(err, domain.ErrUserExists):
+		return status.Errorf(codes.AlreadyExists, "%s: %v", message, err)
+	case errors.Is(err, domain.ErrInvalidCredentials), errors.Is(err, domain.ErrInvalidRefreshToken):
+		return status.Errorf(codes.Unauthenticated, "%s: %v", message, err)
+	default:
+		log.Printf("%s: %v", message, err)
+		return status.Error(codes.Internal, message)
+	}
+}
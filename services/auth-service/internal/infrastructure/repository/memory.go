@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"ride-sharing/services/auth-service/internal/domain"
+	"sync"
+	"time"
+)
+
+type MemoryRepository struct {
+	mu            sync.RWMutex
+	users         map[string]*domain.UserModel
+	usersByEmail  map[string]*domain.UserModel
+	refreshTokens map[string]*domain.RefreshTokenModel
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		users:         make(map[string]*domain.UserModel),
+		usersByEmail:  make(map[string]*domain.UserModel),
+		refreshTokens: make(map[string]*domain.RefreshTokenModel),
+	}
+}
+
+func (r *MemoryRepository) CreateUser(ctx context.Context, user *domain.UserModel) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.usersByEmail[user.Email]; exists {
+		return domain.ErrUserExists
+	}
+
+	r.users[user.ID] = user
+	r.usersByEmail[user.Email] = user
+	return nil
+}
+
+func (r *MemoryRepository) GetUserByEmail(ctx context.Context, email string) (*domain.UserModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, exists := r.usersByEmail[email]
+	if !exists {
+		return nil, domain.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+func (r *MemoryRepository) GetUserByID(ctx context.Context, id string) (*domain.UserModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, exists := r.users[id]
+	if !exists {
+		return nil, domain.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+func (r *MemoryRepository) SaveRefreshToken(ctx context.Context, token *domain.RefreshTokenModel) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.refreshTokens[token.Hash] = token
+	return nil
+}
+
+func (r *MemoryRepository) GetRefreshToken(ctx context.Context, hash string) (*domain.RefreshTokenModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	token, exists := r.refreshTokens[hash]
+	if !exists {
+		return nil, domain.ErrInvalidRefreshToken
+	}
+
+	// hand out a copy so revocations go through the repository
+	copied := *token
+	return &copied, nil
+}
+
+func (r *MemoryRepository) RevokeRefreshToken(ctx context.Context, hash string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, exists := r.refreshTokens[hash]
+	if !exists || token.RevokedAt != nil {
+		return domain.ErrInvalidRefreshToken
+	}
+
+	token.RevokedAt = &at
+	return nil
+}
+
+func (r *MemoryRepository) RevokeUserRefreshTokens(ctx context.Context, userID string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.refreshTokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &at
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ride-sharing/services/auth-service/internal/domain"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	usersCollection         = "users"
+	refreshTokensCollection = "refresh_tokens"
+)
+
+type MongoRepository struct {
+	users         *mongo.Collection
+	refreshTokens *mongo.Collection
+}
+
+// NewMongoRepository creates the indexes the repository relies on: unique
+// emails, and expired refresh tokens being removed by MongoDB.
+func NewMongoRepository(ctx context.Context, db *mongo.Database) (*MongoRepository, error) {
+	r := &MongoRepository{
+		users:         db.Collection(usersCollection),
+		refreshTokens: db.Collection(refreshTokensCollection),
+	}
+
+	_, err := r.users.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "email", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create users index: %v", err)
+	}
+
+	_, err = r.refreshTokens.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userID", Value: 1}}},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create refresh tokens indexes: %v", err)
+	}
+
+	return r, nil
+}
+
+func (r *MongoRepository) CreateUser(ctx context.Context, user *domain.UserModel) error {
+	_, err := r.users.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return domain.ErrUserExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to insert user: %v", err)
+	}
+
+	return nil
+}
+
+func (r *MongoRepository) GetUserByEmail(ctx context.Context, email string) (*domain.UserModel, error) {
+	return r.findUser(ctx, bson.M{"email": email})
+}
+
+func (r *MongoRepository) GetUserByID(ctx context.Context, id string) (*domain.UserModel, error) {
+	return r.findUser(ctx, bson.M{"_id": id})
+}
+
+func (r *MongoRepository) findUser(ctx context.Context, filter bson.M) (*domain.UserModel, error) {
+	var user domain.UserModel
+	err := r.users.FindOne(ctx, filter).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, domain.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %v", err)
+	}
+
+	return &user, nil
+}
+
+func (r *MongoRepository) SaveRefreshToken(ctx context.Context, token *domain.RefreshTokenModel) error {
+	if _, err := r.refreshTokens.InsertOne(ctx, token); err != nil {
+		return fmt.Errorf("failed to insert refresh token: %v", err)
+	}
+
+	return nil
+}
+
+func (r *MongoRepository) GetRefreshToken(ctx context.Context, hash string) (*domain.RefreshTokenModel, error) {
+	var token domain.RefreshTokenModel
+	err := r.refreshTokens.FindOne(ctx, bson.M{"_id": hash}).Decode(&token)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, domain.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find refresh token: %v", err)
+	}
+
+	return &token, nil
+}
+
+func (r *MongoRepository) RevokeRefreshToken(ctx context.Context, hash string, at time.Time) error {
+	result, err := r.refreshTokens.UpdateOne(ctx,
+		bson.M{"_id": hash, "revokedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revokedAt": at}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh token: %v", err)
+	}
+
+	if result.MatchedCount == 0 {
+		// either unknown or already revoked
+		return domain.ErrInvalidRefreshToken
+	}
+
+	return nil
+}
+
+func (r *MongoRepository) RevokeUserRefreshTokens(ctx context.Context, userID string, at time.Time) error {
+	_, err := r.refreshTokens.UpdateMany(ctx,
+		bson.M{"userID": userID, "revokedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revokedAt": at}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %v", err)
+	}
+
+	return nil
+}
+
+// Connect opens a client to the given URI and verifies it with a ping.
+func Connect(ctx context.Context, uri string) (*mongo.Client, error) {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to mongodb: %v", err)
+	}
+
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(ctx)
+		return nil, fmt.Errorf("failed to ping mongodb: %v", err)
+	}
+
+	return client, nil
+}
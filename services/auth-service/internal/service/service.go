@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+
+	"ride-sharing/services/auth-service/internal/domain"
+	"ride-sharing/shared/auth"
+	pb "ride-sharing/shared/proto/auth"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	minPasswordLength = 8
+	// bcrypt ignores anything past 72 bytes
+	maxPasswordLength = 72
+)
+
+type AuthServiceImpl struct {
+	repository domain.AuthRepository
+	issuer     *TokenIssuer
+	refreshTTL time.Duration
+	// compared against on unknown emails so that login takes as long as for
+	// known ones
+	dummyHash []byte
+}
+
+func NewAuthServiceImpl(repository domain.AuthRepository, issuer *TokenIssuer, refreshTTL time.Duration) (*AuthServiceImpl, error) {
+	dummyHash, err := bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthServiceImpl{
+		repository: repository,
+		issuer:     issuer,
+		refreshTTL: refreshTTL,
+		dummyHash:  dummyHash,
+	}, nil
+}
+
+// Signup registers a rider or a driver, admins cannot sign up themselves.
+func (s *AuthServiceImpl) Signup(ctx context.Context, email, password, name, role string) (*domain.TokenPair, error) {
+	email = normalizeEmail(email)
+	if err := validateSignup(email, password, role); err != nil {
+		return nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %v", err)
+	}
+
+	user := &domain.UserModel{
+		ID:           uuid.NewString(),
+		Email:        email,
+		Name:         strings.TrimSpace(name),
+		Role:         role,
+		PasswordHash: hash,
+		CreatedAt:    time.Now(),
+	}
+
+	if err := s.repository.CreateUser(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(ctx, user)
+}
+
+func (s *AuthServiceImpl) Login(ctx context.Context, email, password string) (*domain.TokenPair, error) {
+	user, err := s.repository.GetUserByEmail(ctx, normalizeEmail(email))
+	if errors.Is(err, domain.ErrUserNotFound) {
+		bcrypt.CompareHashAndPassword(s.dummyHash, []byte(password))
+		return nil, domain.ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(password)); err != nil {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	return s.issueTokens(ctx, user)
+}
+
+// Refresh exchanges a refresh token for a new pair, the old token being
+// revoked. Presenting a revoked token again means it leaked, so every session
+// of the user is revoked.
+func (s *AuthServiceImpl) Refresh(ctx context.Context, refreshToken string) (*domain.TokenPair, error) {
+	now := time.Now()
+
+	stored, err := s.repository.GetRefreshToken(ctx, hashRefreshToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+
+	if stored.Revoked() {
+		return nil, s.revokeReusedToken(ctx, stored, now)
+	}
+
+	if now.After(stored.ExpiresAt) {
+		return nil, domain.ErrInvalidRefreshToken
+	}
+
+	err = s.repository.RevokeRefreshToken(ctx, stored.Hash, now)
+	if errors.Is(err, domain.ErrInvalidRefreshToken) {
+		// a concurrent refresh with the same token revoked it first
+		return nil, s.revokeReusedToken(ctx, stored, now)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repository.GetUserByID(ctx, stored.UserID)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		return nil, domain.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(ctx, user)
+}
+
+// revokeReusedToken revokes every session of the user of a token presented
+// again after its revocation.
+func (s *AuthServiceImpl) revokeReusedToken(ctx context.Context, token *domain.RefreshTokenModel, now time.Time) error {
+	log.Printf("revoked refresh token reused for user %s, revoking all sessions", token.UserID)
+	if err := s.repository.RevokeUserRefreshTokens(ctx, token.UserID, now); err != nil {
+		return err
+	}
+
+	return domain.ErrInvalidRefreshToken
+}
+
+// Revoke logs the user out of the session of the token, or out of all of them.
+// Access tokens already issued stay valid until they expire.
+func (s *AuthServiceImpl) Revoke(ctx context.Context, refreshToken string, allSessions bool) error {
+	now := time.Now()
+
+	stored, err := s.repository.GetRefreshToken(ctx, hashRefreshToken(refreshToken))
+	if err != nil {
+		return err
+	}
+
+	if allSessions {
+		return s.repository.RevokeUserRefreshTokens(ctx, stored.UserID, now)
+	}
+
+	err = s.repository.RevokeRefreshToken(ctx, stored.Hash, now)
+	if errors.Is(err, domain.ErrInvalidRefreshToken) {
+		// logged out already
+		return nil
+	}
+
+	return err
+}
+
+func (s *AuthServiceImpl) JWKS() []*pb.JWK {
+	jwk := s.issuer.JWK()
+
+	return []*pb.JWK{{
+		Kty: jwk.Kty,
+		Use: jwk.Use,
+		Alg: jwk.Alg,
+		Kid: jwk.Kid,
+		N:   jwk.N,
+		E:   jwk.E,
+	}}
+}
+
+func (s *AuthServiceImpl) issueTokens(ctx context.Context, user *domain.UserModel) (*domain.TokenPair, error) {
+	now := time.Now()
+
+	accessToken, err := s.issuer.Issue(user, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign access token: %v", err)
+	}
+
+	refreshToken, hash, err := newRefreshToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %v", err)
+	}
+
+	err = s.repository.SaveRefreshToken(ctx, &domain.RefreshTokenModel{
+		Hash:      hash,
+		UserID:    user.ID,
+		ExpiresAt: now.Add(s.refreshTTL),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    s.issuer.ttl,
+		User:         user,
+	}, nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func validateSignup(email, password, role string) error {
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return &domain.SignupError{Field: "email", Message: "must be a valid email address"}
+	}
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return &domain.SignupError{Field: "password", Message: fmt.Sprintf("must be %d to %d characters long", minPasswordLength, maxPasswordLength)}
+	}
+	if role != auth.RoleRider && role != auth.RoleDriver {
+		return &domain.SignupError{Field: "role", Message: fmt.Sprintf("must be %s or %s", auth.RoleRider, auth.RoleDriver)}
+	}
+
+	return nil
+}
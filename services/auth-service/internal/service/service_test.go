@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"ride-sharing/services/auth-service/internal/domain"
+	"ride-sharing/services/auth-service/internal/infrastructure/repository"
+	"ride-sharing/shared/auth"
+)
+
+func newTestService(t *testing.T, repo domain.AuthRepository) *AuthServiceImpl {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service, err := NewAuthServiceImpl(repo, NewTokenIssuer(key, "test", time.Minute), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return service
+}
+
+// barrierRepository holds the refresh token reads until all the callers read
+// it, so that they all find it unrevoked.
+type barrierRepository struct {
+	domain.AuthRepository
+	readers sync.WaitGroup
+}
+
+func (r *barrierRepository) GetRefreshToken(ctx context.Context, hash string) (*domain.RefreshTokenModel, error) {
+	token, err := r.AuthRepository.GetRefreshToken(ctx, hash)
+	r.readers.Done()
+	r.readers.Wait()
+	return token, err
+}
+
+func TestConcurrentRefreshWithSameToken(t *testing.T) {
+	const callers = 10
+
+	ctx := context.Background()
+	repo := &barrierRepository{AuthRepository: repository.NewMemoryRepository()}
+	service := newTestService(t, repo)
+
+	pair, err := service.Signup(ctx, "rider@example.com", "password123", "Rider", auth.RoleRider)
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+
+	repo.readers.Add(callers)
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		refreshed []*domain.TokenPair
+	)
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			next, err := service.Refresh(ctx, pair.RefreshToken)
+			if err != nil {
+				if !errors.Is(err, domain.ErrInvalidRefreshToken) {
+					t.Errorf("Refresh() error = %v, want %v", err, domain.ErrInvalidRefreshToken)
+				}
+				return
+			}
+
+			mu.Lock()
+			refreshed = append(refreshed, next)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if len(refreshed) != 1 {
+		t.Fatalf("%d of %d refreshes with the same token succeeded, want 1", len(refreshed), callers)
+	}
+
+	// the reuse revoked the sessions of the user, the winner's included
+	repo.readers.Add(1)
+	if _, err := service.Refresh(ctx, refreshed[0].RefreshToken); !errors.Is(err, domain.ErrInvalidRefreshToken) {
+		t.Errorf("Refresh() with the rotated token error = %v, want %v", err, domain.ErrInvalidRefreshToken)
+	}
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	ctx := context.Background()
+	service := newTestService(t, repository.NewMemoryRepository())
+
+	pair, err := service.Signup(ctx, "driver@example.com", "password123", "Driver", auth.RoleDriver)
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+
+	next, err := service.Refresh(ctx, pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if _, err := service.Refresh(ctx, next.RefreshToken); err != nil {
+		t.Fatalf("Refresh() with the rotated token error = %v", err)
+	}
+}
+
+func TestRevokeTwice(t *testing.T) {
+	ctx := context.Background()
+	service := newTestService(t, repository.NewMemoryRepository())
+
+	pair, err := service.Signup(ctx, "rider@example.com", "password123", "Rider", auth.RoleRider)
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+
+	for range 2 {
+		if err := service.Revoke(ctx, pair.RefreshToken, false); err != nil {
+			t.Fatalf("Revoke() error = %v", err)
+		}
+	}
+	if _, err := service.Refresh(ctx, pair.RefreshToken); !errors.Is(err, domain.ErrInvalidRefreshToken) {
+		t.Errorf("Refresh() after Revoke() error = %v, want %v", err, domain.ErrInvalidRefreshToken)
+	}
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	"ride-sharing/services/auth-service/internal/domain"
+	"ride-sharing/shared/auth"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// TokenIssuer signs RS256 access tokens with a single private key, advertised
+// in the JWKS under a kid derived from its public half.
+type TokenIssuer struct {
+	key    *rsa.PrivateKey
+	kid    string
+	issuer string
+	ttl    time.Duration
+}
+
+func NewTokenIssuer(key *rsa.PrivateKey, issuer string, ttl time.Duration) *TokenIssuer {
+	return &TokenIssuer{
+		key:    key,
+		kid:    auth.RSAKeyID(&key.PublicKey),
+		issuer: issuer,
+		ttl:    ttl,
+	}
+}
+
+// LoadSigningKey reads a PEM encoded RSA private key, or generates one when no
+// file is given. Generated keys do not survive restarts and differ between
+// replicas, so they are only fit for development.
+func LoadSigningKey(file string) (*rsa.PrivateKey, error) {
+	if file == "" {
+		return rsa.GenerateKey(rand.Reader, 2048)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %v", err)
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key: %v", err)
+	}
+
+	return key, nil
+}
+
+func (t *TokenIssuer) Issue(user *domain.UserModel, now time.Time) (string, error) {
+	claims := auth.Claims{
+		Role: user.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    t.issuer,
+			Subject:   user.ID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(t.ttl)),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = t.kid
+
+	return token.SignedString(t.key)
+}
+
+func (t *TokenIssuer) JWK() auth.JWK {
+	return auth.NewRSAJWK(t.kid, &t.key.PublicKey)
+}
+
+// newRefreshToken returns an opaque token and the hash it is stored under.
+func newRefreshToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashRefreshToken(token), nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
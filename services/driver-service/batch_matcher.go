@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"ride-sharing/shared/matching"
+	"ride-sharing/shared/messaging"
+	pb "ride-sharing/shared/proto/driver"
+	tripPb "ride-sharing/shared/proto/trip"
+	"sync"
+	"time"
+)
+
+const (
+	MatchingModeGreedy = "greedy"
+	MatchingModeBatch  = "batch"
+
+	// average city speed used to turn pickup distance into pickup time
+	averagePickupSpeedKMH = 30.0
+)
+
+var errBatchMatcherStopped = errors.New("batch matcher stopped")
+
+// matchResultHandler is called for every trip of a batch, with an empty
+// driverID when no suitable driver was left for the trip.
+type matchResultHandler func(ctx context.Context, payload messaging.TripEventData, driverID string) error
+
+// batchMatcher collects pending trips for a short window and assigns them to
+// drivers all at once, minimizing the total pickup time of the batch.
+type batchMatcher struct {
+	service *DriverService
+	window  time.Duration
+
+	mu      sync.Mutex
+	pending []*pendingTrip
+	stopped bool
+}
+
+// pendingTrip is a trip waiting for the next flush, done receives the result
+// of its notification.
+type pendingTrip struct {
+	ctx     context.Context
+	payload messaging.TripEventData
+	done    chan error
+}
+
+func NewBatchMatcher(service *DriverService, window time.Duration) *batchMatcher {
+	return &batchMatcher{
+		service: service,
+		window:  window,
+	}
+}
+
+// Enqueue adds the trip to the current batch and waits until its match has
+// been notified, so that the message is only acked once the match is out.
+// When ctx is done before the flush, the trip leaves the batch.
+func (b *batchMatcher) Enqueue(ctx context.Context, payload messaging.TripEventData) error {
+	trip := &pendingTrip{ctx: ctx, payload: payload, done: make(chan error, 1)}
+
+	b.mu.Lock()
+	if b.stopped {
+		b.mu.Unlock()
+		return errBatchMatcherStopped
+	}
+	b.pending = append(b.pending, trip)
+	b.mu.Unlock()
+
+	select {
+	case err := <-trip.done:
+		return err
+	case <-ctx.Done():
+		if b.remove(trip) {
+			return ctx.Err()
+		}
+		// already being flushed
+		return <-trip.done
+	}
+}
+
+// remove takes the trip out of the batch, it returns false when a flush took
+// it already.
+func (b *batchMatcher) remove(trip *pendingTrip) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, p := range b.pending {
+		if p == trip {
+			b.pending = append(b.pending[:i], b.pending[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// Run flushes the pending trips every window until ctx is cancelled, then
+// flushes the last batch and refuses new trips.
+func (b *batchMatcher) Run(ctx context.Context, onResult matchResultHandler) {
+	ticker := time.NewTicker(b.window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			b.mu.Lock()
+			b.stopped = true
+			b.mu.Unlock()
+
+			b.flush(onResult)
+			return
+		case <-ticker.C:
+			b.flush(onResult)
+		}
+	}
+}
+
+// flush matches the pending trips and notifies every match with the context
+// of its trip. The drivers offered a trip are marked busy by the handler, so
+// that the next batch does not offer them again.
+func (b *batchMatcher) flush(onResult matchResultHandler) {
+	b.mu.Lock()
+	trips := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+
+	if len(trips) == 0 {
+		return
+	}
+
+	payloads := make([]messaging.TripEventData, len(trips))
+	for i, trip := range trips {
+		payloads[i] = trip.payload
+	}
+
+	drivers := b.service.AvailableDrivers()
+	assignment := matching.Optimal(buildPickupCostMatrix(payloads, drivers, b.service.DriverAcceptsTrip, b.service.RankingPenalty))
+
+	log.Printf("batch matched %d trips against %d drivers", len(trips), len(drivers))
+
+	for i, trip := range trips {
+		driverID := ""
+		if j := assignment[i]; j != matching.Unassigned {
+			driverID = drivers[j].Id
+		}
+
+		err := onResult(trip.ctx, trip.payload, driverID)
+		if err != nil {
+			log.Printf("failed to notify batch match for trip %s: %v", trip.payload.Trip.GetId(), err)
+		}
+		trip.done <- err
+	}
+}
+
+// buildPickupCostMatrix returns the estimated pickup time in seconds, plus the
+// driver's ranking penalty, for every trip (rows) and driver (columns); pairs
+// the driver does not accept (another package, destination filter) are infeasible.
+func buildPickupCostMatrix(
+	trips []messaging.TripEventData,
+	drivers []*pb.Driver,
+	accepts func(driverID string, trip *tripPb.Trip) bool,
+	penalty func(driverID string) time.Duration,
+) [][]float64 {
+	cost := make([][]float64, len(trips))
+
+	penalties := make([]float64, len(drivers))
+	for j, driver := range drivers {
+		penalties[j] = penalty(driver.Id).Seconds()
+	}
+
+	for i, trip := range trips {
+		cost[i] = make([]float64, len(drivers))
+		pickupLat, pickupLon, hasPickup := tripPickup(trip.Trip)
+
+		for j, driver := range drivers {
+			if !hasPickup || !accepts(driver.Id, trip.Trip) {
+				cost[i][j] = matching.Infeasible
+				continue
+			}
+
+			distance := matching.HaversineKM(
+				driver.GetLocation().GetLatitude(), driver.GetLocation().GetLongitude(),
+				pickupLat, pickupLon,
+			)
+			cost[i][j] = matching.PickupSeconds(distance, averagePickupSpeedKMH) + penalties[j]
+		}
+	}
+
+	return cost
+}
+
+// tripPickup returns the first point of the trip route.
+func tripPickup(trip *tripPb.Trip) (lat, lon float64, ok bool) {
+	geometry := trip.GetRoute().GetGeometry()
+	if len(geometry) == 0 || len(geometry[0].GetCoordinates()) == 0 {
+		return 0, 0, false
+	}
+
+	lat, lon = routePointLatLon(geometry[0].GetCoordinates()[0])
+	return lat, lon, true
+}
+
+// routePointLatLon reads a route coordinate. Routes keep OSRM's [lon, lat]
+// order, so the proto Latitude field actually holds the longitude.
+func routePointLatLon(c *tripPb.Coordinate) (lat, lon float64) {
+	return c.GetLongitude(), c.GetLatitude()
+}
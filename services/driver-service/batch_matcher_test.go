@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"ride-sharing/shared/messaging"
+	pb "ride-sharing/shared/proto/driver"
+	tripPb "ride-sharing/shared/proto/trip"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordedMatches is a matchResultHandler keeping the driver offered each trip
+// and marking it busy like notifyMatch does.
+type recordedMatches struct {
+	service *DriverService
+
+	mu      sync.Mutex
+	drivers map[string]string
+}
+
+func (r *recordedMatches) notify(ctx context.Context, payload messaging.TripEventData, driverID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.drivers[payload.Trip.Id] = driverID
+	if driverID != "" {
+		r.service.RecordOffer(driverID, payload.Trip)
+	}
+	return nil
+}
+
+func (r *recordedMatches) driver(tripID string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	driverID, ok := r.drivers[tripID]
+	return driverID, ok
+}
+
+func TestBatchMatcherMinimizesPickupTime(t *testing.T) {
+	service := newMatchingService(t, map[string][2]float64{
+		"driver-1": {37.7700, -122.4200},
+		"driver-2": {37.7800, -122.4200},
+	})
+	batch := NewBatchMatcher(service, time.Hour)
+	matches := &recordedMatches{service: service, drivers: make(map[string]string)}
+
+	// first come matching could give driver-1 to trip-1 and send driver-2 far
+	// away for trip-2
+	trips := []messaging.TripEventData{
+		newTripEvent("trip-1", 37.7750, -122.4200),
+		newTripEvent("trip-2", 37.7690, -122.4200),
+	}
+
+	var wg sync.WaitGroup
+	for _, trip := range trips {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := batch.Enqueue(context.Background(), trip); err != nil {
+				t.Errorf("Enqueue(%s) error = %v", trip.Trip.Id, err)
+			}
+		}()
+	}
+	waitPending(t, batch, len(trips))
+
+	batch.flush(matches.notify)
+	wg.Wait()
+
+	for tripID, want := range map[string]string{"trip-1": "driver-2", "trip-2": "driver-1"} {
+		if got, _ := matches.driver(tripID); got != want {
+			t.Errorf("%s offered to %q, want %q", tripID, got, want)
+		}
+	}
+}
+
+func TestBatchMatcherSkipsBusyDrivers(t *testing.T) {
+	service := newMatchingService(t, map[string][2]float64{
+		"driver-1": {37.7700, -122.4200},
+	})
+	matches, run := runBatchMatcher(t, service, 10*time.Millisecond)
+	defer run()
+
+	if err := matches.enqueue(newTripEvent("trip-1", 37.7750, -122.4200)); err != nil {
+		t.Fatalf("Enqueue(trip-1) error = %v", err)
+	}
+	if got, _ := matches.driver("trip-1"); got != "driver-1" {
+		t.Fatalf("trip-1 offered to %q, want driver-1", got)
+	}
+
+	// the offer of trip-1 is pending
+	if err := matches.enqueue(newTripEvent("trip-2", 37.7750, -122.4200)); err != nil {
+		t.Fatalf("Enqueue(trip-2) error = %v", err)
+	}
+	if got, _ := matches.driver("trip-2"); got != "" {
+		t.Fatalf("trip-2 offered to busy %q", got)
+	}
+
+	// declining frees the driver
+	service.RecordOfferResponse("driver-1", "trip-1", false)
+	if err := matches.enqueue(newTripEvent("trip-3", 37.7750, -122.4200)); err != nil {
+		t.Fatalf("Enqueue(trip-3) error = %v", err)
+	}
+	if got, _ := matches.driver("trip-3"); got != "driver-1" {
+		t.Fatalf("trip-3 offered to %q, want driver-1", got)
+	}
+
+	// accepting keeps it busy
+	service.RecordOfferResponse("driver-1", "trip-3", true)
+	if err := matches.enqueue(newTripEvent("trip-4", 37.7750, -122.4200)); err != nil {
+		t.Fatalf("Enqueue(trip-4) error = %v", err)
+	}
+	if got, _ := matches.driver("trip-4"); got != "" {
+		t.Fatalf("trip-4 offered to %q on a trip", got)
+	}
+}
+
+func TestBatchMatcherReturnsNotifyError(t *testing.T) {
+	service := newMatchingService(t, nil)
+	batch := NewBatchMatcher(service, 10*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errPublish := errors.New("publish failed")
+	go batch.Run(ctx, func(context.Context, messaging.TripEventData, string) error {
+		return errPublish
+	})
+
+	// the message is not acked when the match could not be notified
+	if err := batch.Enqueue(context.Background(), newTripEvent("trip-1", 37.7750, -122.4200)); !errors.Is(err, errPublish) {
+		t.Fatalf("Enqueue() error = %v, want %v", err, errPublish)
+	}
+}
+
+func TestBatchMatcherFlushesOnStop(t *testing.T) {
+	service := newMatchingService(t, map[string][2]float64{
+		"driver-1": {37.7700, -122.4200},
+	})
+	// the window never ends before the matcher stops
+	matches, stop := runBatchMatcher(t, service, time.Hour)
+
+	enqueued := make(chan error)
+	go func() {
+		enqueued <- matches.enqueue(newTripEvent("trip-1", 37.7750, -122.4200))
+	}()
+	waitPending(t, matches.batch, 1)
+
+	stop()
+
+	if err := <-enqueued; err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if got, _ := matches.driver("trip-1"); got != "driver-1" {
+		t.Fatalf("trip-1 offered to %q, want driver-1", got)
+	}
+
+	if err := matches.enqueue(newTripEvent("trip-2", 37.7750, -122.4200)); !errors.Is(err, errBatchMatcherStopped) {
+		t.Fatalf("Enqueue() after stop error = %v, want %v", err, errBatchMatcherStopped)
+	}
+}
+
+func TestBatchMatcherEnqueueCancelled(t *testing.T) {
+	service := newMatchingService(t, map[string][2]float64{
+		"driver-1": {37.7700, -122.4200},
+	})
+	matches, stop := runBatchMatcher(t, service, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	enqueued := make(chan error)
+	go func() {
+		enqueued <- matches.batch.Enqueue(ctx, newTripEvent("trip-1", 37.7750, -122.4200))
+	}()
+	waitPending(t, matches.batch, 1)
+
+	cancel()
+	if err := <-enqueued; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Enqueue() error = %v, want %v", err, context.Canceled)
+	}
+
+	// the redelivered message is matched instead
+	stop()
+	if _, ok := matches.driver("trip-1"); ok {
+		t.Fatal("cancelled trip-1 was matched")
+	}
+}
+
+type batchMatcherRun struct {
+	*recordedMatches
+	batch *batchMatcher
+}
+
+func (r *batchMatcherRun) enqueue(payload messaging.TripEventData) error {
+	return r.batch.Enqueue(context.Background(), payload)
+}
+
+// runBatchMatcher starts a batch matcher, the returned function stops it and
+// waits for its last flush.
+func runBatchMatcher(t *testing.T, service *DriverService, window time.Duration) (*batchMatcherRun, func()) {
+	t.Helper()
+
+	run := &batchMatcherRun{
+		recordedMatches: &recordedMatches{service: service, drivers: make(map[string]string)},
+		batch:           NewBatchMatcher(service, window),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		run.batch.Run(ctx, run.notify)
+	}()
+
+	return run, func() {
+		cancel()
+		<-done
+	}
+}
+
+func waitPending(t *testing.T, batch *batchMatcher, n int) {
+	t.Helper()
+
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		batch.mu.Lock()
+		pending := len(batch.pending)
+		batch.mu.Unlock()
+
+		if pending == n {
+			return
+		}
+	}
+	t.Fatalf("batch never had %d pending trips", n)
+}
+
+// newMatchingService registers sedan drivers at the given locations.
+func newMatchingService(t *testing.T, locations map[string][2]float64) *DriverService {
+	t.Helper()
+
+	service := NewDriverService(NewMemoryProfileRepository(), false, rankingPenalties{}, 2)
+	for driverID, location := range locations {
+		driver, err := service.RegisterDriver(context.Background(), driverID, "sedan")
+		if err != nil {
+			t.Fatalf("RegisterDriver(%s) error = %v", driverID, err)
+		}
+		driver.Location = &pb.Location{Latitude: location[0], Longitude: location[1]}
+	}
+
+	return service
+}
+
+// newTripEvent returns a sedan trip picking the rider up at lat, lon.
+func newTripEvent(tripID string, lat, lon float64) messaging.TripEventData {
+	return messaging.TripEventData{Trip: &tripPb.Trip{
+		Id:               tripID,
+		UserID:           "rider-" + tripID,
+		SelectedRideFare: &tripPb.RideFare{PackageSlug: "sedan"},
+		Route: &tripPb.Route{Geometry: []*tripPb.Geometry{{
+			// routes keep OSRM's [lon, lat] order
+			Coordinates: []*tripPb.Coordinate{{Latitude: lon, Longitude: lat}},
+		}}},
+	}}
+}
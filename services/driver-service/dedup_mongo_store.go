@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const handledMessagesCollection = "handled_messages"
+
+type handledMessage struct {
+	Key       string    `bson:"_id"`
+	Handled   bool      `bson:"handled"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+// mongoDedupStore shares the handled messages between the replicas and keeps
+// them across restarts. MongoDB deletes the expired documents in the
+// background, until then they are ignored.
+type mongoDedupStore struct {
+	collection *mongo.Collection
+}
+
+func NewMongoDedupStore(ctx context.Context, db *mongo.Database) (*mongoDedupStore, error) {
+	collection := db.Collection(handledMessagesCollection)
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the handled messages index: %v", err)
+	}
+
+	return &mongoDedupStore{collection: collection}, nil
+}
+
+func (s *mongoDedupStore) Claim(ctx context.Context, key string, lease time.Duration) (bool, error) {
+	now := time.Now()
+
+	_, err := s.collection.InsertOne(ctx, handledMessage{Key: key, ExpiresAt: now.Add(lease)})
+	if err == nil {
+		return true, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return false, fmt.Errorf("failed to claim message: %v", err)
+	}
+
+	// take over a claim or a handled message that expired
+	err = s.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": key, "expiresAt": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"handled": false, "expiresAt": now.Add(lease)}},
+	).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to claim message: %v", err)
+	}
+
+	return true, nil
+}
+
+func (s *mongoDedupStore) Complete(ctx context.Context, key string, ttl time.Duration) error {
+	_, err := s.collection.UpdateOne(ctx,
+		bson.M{"_id": key},
+		bson.M{"$set": bson.M{"handled": true, "expiresAt": time.Now().Add(ttl)}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to mark message as handled: %v", err)
+	}
+
+	return nil
+}
+
+func (s *mongoDedupStore) Release(ctx context.Context, key string) error {
+	_, err := s.collection.DeleteOne(ctx, bson.M{"_id": key, "handled": false})
+	if err != nil {
+		return fmt.Errorf("failed to release message: %v", err)
+	}
+
+	return nil
+}
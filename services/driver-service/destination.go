@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"ride-sharing/shared/matching"
+	pb "ride-sharing/shared/proto/driver"
+	tripPb "ride-sharing/shared/proto/trip"
+	"time"
+)
+
+var (
+	ErrInvalidDestination      = errors.New("destination and a positive max detour are required")
+	ErrDestinationLimitReached = errors.New("daily destination filter limit reached")
+)
+
+// destinationPreference restricts the trips offered to a driver to those that
+// bring the driver closer to a destination, e.g. home at the end of a shift.
+type destinationPreference struct {
+	Destination *pb.Location
+	MaxDetourKM float64
+}
+
+// destinationUsage counts the destinations a driver set in a day.
+type destinationUsage struct {
+	Day   string
+	Count int
+}
+
+// sameDestination reports whether p is on and heads to the destination of
+// other.
+func (p *destinationPreference) sameDestination(other *destinationPreference) bool {
+	return p != nil &&
+		p.Destination.GetLatitude() == other.Destination.GetLatitude() &&
+		p.Destination.GetLongitude() == other.Destination.GetLongitude()
+}
+
+// Accepts reports whether driving from the driver's location to the pickup,
+// then to the drop-off and finally to the destination adds at most MaxDetourKM
+// to driving straight to the destination.
+func (p *destinationPreference) Accepts(driverLocation *pb.Location, trip *tripPb.Trip) bool {
+	geometry := trip.GetRoute().GetGeometry()
+	if len(geometry) == 0 || len(geometry[0].GetCoordinates()) == 0 {
+		return false
+	}
+
+	coordinates := geometry[0].GetCoordinates()
+	pickupLat, pickupLon := routePointLatLon(coordinates[0])
+	dropoffLat, dropoffLon := routePointLatLon(coordinates[len(coordinates)-1])
+
+	driverLat, driverLon := driverLocation.GetLatitude(), driverLocation.GetLongitude()
+	destLat, destLon := p.Destination.GetLatitude(), p.Destination.GetLongitude()
+
+	direct := matching.HaversineKM(driverLat, driverLon, destLat, destLon)
+	viaTrip := matching.HaversineKM(driverLat, driverLon, pickupLat, pickupLon) +
+		matching.HaversineKM(pickupLat, pickupLon, dropoffLat, dropoffLon) +
+		matching.HaversineKM(dropoffLat, dropoffLon, destLat, destLon)
+
+	return viaTrip-direct <= p.MaxDetourKM
+}
+
+func usageDay(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"log"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+)
+
+// driverConsumer tracks how drivers respond to trip offers, to feed the
+// acceptance and cancellation metrics used when ranking drivers.
+type driverConsumer struct {
+	rabbitmq messaging.Broker
+	service  *DriverService
+}
+
+func NewDriverConsumer(rabbitmq messaging.Broker, service *DriverService) *driverConsumer {
+	return &driverConsumer{
+		rabbitmq: rabbitmq,
+		service:  service,
+	}
+}
+
+func (d *driverConsumer) Listen(opts ...messaging.ConsumerOption) error {
+	return messaging.Subscribe(d.rabbitmq, messaging.DriverOfferOutcomesQueue, func(ctx context.Context, event messaging.Event[messaging.DriverTripResponseData]) error {
+		payload := event.Payload
+
+		driverID := payload.Driver.GetId()
+
+		var recorded bool
+		switch event.Type {
+		case contracts.DriverCmdTripAccept:
+			recorded = d.service.RecordOfferResponse(driverID, payload.TripID, true)
+		case contracts.DriverCmdTripDecline:
+			recorded = d.service.RecordOfferResponse(driverID, payload.TripID, false)
+		case contracts.DriverCmdTripCancel:
+			recorded = d.service.RecordCancellation(driverID, payload.TripID)
+		default:
+			log.Println("unknown driver event")
+			return nil
+		}
+
+		// unknown trips or offers answered already must not skew the metrics
+		if !recorded {
+			log.Printf("ignoring %s of driver %s for trip %s without a matching offer", event.Type, driverID, payload.TripID)
+		}
+
+		return nil
+	}, opts...)
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	pb "ride-sharing/shared/proto/driver"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidRating   = errors.New("rating must be between 1 and 5")
+	ErrTripNotRateable = errors.New("trip cannot be rated")
+)
+
+// driverStats aggregates the offer outcomes and rider ratings of a driver.
+type driverStats struct {
+	Offers        int
+	Accepted      int
+	Declined      int
+	Cancelled     int
+	Responses     int
+	ResponseTotal time.Duration
+	RatingSum     int
+	RatingCount   int
+}
+
+const (
+	// an offer left unanswered for longer is forgotten, the driver's late
+	// response is ignored
+	offerResponseTimeout = 5 * time.Minute
+	// how long the rider of a completed trip can rate the driver
+	tripRatingWindow = 24 * time.Hour
+)
+
+// tripOffer is a trip offer awaiting the driver's response, or an accepted trip
+// awaiting the rider's rating. It is forgotten once answered or expired.
+type tripOffer struct {
+	DriverID  string
+	RiderID   string
+	SentAt    time.Time
+	ExpiresAt time.Time
+	Accepted  bool
+	// Duration is the route's estimated duration, zero when unknown. An accepted
+	// trip is completed at CompletesAt, never when its duration is unknown.
+	Duration    time.Duration
+	CompletesAt time.Time
+}
+
+// Completed reports whether the accepted trip has ended.
+func (o *tripOffer) Completed(now time.Time) bool {
+	return o.Accepted && !o.CompletesAt.IsZero() && !now.Before(o.CompletesAt)
+}
+
+type driverStatsStore struct {
+	mu     sync.Mutex
+	stats  map[string]*driverStats
+	offers map[string]*tripOffer
+}
+
+func newDriverStatsStore() *driverStatsStore {
+	return &driverStatsStore{
+		stats:  make(map[string]*driverStats),
+		offers: make(map[string]*tripOffer),
+	}
+}
+
+func (s *driverStatsStore) get(driverID string) *driverStats {
+	stats, ok := s.stats[driverID]
+	if !ok {
+		stats = &driverStats{}
+		s.stats[driverID] = stats
+	}
+
+	return stats
+}
+
+func (s *driverStatsStore) RecordOffer(driverID, tripID, riderID string, duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	s.get(driverID).Offers++
+	s.offers[tripID] = &tripOffer{
+		DriverID:  driverID,
+		RiderID:   riderID,
+		SentAt:    now,
+		ExpiresAt: now.Add(offerResponseTimeout),
+		Duration:  duration,
+	}
+}
+
+// RecordResponse counts an accept or decline for the trip offer, along with the
+// time the driver took to respond. It returns false, counting nothing, when the
+// trip was not offered to the driver or the offer was answered or expired.
+func (s *driverStatsStore) RecordResponse(driverID, tripID string, accepted bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	offer, ok := s.offer(tripID, driverID, now)
+	if !ok || offer.Accepted {
+		return false
+	}
+
+	stats := s.get(driverID)
+	stats.Responses++
+	stats.ResponseTotal += now.Sub(offer.SentAt)
+
+	if accepted {
+		stats.Accepted++
+		offer.Accepted = true
+		offer.ExpiresAt = now.Add(tripRatingWindow)
+		if offer.Duration > 0 {
+			offer.CompletesAt = now.Add(offer.Duration)
+			offer.ExpiresAt = offer.CompletesAt.Add(tripRatingWindow)
+		}
+	} else {
+		stats.Declined++
+		delete(s.offers, tripID)
+	}
+
+	return true
+}
+
+// RecordCancellation counts the cancellation of a trip the driver accepted and
+// did not complete. It returns false, counting nothing, for any other trip.
+func (s *driverStatsStore) RecordCancellation(driverID, tripID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	offer, ok := s.offer(tripID, driverID, now)
+	if !ok || !offer.Accepted || offer.Completed(now) {
+		return false
+	}
+
+	s.get(driverID).Cancelled++
+	delete(s.offers, tripID)
+
+	return true
+}
+
+// RecordRating stores the rider's rating of the driver for a completed trip.
+// Each trip can only be rated once and only by its rider.
+func (s *driverStatsStore) RecordRating(driverID, tripID, riderID string, rating int) error {
+	if rating < 1 || rating > 5 {
+		return ErrInvalidRating
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	offer, ok := s.offer(tripID, driverID, now)
+	if !ok || !offer.Accepted || offer.RiderID != riderID {
+		return fmt.Errorf("%w: trip %s driven by %s is not awaiting a rating from %s", ErrTripNotRateable, tripID, driverID, riderID)
+	}
+	if !offer.Completed(now) {
+		return fmt.Errorf("%w: trip %s is not completed", ErrTripNotRateable, tripID)
+	}
+
+	// rated once only
+	delete(s.offers, tripID)
+	stats := s.get(driverID)
+	stats.RatingSum += rating
+	stats.RatingCount++
+
+	return nil
+}
+
+// CompletesAt returns when the trip accepted by the driver ends, zero when it
+// is unknown.
+func (s *driverStatsStore) CompletesAt(driverID, tripID string) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	offer, ok := s.offer(tripID, driverID, time.Now())
+	if !ok || !offer.Accepted {
+		return time.Time{}
+	}
+
+	return offer.CompletesAt
+}
+
+// offer returns the live offer of the trip made to the driver.
+func (s *driverStatsStore) offer(tripID, driverID string, now time.Time) (*tripOffer, bool) {
+	offer, ok := s.offers[tripID]
+	if !ok || offer.DriverID != driverID || !now.Before(offer.ExpiresAt) {
+		return nil, false
+	}
+
+	return offer, true
+}
+
+// sweep forgets the expired offers.
+func (s *driverStatsStore) sweep(now time.Time) {
+	for tripID, offer := range s.offers {
+		if !now.Before(offer.ExpiresAt) {
+			delete(s.offers, tripID)
+		}
+	}
+}
+
+func (s *driverStatsStore) Snapshot(driverID string) driverStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stats, ok := s.stats[driverID]; ok {
+		return *stats
+	}
+
+	return driverStats{}
+}
+
+func (d driverStats) AverageRating() float64 {
+	if d.RatingCount == 0 {
+		return 0
+	}
+
+	return float64(d.RatingSum) / float64(d.RatingCount)
+}
+
+func (d driverStats) AcceptanceRate() float64 {
+	if d.Offers == 0 {
+		return 0
+	}
+
+	return min(1, float64(d.Accepted)/float64(d.Offers))
+}
+
+func (d driverStats) CancellationRate() float64 {
+	if d.Accepted == 0 {
+		return 0
+	}
+
+	return min(1, float64(d.Cancelled)/float64(d.Accepted))
+}
+
+func (d driverStats) AverageResponseTime() time.Duration {
+	if d.Responses == 0 {
+		return 0
+	}
+
+	return d.ResponseTotal / time.Duration(d.Responses)
+}
+
+func (d driverStats) ToProto(driverID string) *pb.DriverStats {
+	return &pb.DriverStats{
+		DriverID:               driverID,
+		AverageRating:          d.AverageRating(),
+		RatingCount:            int32(d.RatingCount),
+		Offers:                 int32(d.Offers),
+		Accepted:               int32(d.Accepted),
+		Declined:               int32(d.Declined),
+		Cancelled:              int32(d.Cancelled),
+		AcceptanceRate:         d.AcceptanceRate(),
+		CancellationRate:       d.CancellationRate(),
+		AverageResponseSeconds: d.AverageResponseTime().Seconds(),
+	}
+}
+
+// rankingPenalties turn a driver's quality signals into extra pickup time, so
+// drivers with poor ratings or many declines lose ties against better ones.
+type rankingPenalties struct {
+	// added per star the average rating is below 5
+	PerMissingStar time.Duration
+	// added for a 100% decline rate, scaled down linearly
+	FullDeclineRate time.Duration
+	// added for a 100% cancellation rate, scaled down linearly
+	FullCancellationRate time.Duration
+}
+
+// Penalty returns zero for drivers without any history.
+func (p rankingPenalties) Penalty(d driverStats) time.Duration {
+	var penalty float64
+
+	if d.RatingCount > 0 {
+		penalty += (5 - d.AverageRating()) * float64(p.PerMissingStar)
+	}
+	if d.Offers > 0 {
+		penalty += (1 - d.AcceptanceRate()) * float64(p.FullDeclineRate)
+	}
+	if d.Accepted > 0 {
+		penalty += d.CancellationRate() * float64(p.FullCancellationRate)
+	}
+
+	return time.Duration(penalty)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRecordResponseWithoutOffer(t *testing.T) {
+	store := newDriverStatsStore()
+	store.RecordOffer("driver-1", "trip-1", "rider-1", 0)
+
+	tests := []struct {
+		name     string
+		driverID string
+		tripID   string
+	}{
+		{"made-up trip", "driver-1", "trip-2"},
+		{"trip offered to another driver", "driver-2", "trip-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if store.RecordResponse(tt.driverID, tt.tripID, true) {
+				t.Error("RecordResponse() = true, want false")
+			}
+		})
+	}
+
+	if stats := store.Snapshot("driver-2"); stats.Accepted != 0 {
+		t.Errorf("Accepted = %d for a driver without offers", stats.Accepted)
+	}
+}
+
+func TestRecordResponseCountsOnce(t *testing.T) {
+	store := newDriverStatsStore()
+	store.RecordOffer("driver-1", "trip-1", "rider-1", 0)
+
+	if !store.RecordResponse("driver-1", "trip-1", true) {
+		t.Fatal("RecordResponse() = false for a pending offer")
+	}
+	for range 3 {
+		if store.RecordResponse("driver-1", "trip-1", true) {
+			t.Error("RecordResponse() = true for an answered offer")
+		}
+	}
+
+	stats := store.Snapshot("driver-1")
+	if stats.Accepted != 1 || stats.Responses != 1 {
+		t.Errorf("Accepted = %d, Responses = %d, want 1 and 1", stats.Accepted, stats.Responses)
+	}
+	if rate := stats.AcceptanceRate(); rate != 1 {
+		t.Errorf("AcceptanceRate() = %v, want 1", rate)
+	}
+}
+
+func TestOffersAreForgotten(t *testing.T) {
+	store := newDriverStatsStore()
+
+	store.RecordOffer("driver-1", "declined", "rider-1", 0)
+	store.RecordResponse("driver-1", "declined", false)
+
+	store.RecordOffer("driver-1", "rated", "rider-1", time.Minute)
+	store.RecordResponse("driver-1", "rated", true)
+	store.offers["rated"].CompletesAt = time.Now()
+	if err := store.RecordRating("driver-1", "rated", "rider-1", 5); err != nil {
+		t.Fatalf("RecordRating() error = %v", err)
+	}
+	if err := store.RecordRating("driver-1", "rated", "rider-1", 5); !errors.Is(err, ErrTripNotRateable) {
+		t.Errorf("second RecordRating() error = %v, want %v", err, ErrTripNotRateable)
+	}
+
+	store.RecordOffer("driver-1", "cancelled", "rider-1", 0)
+	store.RecordResponse("driver-1", "cancelled", true)
+	store.RecordCancellation("driver-1", "cancelled")
+
+	store.RecordOffer("driver-1", "expired", "rider-1", 0)
+	store.offers["expired"].ExpiresAt = time.Now()
+	if store.RecordResponse("driver-1", "expired", true) {
+		t.Error("RecordResponse() = true for an expired offer")
+	}
+
+	// the next offer sweeps the expired one
+	store.RecordOffer("driver-1", "pending", "rider-1", 0)
+
+	if len(store.offers) != 1 || store.offers["pending"] == nil {
+		t.Errorf("offers = %v, want only the pending one", store.offers)
+	}
+}
+
+func TestRecordCancellationWithoutAcceptedTrip(t *testing.T) {
+	store := newDriverStatsStore()
+	store.RecordOffer("driver-1", "trip-1", "rider-1", 0)
+
+	if store.RecordCancellation("driver-1", "trip-1") {
+		t.Error("RecordCancellation() = true for an offer not accepted")
+	}
+	if store.RecordCancellation("driver-1", "trip-2") {
+		t.Error("RecordCancellation() = true for a made-up trip")
+	}
+	if stats := store.Snapshot("driver-1"); stats.Cancelled != 0 {
+		t.Errorf("Cancelled = %d, want 0", stats.Cancelled)
+	}
+}
+
+func TestRecordRatingOfCompletedTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration time.Duration
+		accept   bool
+		// ended moves the end of the trip to now
+		ended   bool
+		wantErr error
+	}{
+		{"offer not answered", time.Minute, false, false, ErrTripNotRateable},
+		{"trip under way", time.Minute, true, false, ErrTripNotRateable},
+		{"trip of unknown duration", 0, true, false, ErrTripNotRateable},
+		{"trip ended", time.Minute, true, true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newDriverStatsStore()
+			store.RecordOffer("driver-1", "trip-1", "rider-1", tt.duration)
+			if tt.accept {
+				store.RecordResponse("driver-1", "trip-1", true)
+			}
+			if tt.ended {
+				store.offers["trip-1"].CompletesAt = time.Now()
+			}
+
+			if err := store.RecordRating("driver-1", "trip-1", "rider-1", 4); !errors.Is(err, tt.wantErr) {
+				t.Errorf("RecordRating() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRecordCancellationOfCompletedTrip(t *testing.T) {
+	store := newDriverStatsStore()
+	store.RecordOffer("driver-1", "trip-1", "rider-1", time.Minute)
+	store.RecordResponse("driver-1", "trip-1", true)
+	store.offers["trip-1"].CompletesAt = time.Now()
+
+	if store.RecordCancellation("driver-1", "trip-1") {
+		t.Error("RecordCancellation() = true for a completed trip")
+	}
+	if err := store.RecordRating("driver-1", "trip-1", "rider-1", 5); err != nil {
+		t.Errorf("RecordRating() error = %v after the cancellation was refused", err)
+	}
+}
+
+func TestPenalty(t *testing.T) {
+	penalties := rankingPenalties{
+		PerMissingStar:       time.Minute,
+		FullDeclineRate:      2 * time.Minute,
+		FullCancellationRate: 5 * time.Minute,
+	}
+
+	tests := []struct {
+		name  string
+		stats driverStats
+		want  time.Duration
+	}{
+		{"no history", driverStats{}, 0},
+		{"perfect driver", driverStats{Offers: 4, Accepted: 4, RatingSum: 10, RatingCount: 2}, 0},
+		{"3 stars", driverStats{RatingSum: 6, RatingCount: 2}, 2 * time.Minute},
+		{"declines half", driverStats{Offers: 4, Accepted: 2}, time.Minute},
+		{"never accepts", driverStats{Offers: 4}, 2 * time.Minute},
+		{"cancels a quarter", driverStats{Offers: 4, Accepted: 4, Cancelled: 1}, 75 * time.Second},
+		// counts skewed by responses recorded before the offers were checked
+		{"more accepts than offers", driverStats{Offers: 1, Accepted: 5}, 0},
+		{"more cancels than accepts", driverStats{Offers: 1, Accepted: 1, Cancelled: 3}, 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := penalties.Penalty(tt.stats); got != tt.want {
+				t.Errorf("Penalty() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	pb "ride-sharing/shared/proto/driver"
+	"ride-sharing/shared/types"
+	"testing"
+	"time"
+)
+
+// TestTripFlow runs a trip through the default topology on a MemoryBroker: the
+// trip service publishes the created trip, the driver service offers it to a
+// driver through the gateway, whose driver accepts it, and the next trip finds
+// no driver left.
+func TestTripFlow(t *testing.T) {
+	ctx := context.Background()
+	broker := messaging.NewMemoryBroker(messaging.DriverService)
+	defer broker.Close()
+
+	service := newMatchingService(t, map[string][2]float64{
+		"driver-1": {37.7700, -122.4200},
+	})
+	if err := NewTripConsumer(broker, service, nil).Listen(); err != nil {
+		t.Fatalf("trip consumer: %v", err)
+	}
+	if err := NewDriverConsumer(broker, service).Listen(); err != nil {
+		t.Fatalf("driver consumer: %v", err)
+	}
+
+	// the gateway pushes these to the websocket of their owner
+	tripRequests := subscribe[messaging.TripEventData](t, broker, messaging.DriverCmdTripRequestQueue)
+	noDrivers := subscribe[any](t, broker, messaging.NotifyDriverNoDriversFoundQueue)
+
+	trip := newTripEvent("trip-1", 37.7750, -122.4200)
+	err := broker.PublishEvent(ctx, contracts.TripEventCreated, contracts.AmqpMessage{EventID: "created-1", OwnerID: trip.Trip.UserID}, trip)
+	if err != nil {
+		t.Fatalf("publish %s: %v", contracts.TripEventCreated, err)
+	}
+
+	request := receive(t, tripRequests)
+	if request.OwnerID != "driver-1" || request.Payload.Trip.GetId() != "trip-1" {
+		t.Fatalf("gateway received %s of trip %s for %s, want trip-1 for driver-1", request.Type, request.Payload.Trip.GetId(), request.OwnerID)
+	}
+	if request.CorrelationID != "created-1" {
+		t.Errorf("trip request correlated to %q, want the created event", request.CorrelationID)
+	}
+
+	// the driver accepts through its websocket
+	err = messaging.Publish(ctx, broker, contracts.DriverCmdTripAccept, "driver-1", messaging.DriverTripResponseData{
+		Driver:  &pb.Driver{Id: "driver-1"},
+		TripID:  "trip-1",
+		RiderID: trip.Trip.UserID,
+	})
+	if err != nil {
+		t.Fatalf("publish %s: %v", contracts.DriverCmdTripAccept, err)
+	}
+	waitFor(t, "the acceptance to be recorded", func() bool {
+		return service.GetDriverStats("driver-1").Accepted == 1
+	})
+
+	// the only driver is on trip-1 now
+	next := newTripEvent("trip-2", 37.7750, -122.4200)
+	if err := messaging.Publish(ctx, broker, contracts.TripEventCreated, next.Trip.UserID, next); err != nil {
+		t.Fatalf("publish %s: %v", contracts.TripEventCreated, err)
+	}
+	if notification := receive(t, noDrivers); notification.OwnerID != next.Trip.UserID {
+		t.Errorf("no drivers found sent to %s, want the rider of trip-2", notification.OwnerID)
+	}
+
+	if err := broker.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+}
+
+func subscribe[T any](t *testing.T, broker messaging.Broker, queue string) <-chan messaging.Event[T] {
+	t.Helper()
+
+	events := make(chan messaging.Event[T], 10)
+	err := messaging.Subscribe(broker, queue, func(ctx context.Context, event messaging.Event[T]) error {
+		events <- event
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("subscribe to %s: %v", queue, err)
+	}
+
+	return events
+}
+
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(time.Second):
+		t.Fatal("nothing received")
+		var zero T
+		return zero
+	}
+}
+
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if condition() {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+// TestLocationFlow moves a driver with the location update its gateway
+// publishes.
+func TestLocationFlow(t *testing.T) {
+	broker := messaging.NewMemoryBroker(messaging.APIGatewayService)
+	defer broker.Close()
+
+	service := newMatchingService(t, map[string][2]float64{
+		"driver-1": {37.7700, -122.4200},
+	})
+	if err := NewLocationConsumer(broker, service).Listen(); err != nil {
+		t.Fatalf("location consumer: %v", err)
+	}
+
+	location := messaging.DriverLocationData{Location: types.Coordinate{Latitude: 37.7900, Longitude: -122.4000}}
+	if err := messaging.Publish(context.Background(), broker, contracts.DriverCmdLocation, "driver-1", location); err != nil {
+		t.Fatalf("publish %s: %v", contracts.DriverCmdLocation, err)
+	}
+
+	waitFor(t, "the driver to move", func() bool {
+		service.mu.RLock()
+		defer service.mu.RUnlock()
+
+		return service.drivers[0].Driver.Location.GetLatitude() == 37.7900
+	})
+}
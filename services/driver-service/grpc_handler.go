@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"errors"
+	"ride-sharing/shared/auth"
 	pb "ride-sharing/shared/proto/driver"
 
 	"google.golang.org/grpc"
@@ -25,9 +27,13 @@ func NewGrpcHandler(s *grpc.Server, service *DriverService) *grpcHandler {
 }
 
 func (h *grpcHandler) RegisterDriver(c context.Context, req *pb.RegisterDriverRequest) (*pb.RegisterDriverResponse, error) {
-	driver, err := h.service.RegisterDriver(req.DriverID, req.PackageSlug)
+	if err := auth.CheckUser(c, req.DriverID); err != nil {
+		return nil, err
+	}
+
+	driver, err := h.service.RegisterDriver(c, req.DriverID, req.PackageSlug)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to register driver: %v", err)
+		return nil, status.Errorf(profileErrorCode(err), "failed to register driver: %v", err)
 	}
 
 	resp := &pb.RegisterDriverResponse{
@@ -37,9 +43,159 @@ func (h *grpcHandler) RegisterDriver(c context.Context, req *pb.RegisterDriverRe
 }
 
 func (h *grpcHandler) UnregisterDriver(c context.Context, req *pb.RegisterDriverRequest) (*pb.RegisterDriverResponse, error) {
+	if err := auth.CheckUser(c, req.DriverID); err != nil {
+		return nil, err
+	}
+
 	h.service.UnregisterDriver(req.DriverID)
 
 	return &pb.RegisterDriverResponse{
 		Driver: &pb.Driver{Id: req.DriverID},
 	}, nil
 }
+
+func (h *grpcHandler) Heartbeat(c context.Context, req *pb.HeartbeatRequest) (*pb.HeartbeatResponse, error) {
+	if err := auth.CheckUser(c, req.DriverID); err != nil {
+		return nil, err
+	}
+
+	lastSeen, err := h.service.Heartbeat(req.DriverID)
+	switch {
+	case errors.Is(err, ErrDriverNotRegistered):
+		// the gateway closes the socket of unknown drivers
+		return nil, status.Errorf(codes.NotFound, "failed to record heartbeat: %v", err)
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "failed to record heartbeat: %v", err)
+	}
+
+	return &pb.HeartbeatResponse{
+		LastSeenAt: lastSeen.UnixMilli(),
+	}, nil
+}
+
+func (h *grpcHandler) CreateDriverProfile(c context.Context, req *pb.DriverProfileRequest) (*pb.DriverProfileResponse, error) {
+	if err := auth.CheckUser(c, req.GetProfile().GetId()); err != nil {
+		return nil, err
+	}
+
+	profile, err := h.service.CreateDriverProfile(c, DriverProfileFromProto(req.GetProfile()))
+	if err != nil {
+		return nil, status.Errorf(profileErrorCode(err), "failed to create driver profile: %v", err)
+	}
+
+	return &pb.DriverProfileResponse{Profile: profile.ToProto()}, nil
+}
+
+func (h *grpcHandler) GetDriverProfile(c context.Context, req *pb.GetDriverProfileRequest) (*pb.DriverProfileResponse, error) {
+	if err := auth.CheckUser(c, req.DriverID); err != nil {
+		return nil, err
+	}
+
+	profile, err := h.service.GetDriverProfile(c, req.DriverID)
+	if err != nil {
+		return nil, status.Errorf(profileErrorCode(err), "failed to get driver profile: %v", err)
+	}
+
+	return &pb.DriverProfileResponse{Profile: profile.ToProto()}, nil
+}
+
+func (h *grpcHandler) UpdateDriverProfile(c context.Context, req *pb.DriverProfileRequest) (*pb.DriverProfileResponse, error) {
+	if err := auth.CheckUser(c, req.GetProfile().GetId()); err != nil {
+		return nil, err
+	}
+
+	profile, err := h.service.UpdateDriverProfile(c, DriverProfileFromProto(req.GetProfile()))
+	if err != nil {
+		return nil, status.Errorf(profileErrorCode(err), "failed to update driver profile: %v", err)
+	}
+
+	return &pb.DriverProfileResponse{Profile: profile.ToProto()}, nil
+}
+
+func (h *grpcHandler) DeleteDriverProfile(c context.Context, req *pb.GetDriverProfileRequest) (*pb.DriverProfileResponse, error) {
+	if err := auth.CheckUser(c, req.DriverID); err != nil {
+		return nil, err
+	}
+
+	profile, err := h.service.DeleteDriverProfile(c, req.DriverID)
+	if err != nil {
+		return nil, status.Errorf(profileErrorCode(err), "failed to delete driver profile: %v", err)
+	}
+
+	return &pb.DriverProfileResponse{Profile: profile.ToProto()}, nil
+}
+
+func (h *grpcHandler) RateDriver(c context.Context, req *pb.RateDriverRequest) (*pb.DriverStatsResponse, error) {
+	if err := auth.CheckUser(c, req.RiderID); err != nil {
+		return nil, err
+	}
+
+	stats, err := h.service.RateDriver(req.DriverID, req.TripID, req.RiderID, int(req.Rating))
+	if errors.Is(err, ErrInvalidRating) {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to rate driver: %v", err)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "failed to rate driver: %v", err)
+	}
+
+	return &pb.DriverStatsResponse{Stats: stats.ToProto(req.DriverID)}, nil
+}
+
+func (h *grpcHandler) GetDriverStats(c context.Context, req *pb.GetDriverStatsRequest) (*pb.DriverStatsResponse, error) {
+	stats := h.service.GetDriverStats(req.DriverID)
+
+	return &pb.DriverStatsResponse{Stats: stats.ToProto(req.DriverID)}, nil
+}
+
+func (h *grpcHandler) SetDestinationPreference(c context.Context, req *pb.DestinationPreferenceRequest) (*pb.DestinationPreferenceResponse, error) {
+	if err := auth.CheckUser(c, req.DriverID); err != nil {
+		return nil, err
+	}
+
+	var preference *destinationPreference
+	if req.Enabled {
+		preference = &destinationPreference{
+			Destination: req.Destination,
+			MaxDetourKM: req.MaxDetourKm,
+		}
+	}
+
+	remaining, err := h.service.SetDestinationPreference(req.DriverID, preference)
+	switch {
+	case errors.Is(err, ErrInvalidDestination):
+		return nil, status.Errorf(codes.InvalidArgument, "failed to set destination: %v", err)
+	case errors.Is(err, ErrDestinationLimitReached):
+		return nil, status.Errorf(codes.ResourceExhausted, "failed to set destination: %v", err)
+	case errors.Is(err, ErrDriverNotRegistered):
+		return nil, status.Errorf(codes.NotFound, "failed to set destination: %v", err)
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "failed to set destination: %v", err)
+	}
+
+	resp := &pb.DestinationPreferenceResponse{
+		Enabled:            req.Enabled,
+		RemainingUsesToday: int32(remaining),
+	}
+	if req.Enabled {
+		resp.Destination = req.Destination
+		resp.MaxDetourKm = req.MaxDetourKm
+	}
+
+	return resp, nil
+}
+
+// profileErrorCode maps driver profile errors to the matching gRPC status code.
+func profileErrorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, ErrDriverProfileNotFound):
+		return codes.NotFound
+	case errors.Is(err, ErrDriverProfileExists):
+		return codes.AlreadyExists
+	case errors.Is(err, ErrInvalidDriverProfile):
+		return codes.InvalidArgument
+	case errors.Is(err, ErrNoApprovedVehicle):
+		return codes.FailedPrecondition
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	pb "ride-sharing/shared/proto/driver"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestHeartbeat(t *testing.T) {
+	ctx := context.Background()
+	service := NewDriverService(NewMemoryProfileRepository(), false, rankingPenalties{}, 2)
+	handler := &grpcHandler{service: service}
+
+	// the gateway closes the socket of drivers the service does not know
+	_, err := handler.Heartbeat(ctx, &pb.HeartbeatRequest{DriverID: "driver-1"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("Heartbeat() of an unknown driver error = %v, want %v", err, codes.NotFound)
+	}
+
+	if _, err := service.RegisterDriver(ctx, "driver-1", "sedan"); err != nil {
+		t.Fatalf("RegisterDriver() error = %v", err)
+	}
+	resp, err := handler.Heartbeat(ctx, &pb.HeartbeatRequest{DriverID: "driver-1"})
+	if err != nil {
+		t.Fatalf("Heartbeat() error = %v", err)
+	}
+	if resp.LastSeenAt == 0 {
+		t.Error("Heartbeat() returned no last seen time")
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"log"
+	"ride-sharing/shared/messaging"
+)
+
+// locationConsumer moves the drivers to the locations their app reports, so
+// that they are matched by where they are.
+type locationConsumer struct {
+	rabbitmq messaging.Broker
+	service  *DriverService
+}
+
+func NewLocationConsumer(rabbitmq messaging.Broker, service *DriverService) *locationConsumer {
+	return &locationConsumer{
+		rabbitmq: rabbitmq,
+		service:  service,
+	}
+}
+
+func (l *locationConsumer) Listen(opts ...messaging.ConsumerOption) error {
+	return messaging.Subscribe(l.rabbitmq, messaging.DriverLocationQueue, func(ctx context.Context, event messaging.Event[messaging.DriverLocationData]) error {
+		location := event.Payload.Location
+
+		// the driver disconnected since, its next registration places it again
+		if !l.service.UpdateLocation(event.OwnerID, location.Latitude, location.Longitude) {
+			log.Printf("ignoring the location of unregistered driver %s", event.OwnerID)
+		}
+
+		return nil
+	}, opts...)
+}
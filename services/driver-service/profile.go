@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	pb "ride-sharing/shared/proto/driver"
+	"slices"
+)
+
+var (
+	ErrDriverProfileNotFound = errors.New("driver profile not found")
+	ErrDriverProfileExists   = errors.New("driver profile already exists")
+	ErrInvalidDriverProfile  = errors.New("invalid driver profile")
+	ErrNoApprovedVehicle     = errors.New("driver has no approved vehicle for the package")
+)
+
+type DriverProfileModel struct {
+	ID             string         `bson:"_id"`
+	Name           string         `bson:"name"`
+	ProfilePicture string         `bson:"profilePicture"`
+	PhoneNumber    string         `bson:"phoneNumber"`
+	LicenseNumber  string         `bson:"licenseNumber"`
+	Vehicles       []VehicleModel `bson:"vehicles"`
+}
+
+type VehicleModel struct {
+	Plate        string   `bson:"plate"`
+	Make         string   `bson:"make"`
+	Model        string   `bson:"model"`
+	Color        string   `bson:"color"`
+	Seats        int32    `bson:"seats"`
+	PackageSlugs []string `bson:"packageSlugs"`
+	Approved     bool     `bson:"approved"`
+}
+
+// packages of the vehicle of the default profiles
+var defaultPackageSlugs = []string{"suv", "sedan", "van", "luxury"}
+
+// defaultDriverProfile is given to the drivers registering without a profile,
+// with an approved vehicle for every package like before the profiles.
+func defaultDriverProfile(driverID string) *DriverProfileModel {
+	return &DriverProfileModel{
+		ID:   driverID,
+		Name: "Lando Norris",
+		Vehicles: []VehicleModel{{
+			Plate:        GenerateRandomPlate(),
+			Seats:        4,
+			PackageSlugs: defaultPackageSlugs,
+			Approved:     true,
+		}},
+	}
+}
+
+// ApprovedVehicleFor returns the first approved vehicle eligible for the package.
+func (p *DriverProfileModel) ApprovedVehicleFor(packageSlug string) (*VehicleModel, error) {
+	for i, v := range p.Vehicles {
+		if v.Approved && slices.Contains(v.PackageSlugs, packageSlug) {
+			return &p.Vehicles[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w %q", ErrNoApprovedVehicle, packageSlug)
+}
+
+func (p *DriverProfileModel) Validate() error {
+	if p.ID == "" {
+		return errors.New("id is required")
+	}
+	if p.Name == "" {
+		return errors.New("name is required")
+	}
+
+	for _, v := range p.Vehicles {
+		if v.Plate == "" {
+			return errors.New("vehicle plate is required")
+		}
+		if v.Seats <= 0 {
+			return fmt.Errorf("vehicle %s must have at least one seat", v.Plate)
+		}
+	}
+
+	return nil
+}
+
+func (p *DriverProfileModel) ToProto() *pb.DriverProfile {
+	vehicles := make([]*pb.Vehicle, len(p.Vehicles))
+	for i, v := range p.Vehicles {
+		vehicles[i] = &pb.Vehicle{
+			Plate:        v.Plate,
+			Make:         v.Make,
+			Model:        v.Model,
+			Color:        v.Color,
+			Seats:        v.Seats,
+			PackageSlugs: v.PackageSlugs,
+			Approved:     v.Approved,
+		}
+	}
+
+	return &pb.DriverProfile{
+		Id:             p.ID,
+		Name:           p.Name,
+		ProfilePicture: p.ProfilePicture,
+		PhoneNumber:    p.PhoneNumber,
+		LicenseNumber:  p.LicenseNumber,
+		Vehicles:       vehicles,
+	}
+}
+
+func DriverProfileFromProto(p *pb.DriverProfile) *DriverProfileModel {
+	vehicles := make([]VehicleModel, len(p.GetVehicles()))
+	for i, v := range p.GetVehicles() {
+		vehicles[i] = VehicleModel{
+			Plate:        v.Plate,
+			Make:         v.Make,
+			Model:        v.Model,
+			Color:        v.Color,
+			Seats:        v.Seats,
+			PackageSlugs: v.PackageSlugs,
+			Approved:     v.Approved,
+		}
+	}
+
+	return &DriverProfileModel{
+		ID:             p.GetId(),
+		Name:           p.GetName(),
+		ProfilePicture: p.GetProfilePicture(),
+		PhoneNumber:    p.GetPhoneNumber(),
+		LicenseNumber:  p.GetLicenseNumber(),
+		Vehicles:       vehicles,
+	}
+}
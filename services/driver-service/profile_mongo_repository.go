@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const driverProfilesCollection = "driver_profiles"
+
+type mongoProfileRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoProfileRepository(db *mongo.Database) *mongoProfileRepository {
+	return &mongoProfileRepository{
+		collection: db.Collection(driverProfilesCollection),
+	}
+}
+
+func (r *mongoProfileRepository) CreateProfile(ctx context.Context, profile *DriverProfileModel) error {
+	_, err := r.collection.InsertOne(ctx, profile)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDriverProfileExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to insert driver profile: %v", err)
+	}
+
+	return nil
+}
+
+func (r *mongoProfileRepository) GetProfile(ctx context.Context, driverID string) (*DriverProfileModel, error) {
+	var profile DriverProfileModel
+	err := r.collection.FindOne(ctx, bson.M{"_id": driverID}).Decode(&profile)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrDriverProfileNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find driver profile: %v", err)
+	}
+
+	return &profile, nil
+}
+
+func (r *mongoProfileRepository) UpdateProfile(ctx context.Context, profile *DriverProfileModel) error {
+	result, err := r.collection.ReplaceOne(ctx, bson.M{"_id": profile.ID}, profile)
+	if err != nil {
+		return fmt.Errorf("failed to update driver profile: %v", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrDriverProfileNotFound
+	}
+
+	return nil
+}
+
+func (r *mongoProfileRepository) DeleteProfile(ctx context.Context, driverID string) (*DriverProfileModel, error) {
+	var profile DriverProfileModel
+	err := r.collection.FindOneAndDelete(ctx, bson.M{"_id": driverID}).Decode(&profile)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrDriverProfileNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete driver profile: %v", err)
+	}
+
+	return &profile, nil
+}
+
+// connectMongo opens a client to the given URI and verifies it with a ping.
+func connectMongo(ctx context.Context, uri string) (*mongo.Client, error) {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to mongodb: %v", err)
+	}
+
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(ctx)
+		return nil, fmt.Errorf("failed to ping mongodb: %v", err)
+	}
+
+	return client, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+type DriverProfileRepository interface {
+	CreateProfile(ctx context.Context, profile *DriverProfileModel) error
+	GetProfile(ctx context.Context, driverID string) (*DriverProfileModel, error)
+	UpdateProfile(ctx context.Context, profile *DriverProfileModel) error
+	DeleteProfile(ctx context.Context, driverID string) (*DriverProfileModel, error)
+}
+
+type memoryProfileRepository struct {
+	profiles map[string]*DriverProfileModel
+	mu       sync.RWMutex
+}
+
+func NewMemoryProfileRepository() *memoryProfileRepository {
+	return &memoryProfileRepository{
+		profiles: make(map[string]*DriverProfileModel),
+	}
+}
+
+func (r *memoryProfileRepository) CreateProfile(ctx context.Context, profile *DriverProfileModel) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.profiles[profile.ID]; exists {
+		return ErrDriverProfileExists
+	}
+
+	r.profiles[profile.ID] = profile
+	return nil
+}
+
+func (r *memoryProfileRepository) GetProfile(ctx context.Context, driverID string) (*DriverProfileModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	profile, exists := r.profiles[driverID]
+	if !exists {
+		return nil, ErrDriverProfileNotFound
+	}
+
+	return profile, nil
+}
+
+func (r *memoryProfileRepository) UpdateProfile(ctx context.Context, profile *DriverProfileModel) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.profiles[profile.ID]; !exists {
+		return ErrDriverProfileNotFound
+	}
+
+	r.profiles[profile.ID] = profile
+	return nil
+}
+
+func (r *memoryProfileRepository) DeleteProfile(ctx context.Context, driverID string) (*DriverProfileModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	profile, exists := r.profiles[driverID]
+	if !exists {
+		return nil, ErrDriverProfileNotFound
+	}
+
+	delete(r.profiles, driverID)
+	return profile, nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// runStaleDriverReaper periodically marks drivers offline when their gateway
+// stopped sending heartbeats, so ghost drivers are no longer matched, and
+// forgets the ones still silent after evictAfter.
+func runStaleDriverReaper(ctx context.Context, service *DriverService, interval, staleAfter, evictAfter time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			staleIDs := service.MarkStaleDriversOffline(staleAfter)
+			if len(staleIDs) > 0 {
+				log.Printf("marked %d stale drivers offline: %v", len(staleIDs), staleIDs)
+			}
+
+			evictedIDs := service.EvictStaleDrivers(evictAfter)
+			if len(evictedIDs) > 0 {
+				log.Printf("evicted %d stale drivers: %v", len(evictedIDs), evictedIDs)
+			}
+		}
+	}
+}
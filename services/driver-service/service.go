@@ -1,31 +1,111 @@
 package main
 
 import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
 	math "math/rand/v2"
+	"ride-sharing/shared/matching"
+	"ride-sharing/shared/messaging"
 	pb "ride-sharing/shared/proto/driver"
+	tripPb "ride-sharing/shared/proto/trip"
 	"ride-sharing/shared/util"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/mmcloughlin/geohash"
 )
 
+// ErrDriverNotRegistered is returned for drivers that are not connected to a
+// gateway, e.g. after their websocket closed.
+var ErrDriverNotRegistered = errors.New("driver is not registered")
+
 type DriverService struct {
-	drivers []*driverInMap
-	mu      sync.RWMutex
+	drivers   []*driverInMap
+	mu        sync.RWMutex
+	profiles  DriverProfileRepository
+	stats     *driverStatsStore
+	penalties rankingPenalties
+
+	// refuse the drivers without a profile instead of giving them the default one
+	requireProfile bool
+
+	destinationUsage      map[string]*destinationUsage
+	destinationDailyLimit int
+
+	// declines holds, per trip, the drivers who declined it and when their
+	// decline is forgotten
+	declines map[string]map[string]time.Time
 }
 
+// a declined trip is not offered to the driver again while it is matched
+const declineMemory = time.Hour
+
 type driverInMap struct {
-	Driver *pb.Driver
+	Driver   *pb.Driver
+	LastSeen time.Time
+	Online   bool
+	// Destination is set while the driver only wants trips heading its way
+	Destination *destinationPreference
+	// TripID is the trip offered to or accepted by the driver, no other trip is
+	// offered meanwhile. BusyUntil is when an unanswered offer expires, then
+	// when the accepted trip ends according to its route. It is zero for an
+	// accepted trip of unknown duration, the driver then stays busy until the
+	// trip is cancelled or it registers again.
+	TripID    string
+	BusyUntil time.Time
 	// TODO: route
 }
 
-func NewDriverService() *DriverService {
+// Available reports whether the driver is online and not busy with a trip.
+func (d *driverInMap) Available(now time.Time) bool {
+	if !d.Online {
+		return false
+	}
+
+	return d.TripID == "" || (!d.BusyUntil.IsZero() && !now.Before(d.BusyUntil))
+}
+
+// AcceptsTrip reports whether the trip can be offered to the driver.
+func (d *driverInMap) AcceptsTrip(trip *tripPb.Trip) bool {
+	if !d.Available(time.Now()) || d.Driver.PackageSlug != trip.GetSelectedRideFare().GetPackageSlug() {
+		return false
+	}
+
+	return d.Destination == nil || d.Destination.Accepts(d.Driver.Location, trip)
+}
+
+func NewDriverService(profiles DriverProfileRepository, requireProfile bool, penalties rankingPenalties, destinationDailyLimit int) *DriverService {
 	return &DriverService{
-		drivers: make([]*driverInMap, 0),
+		drivers:               make([]*driverInMap, 0),
+		profiles:              profiles,
+		requireProfile:        requireProfile,
+		stats:                 newDriverStatsStore(),
+		penalties:             penalties,
+		destinationUsage:      make(map[string]*destinationUsage),
+		destinationDailyLimit: destinationDailyLimit,
+		declines:              make(map[string]map[string]time.Time),
 	}
 }
 
-func (s *DriverService) RegisterDriver(driverId string, packageSlug string) (*pb.Driver, error) {
+func (s *DriverService) RegisterDriver(ctx context.Context, driverId string, packageSlug string) (*pb.Driver, error) {
+	profile, err := s.profiles.GetProfile(ctx, driverId)
+	if errors.Is(err, ErrDriverProfileNotFound) && !s.requireProfile {
+		profile, err = s.createDefaultProfile(ctx, driverId)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	vehicle, err := profile.ApprovedVehicleFor(packageSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	profilePicture := profile.ProfilePicture
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	randomIndex := math.IntN(len(PredefinedRoutes))
@@ -34,45 +114,390 @@ func (s *DriverService) RegisterDriver(driverId string, packageSlug string) (*pb
 	// we can ignore this property for now, but it must be sent to the frontend.
 	geohash := geohash.Encode(randomRoute[0][0], randomRoute[0][1])
 
+	if profilePicture == "" {
+		profilePicture = util.GetRandomAvatar(randomIndex)
+	}
+
+	now := time.Now()
 	driver := &pb.Driver{
 		Geohash:        geohash,
 		Location:       &pb.Location{Latitude: randomRoute[0][0], Longitude: randomRoute[0][1]},
-		Name:           "Lando Norris",
+		Name:           profile.Name,
 		Id:             driverId,
-		ProfilePicture: util.GetRandomAvatar(randomIndex),
-		CarPlate:       GenerateRandomPlate(),
+		ProfilePicture: profilePicture,
+		CarPlate:       vehicle.Plate,
 		PackageSlug:    packageSlug,
+		LastSeenAt:     now.UnixMilli(),
+	}
+
+	entry := &driverInMap{Driver: driver, LastSeen: now, Online: true}
+
+	// a driver reconnecting before its previous socket was unregistered
+	// replaces its registration, keeping the destination it already paid for
+	if i := slices.IndexFunc(s.drivers, func(d *driverInMap) bool { return d.Driver.Id == driverId }); i >= 0 {
+		entry.Destination = s.drivers[i].Destination
+		s.drivers[i] = entry
+		return driver, nil
 	}
 
 	// Add driver to list
-	s.drivers = append(s.drivers, &driverInMap{Driver: driver})
+	s.drivers = append(s.drivers, entry)
 	return driver, nil
 }
 
+// createDefaultProfile stores the default profile of a driver, so that it
+// keeps its plate when it registers again.
+func (s *DriverService) createDefaultProfile(ctx context.Context, driverId string) (*DriverProfileModel, error) {
+	profile := defaultDriverProfile(driverId)
+
+	err := s.profiles.CreateProfile(ctx, profile)
+	if errors.Is(err, ErrDriverProfileExists) {
+		// created by a concurrent registration
+		return s.profiles.GetProfile(ctx, driverId)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return profile, nil
+}
+
 func (s *DriverService) UnregisterDriver(driverId string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	for i, d := range s.drivers {
+	s.drivers = slices.DeleteFunc(s.drivers, func(d *driverInMap) bool {
+		return d.Driver.Id == driverId
+	})
+}
+
+// UpdateLocation moves a driver to the location its app reported. It returns
+// false when the driver is not registered.
+func (s *DriverService) UpdateLocation(driverId string, latitude, longitude float64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, d := range s.drivers {
 		if d.Driver.Id == driverId {
-			// delete driver from list
-			s.drivers = append(s.drivers[:i], s.drivers[i+1:]...)
+			d.Driver.Location = &pb.Location{Latitude: latitude, Longitude: longitude}
+			d.Driver.Geohash = geohash.Encode(latitude, longitude)
+			return true
 		}
 	}
+
+	return false
 }
 
-func (s *DriverService) FindAvailableDrivers(packageType string) []string {
-	var matchingDrivers []string
+// Heartbeat refreshes the last-seen timestamp of a driver and brings it back
+// online if the reaper marked it offline in the meantime.
+func (s *DriverService) Heartbeat(driverId string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
 	for _, d := range s.drivers {
-		if d.Driver.PackageSlug == packageType {
-			matchingDrivers = append(matchingDrivers, d.Driver.Id)
+		if d.Driver.Id == driverId {
+			d.LastSeen = time.Now()
+			d.Online = true
+			d.Driver.LastSeenAt = d.LastSeen.UnixMilli()
+			return d.LastSeen, nil
 		}
 	}
 
-	if len(matchingDrivers) == 0 {
+	return time.Time{}, fmt.Errorf("%w: %s", ErrDriverNotRegistered, driverId)
+}
+
+// EvictStaleDrivers unregisters every driver that has not been seen for longer
+// than evictAfter and returns their IDs. Their gateway is gone, or it closes
+// their socket on the next heartbeat and the driver registers again.
+func (s *DriverService) EvictStaleDrivers(evictAfter time.Duration) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var evictedIDs []string
+	deadline := time.Now().Add(-evictAfter)
+
+	s.drivers = slices.DeleteFunc(s.drivers, func(d *driverInMap) bool {
+		if d.LastSeen.Before(deadline) {
+			evictedIDs = append(evictedIDs, d.Driver.Id)
+			return true
+		}
+		return false
+	})
+
+	return evictedIDs
+}
+
+// MarkStaleDriversOffline marks every driver that has not been seen for longer
+// than staleAfter as offline and returns their IDs.
+func (s *DriverService) MarkStaleDriversOffline(staleAfter time.Duration) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var staleIDs []string
+	deadline := time.Now().Add(-staleAfter)
+
+	for _, d := range s.drivers {
+		if d.Online && d.LastSeen.Before(deadline) {
+			d.Online = false
+			staleIDs = append(staleIDs, d.Driver.Id)
+		}
+	}
+
+	return staleIDs
+}
+
+// FindAvailableDrivers returns the online drivers that accept the trip, the
+// cheapest first by the pickup cost the batch matcher minimizes. Drivers with
+// equal costs keep their registration order. Like in batch mode, a trip
+// without a pickup point matches no driver.
+func (s *DriverService) FindAvailableDrivers(trip *tripPb.Trip) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var candidates []*pb.Driver
+
+	for _, d := range s.drivers {
+		if d.AcceptsTrip(trip) && !s.declined(d.Driver.Id, trip.GetId()) {
+			candidates = append(candidates, d.Driver)
+		}
+	}
+
+	if len(candidates) == 0 {
 		return []string{}
 	}
 
+	// the candidates accept the trip already
+	accepts := func(string, *tripPb.Trip) bool { return true }
+	cost := buildPickupCostMatrix([]messaging.TripEventData{{Trip: trip}}, candidates, accepts, s.RankingPenalty)[0]
+
+	order := make([]int, 0, len(candidates))
+	for j := range candidates {
+		if cost[j] != matching.Infeasible {
+			order = append(order, j)
+		}
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(cost[a], cost[b])
+	})
+
+	matchingDrivers := make([]string, len(order))
+	for i, j := range order {
+		matchingDrivers[i] = candidates[j].Id
+	}
+
 	return matchingDrivers
 }
+
+// RankingPenalty is the extra pickup time charged to a driver during matching
+// for a poor rating, acceptance or cancellation history.
+func (s *DriverService) RankingPenalty(driverId string) time.Duration {
+	return s.penalties.Penalty(s.stats.Snapshot(driverId))
+}
+
+// RecordOffer marks the driver busy until it answers the offer or the offer
+// expires.
+func (s *DriverService) RecordOffer(driverId string, trip *tripPb.Trip) {
+	duration := time.Duration(trip.GetRoute().GetDuration() * float64(time.Second))
+	s.stats.RecordOffer(driverId, trip.GetId(), trip.GetUserID(), duration)
+	s.setDriverTrip(driverId, trip.GetId(), time.Now().Add(offerResponseTimeout))
+}
+
+// RecordOfferResponse returns false when the driver has no pending offer for
+// the trip, the response is then ignored. An accepted offer keeps the driver
+// busy until the trip ends, a declined one frees the driver, who is not
+// offered the trip again.
+func (s *DriverService) RecordOfferResponse(driverId, tripId string, accepted bool) bool {
+	if !s.stats.RecordResponse(driverId, tripId, accepted) {
+		return false
+	}
+
+	if accepted {
+		s.setDriverTrip(driverId, tripId, s.stats.CompletesAt(driverId, tripId))
+	} else {
+		s.recordDecline(driverId, tripId)
+		s.releaseDriver(driverId, tripId)
+	}
+
+	return true
+}
+
+func (s *DriverService) recordDecline(driverId, tripId string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, drivers := range s.declines {
+		for driver, forgetAt := range drivers {
+			if !now.Before(forgetAt) {
+				delete(drivers, driver)
+			}
+		}
+		if len(drivers) == 0 {
+			delete(s.declines, id)
+		}
+	}
+
+	if s.declines[tripId] == nil {
+		s.declines[tripId] = make(map[string]time.Time)
+	}
+	s.declines[tripId][driverId] = now.Add(declineMemory)
+}
+
+// declined reports whether the driver declined the trip, s.mu must be held.
+func (s *DriverService) declined(driverId, tripId string) bool {
+	forgetAt, ok := s.declines[tripId][driverId]
+	return ok && time.Now().Before(forgetAt)
+}
+
+// RecordCancellation returns false when the driver has not accepted the trip,
+// the cancellation is then ignored. It frees the driver otherwise.
+func (s *DriverService) RecordCancellation(driverId, tripId string) bool {
+	if !s.stats.RecordCancellation(driverId, tripId) {
+		return false
+	}
+
+	s.releaseDriver(driverId, tripId)
+	return true
+}
+
+func (s *DriverService) setDriverTrip(driverId, tripId string, busyUntil time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, d := range s.drivers {
+		if d.Driver.Id == driverId {
+			d.TripID = tripId
+			d.BusyUntil = busyUntil
+		}
+	}
+}
+
+// releaseDriver makes the driver available again unless it moved on to
+// another trip.
+func (s *DriverService) releaseDriver(driverId, tripId string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, d := range s.drivers {
+		if d.Driver.Id == driverId && d.TripID == tripId {
+			d.TripID = ""
+			d.BusyUntil = time.Time{}
+		}
+	}
+}
+
+func (s *DriverService) RateDriver(driverId, tripId, riderId string, rating int) (driverStats, error) {
+	if err := s.stats.RecordRating(driverId, tripId, riderId, rating); err != nil {
+		return driverStats{}, err
+	}
+
+	return s.stats.Snapshot(driverId), nil
+}
+
+func (s *DriverService) GetDriverStats(driverId string) driverStats {
+	return s.stats.Snapshot(driverId)
+}
+
+// DriverAcceptsTrip reports whether the trip can be offered to the driver.
+func (s *DriverService) DriverAcceptsTrip(driverId string, trip *tripPb.Trip) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, d := range s.drivers {
+		if d.Driver.Id == driverId {
+			return d.AcceptsTrip(trip) && !s.declined(driverId, trip.GetId())
+		}
+	}
+
+	return false
+}
+
+// SetDestinationPreference turns the destination filter of a driver on, or off
+// when preference is nil, and returns how many destinations can still be set
+// today. Turning the filter on and changing its destination count against the
+// daily limit, turning it off or changing only the detour does not.
+func (s *DriverService) SetDestinationPreference(driverId string, preference *destinationPreference) (int, error) {
+	if preference != nil && (preference.Destination == nil || preference.MaxDetourKM <= 0) {
+		return 0, ErrInvalidDestination
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var driver *driverInMap
+	for _, d := range s.drivers {
+		if d.Driver.Id == driverId {
+			driver = d
+			break
+		}
+	}
+	if driver == nil {
+		return 0, fmt.Errorf("%w: %s", ErrDriverNotRegistered, driverId)
+	}
+
+	today := usageDay(time.Now())
+	usage, ok := s.destinationUsage[driverId]
+	if !ok || usage.Day != today {
+		usage = &destinationUsage{Day: today}
+		s.destinationUsage[driverId] = usage
+	}
+
+	if preference != nil && !driver.Destination.sameDestination(preference) {
+		if usage.Count >= s.destinationDailyLimit {
+			return 0, ErrDestinationLimitReached
+		}
+		usage.Count++
+	}
+
+	driver.Destination = preference
+	return s.destinationDailyLimit - usage.Count, nil
+}
+
+// AvailableDrivers returns every online driver not busy with a trip,
+// regardless of package.
+func (s *DriverService) AvailableDrivers() []*pb.Driver {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	drivers := make([]*pb.Driver, 0, len(s.drivers))
+	for _, d := range s.drivers {
+		if d.Available(now) {
+			drivers = append(drivers, d.Driver)
+		}
+	}
+
+	return drivers
+}
+
+func (s *DriverService) CreateDriverProfile(ctx context.Context, profile *DriverProfileModel) (*DriverProfileModel, error) {
+	if err := profile.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDriverProfile, err)
+	}
+
+	if err := s.profiles.CreateProfile(ctx, profile); err != nil {
+		return nil, err
+	}
+
+	return profile, nil
+}
+
+func (s *DriverService) GetDriverProfile(ctx context.Context, driverId string) (*DriverProfileModel, error) {
+	return s.profiles.GetProfile(ctx, driverId)
+}
+
+func (s *DriverService) UpdateDriverProfile(ctx context.Context, profile *DriverProfileModel) (*DriverProfileModel, error) {
+	if err := profile.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDriverProfile, err)
+	}
+
+	if err := s.profiles.UpdateProfile(ctx, profile); err != nil {
+		return nil, err
+	}
+
+	return profile, nil
+}
+
+func (s *DriverService) DeleteDriverProfile(ctx context.Context, driverId string) (*DriverProfileModel, error) {
+	return s.profiles.DeleteProfile(ctx, driverId)
+}
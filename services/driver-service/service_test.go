@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"errors"
+	pb "ride-sharing/shared/proto/driver"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRegisterDriverWithoutProfile(t *testing.T) {
+	ctx := context.Background()
+	service := NewDriverService(NewMemoryProfileRepository(), false, rankingPenalties{}, 2)
+
+	driver, err := service.RegisterDriver(ctx, "driver-1", "sedan")
+	if err != nil {
+		t.Fatalf("RegisterDriver() error = %v", err)
+	}
+	if driver.CarPlate == "" {
+		t.Error("RegisterDriver() gave the driver no plate")
+	}
+
+	service.UnregisterDriver("driver-1")
+	again, err := service.RegisterDriver(ctx, "driver-1", "luxury")
+	if err != nil {
+		t.Fatalf("RegisterDriver() again error = %v", err)
+	}
+	if again.CarPlate != driver.CarPlate {
+		t.Errorf("plate changed from %s to %s, want the one of the stored default profile", driver.CarPlate, again.CarPlate)
+	}
+}
+
+func TestRegisterDriverProfileRequired(t *testing.T) {
+	service := NewDriverService(NewMemoryProfileRepository(), true, rankingPenalties{}, 2)
+
+	_, err := service.RegisterDriver(context.Background(), "driver-1", "sedan")
+	if !errors.Is(err, ErrDriverProfileNotFound) {
+		t.Fatalf("RegisterDriver() error = %v, want %v", err, ErrDriverProfileNotFound)
+	}
+}
+
+func TestRegisterDriverWithoutApprovedVehicle(t *testing.T) {
+	ctx := context.Background()
+	profiles := NewMemoryProfileRepository()
+	profiles.CreateProfile(ctx, &DriverProfileModel{
+		ID:       "driver-1",
+		Name:     "Driver",
+		Vehicles: []VehicleModel{{Plate: "ABC", Seats: 4, PackageSlugs: []string{"sedan"}}},
+	})
+	service := NewDriverService(profiles, false, rankingPenalties{}, 2)
+
+	_, err := service.RegisterDriver(ctx, "driver-1", "sedan")
+	if !errors.Is(err, ErrNoApprovedVehicle) {
+		t.Fatalf("RegisterDriver() error = %v, want %v", err, ErrNoApprovedVehicle)
+	}
+}
+
+func TestSetDestinationPreferenceDailyLimit(t *testing.T) {
+	service := NewDriverService(NewMemoryProfileRepository(), false, rankingPenalties{}, 2)
+	if _, err := service.RegisterDriver(context.Background(), "driver-1", "sedan"); err != nil {
+		t.Fatalf("RegisterDriver() error = %v", err)
+	}
+
+	home := &pb.Location{Latitude: 37.7750, Longitude: -122.4200}
+	work := &pb.Location{Latitude: 37.7900, Longitude: -122.4000}
+	gym := &pb.Location{Latitude: 37.7600, Longitude: -122.4400}
+
+	steps := []struct {
+		name          string
+		preference    *destinationPreference
+		wantRemaining int
+		wantErr       error
+	}{
+		{"turn on", &destinationPreference{Destination: home, MaxDetourKM: 5}, 1, nil},
+		{"change the detour", &destinationPreference{Destination: home, MaxDetourKM: 10}, 1, nil},
+		{"change the destination", &destinationPreference{Destination: work, MaxDetourKM: 10}, 0, nil},
+		{"change the destination past the limit", &destinationPreference{Destination: gym, MaxDetourKM: 10}, 0, ErrDestinationLimitReached},
+		{"turn off", nil, 0, nil},
+		{"turn on past the limit", &destinationPreference{Destination: work, MaxDetourKM: 10}, 0, ErrDestinationLimitReached},
+	}
+
+	for _, step := range steps {
+		remaining, err := service.SetDestinationPreference("driver-1", step.preference)
+		if !errors.Is(err, step.wantErr) {
+			t.Fatalf("%s: error = %v, want %v", step.name, err, step.wantErr)
+		}
+		if remaining != step.wantRemaining {
+			t.Errorf("%s: remaining = %d, want %d", step.name, remaining, step.wantRemaining)
+		}
+	}
+}
+
+func TestRegisterDriverAgain(t *testing.T) {
+	ctx := context.Background()
+	service := NewDriverService(NewMemoryProfileRepository(), false, rankingPenalties{}, 2)
+
+	if _, err := service.RegisterDriver(ctx, "driver-1", "sedan"); err != nil {
+		t.Fatalf("RegisterDriver() error = %v", err)
+	}
+	home := &destinationPreference{Destination: &pb.Location{Latitude: 37.7750, Longitude: -122.4200}, MaxDetourKM: 50}
+	if _, err := service.SetDestinationPreference("driver-1", home); err != nil {
+		t.Fatalf("SetDestinationPreference() error = %v", err)
+	}
+
+	// the gateway registers the new socket before unregistering the old one
+	if _, err := service.RegisterDriver(ctx, "driver-1", "sedan"); err != nil {
+		t.Fatalf("RegisterDriver() again error = %v", err)
+	}
+	if len(service.drivers) != 1 {
+		t.Fatalf("%d registrations of driver-1, want 1", len(service.drivers))
+	}
+	if service.drivers[0].Destination != home {
+		t.Error("registering again dropped the destination")
+	}
+
+	service.UnregisterDriver("driver-1")
+	if _, err := service.Heartbeat("driver-1"); !errors.Is(err, ErrDriverNotRegistered) {
+		t.Errorf("Heartbeat() after unregistering error = %v, want %v", err, ErrDriverNotRegistered)
+	}
+}
+
+func TestEvictStaleDrivers(t *testing.T) {
+	ctx := context.Background()
+	service := NewDriverService(NewMemoryProfileRepository(), false, rankingPenalties{}, 2)
+	for _, id := range []string{"driver-1", "driver-2"} {
+		if _, err := service.RegisterDriver(ctx, id, "sedan"); err != nil {
+			t.Fatalf("RegisterDriver(%s) error = %v", id, err)
+		}
+	}
+	// driver-1 stopped sending heartbeats an hour ago
+	service.drivers[0].LastSeen = time.Now().Add(-time.Hour)
+
+	if offline := service.MarkStaleDriversOffline(time.Minute); len(offline) != 1 || offline[0] != "driver-1" {
+		t.Fatalf("MarkStaleDriversOffline() = %v, want driver-1", offline)
+	}
+	if evicted := service.EvictStaleDrivers(2 * time.Hour); len(evicted) != 0 {
+		t.Fatalf("EvictStaleDrivers() before the timeout = %v", evicted)
+	}
+
+	if evicted := service.EvictStaleDrivers(10 * time.Minute); len(evicted) != 1 || evicted[0] != "driver-1" {
+		t.Fatalf("EvictStaleDrivers() = %v, want driver-1", evicted)
+	}
+	if _, err := service.Heartbeat("driver-1"); !errors.Is(err, ErrDriverNotRegistered) {
+		t.Errorf("Heartbeat() of an evicted driver error = %v, want %v", err, ErrDriverNotRegistered)
+	}
+	if _, err := service.Heartbeat("driver-2"); err != nil {
+		t.Errorf("Heartbeat() of a live driver error = %v", err)
+	}
+}
+
+func TestDeclinedTripNotOfferedAgain(t *testing.T) {
+	service := newMatchingService(t, map[string][2]float64{
+		"driver-1": {37.7700, -122.4200},
+		"driver-2": {37.7800, -122.4200},
+	})
+	trip := newTripEvent("trip-1", 37.7700, -122.4200).Trip
+
+	service.RecordOffer("driver-1", trip)
+	service.RecordOfferResponse("driver-1", "trip-1", false)
+
+	if got := service.FindAvailableDrivers(trip); len(got) != 1 || got[0] != "driver-2" {
+		t.Errorf("FindAvailableDrivers(trip-1) = %v, want only driver-2", got)
+	}
+	if service.DriverAcceptsTrip("driver-1", trip) {
+		t.Error("DriverAcceptsTrip() = true for the trip the driver declined")
+	}
+
+	// the decliner is free for other trips
+	if got := service.FindAvailableDrivers(newTripEvent("trip-2", 37.7700, -122.4200).Trip); len(got) != 2 {
+		t.Errorf("FindAvailableDrivers(trip-2) = %v, want both drivers", got)
+	}
+}
+
+func TestRateDriverAfterTripEnds(t *testing.T) {
+	service := newMatchingService(t, map[string][2]float64{
+		"driver-1": {37.7700, -122.4200},
+	})
+	trip := newTripEvent("trip-1", 37.7700, -122.4200).Trip
+	trip.Route.Duration = 0.05
+
+	service.RecordOffer("driver-1", trip)
+	service.RecordOfferResponse("driver-1", "trip-1", true)
+
+	if _, err := service.RateDriver("driver-1", "trip-1", trip.UserID, 5); !errors.Is(err, ErrTripNotRateable) {
+		t.Errorf("RateDriver() during the trip error = %v, want %v", err, ErrTripNotRateable)
+	}
+	next := newTripEvent("trip-2", 37.7700, -122.4200).Trip
+	if got := service.FindAvailableDrivers(next); len(got) != 0 {
+		t.Errorf("FindAvailableDrivers() during the trip = %v, want none", got)
+	}
+
+	time.Sleep(time.Until(service.drivers[0].BusyUntil))
+
+	if _, err := service.RateDriver("driver-1", "trip-1", trip.UserID, 5); err != nil {
+		t.Errorf("RateDriver() after the trip error = %v", err)
+	}
+	if got := service.FindAvailableDrivers(next); len(got) != 1 {
+		t.Errorf("FindAvailableDrivers() after the trip = %v, want driver-1", got)
+	}
+}
+
+func TestFindAvailableDriversNearestFirst(t *testing.T) {
+	service := newMatchingService(t, map[string][2]float64{
+		"far":  {37.8000, -122.4200},
+		"near": {37.7710, -122.4200},
+	})
+	trip := newTripEvent("trip-1", 37.7700, -122.4200).Trip
+
+	if got := service.FindAvailableDrivers(trip); !slices.Equal(got, []string{"near", "far"}) {
+		t.Errorf("FindAvailableDrivers() = %v, want [near far]", got)
+	}
+
+	// a poor rating costs the near driver more than the longer pickup
+	service.penalties = rankingPenalties{PerMissingStar: time.Hour}
+	service.stats.get("near").RatingSum, service.stats.get("near").RatingCount = 1, 1
+	if got := service.FindAvailableDrivers(trip); !slices.Equal(got, []string{"far", "near"}) {
+		t.Errorf("FindAvailableDrivers() with a penalty = %v, want [far near]", got)
+	}
+
+	trip.Route = nil
+	if got := service.FindAvailableDrivers(trip); len(got) != 0 {
+		t.Errorf("FindAvailableDrivers() of a trip without a pickup = %v, want none", got)
+	}
+}
@@ -2,59 +2,61 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"log"
 	"ride-sharing/shared/contracts"
 	"ride-sharing/shared/messaging"
-
-	"github.com/rabbitmq/amqp091-go"
 )
 
 type tripConsumer struct {
-	rabbitmq *messaging.RabbitMQ
+	rabbitmq messaging.Broker
 	service  *DriverService
+	// batch is nil in greedy mode, where each trip is matched as it arrives
+	batch *batchMatcher
 }
 
-func NewTripConsumer(rabbitmq *messaging.RabbitMQ, service *DriverService) *tripConsumer {
+func NewTripConsumer(rabbitmq messaging.Broker, service *DriverService, batch *batchMatcher) *tripConsumer {
 	return &tripConsumer{
 		rabbitmq: rabbitmq,
 		service:  service,
+		batch:    batch,
 	}
 }
 
-func (t *tripConsumer) Listen() error {
-	return t.rabbitmq.ConsumeMessages(messaging.FindAvailableDriversQueue, func(ctx context.Context, msg amqp091.Delivery) error {
-		var tripEvent contracts.AmqpMessage
-		if err := json.Unmarshal(msg.Body, &tripEvent); err != nil {
-			log.Printf("failed to unmarshal message: %v", err)
-			return err
-		}
+func (t *tripConsumer) Listen(opts ...messaging.ConsumerOption) error {
+	return messaging.Subscribe(t.rabbitmq, messaging.FindAvailableDriversQueue, func(ctx context.Context, event messaging.Event[messaging.TripEventData]) error {
+		log.Printf("driver receive message: %+v", event.Payload)
 
-		var payload messaging.TripEventData
-		if err := json.Unmarshal(tripEvent.Data, &payload); err != nil {
-			log.Printf("failed to unmarshal message: %v", err)
-			return err
-		}
-
-		log.Printf("driver receive message: %+v", payload)
-
-		switch msg.RoutingKey {
+		switch event.Type {
 		case contracts.TripEventCreated, contracts.TripEventDriverNotInterested:
-			return t.handleFindAndNotifyDriver(ctx, payload)
+			return t.handleFindAndNotifyDriver(ctx, event.Payload)
 		}
 
 		log.Println("unknown trip event")
 
 		return nil
-	})
+	}, opts...)
 }
 
 func (t *tripConsumer) handleFindAndNotifyDriver(ctx context.Context, payload messaging.TripEventData) error {
-	suitableIDs := t.service.FindAvailableDrivers(payload.Trip.SelectedRideFare.PackageSlug)
+	if t.batch != nil {
+		return t.batch.Enqueue(ctx, payload)
+	}
+
+	suitableIDs := t.service.FindAvailableDrivers(payload.Trip)
 
 	log.Printf("found suitable drivers: %v", len(suitableIDs))
 
 	if len(suitableIDs) == 0 {
+		return t.notifyMatch(ctx, payload, "")
+	}
+
+	return t.notifyMatch(ctx, payload, suitableIDs[0])
+}
+
+// notifyMatch offers the trip to the driver, or tells the rider that no driver
+// is available when driverID is empty.
+func (t *tripConsumer) notifyMatch(ctx context.Context, payload messaging.TripEventData, driverID string) error {
+	if driverID == "" {
 		// notify the rider that no driver is available
 		if err := t.rabbitmq.PublishMessage(ctx, contracts.TripEventNoDriversFound, contracts.AmqpMessage{
 			OwnerID: payload.Trip.UserID,
@@ -66,19 +68,13 @@ func (t *tripConsumer) handleFindAndNotifyDriver(ctx context.Context, payload me
 		return nil
 	}
 
-	marshalledEvent, err := json.Marshal(payload)
-	if err != nil {
-		return err
-	}
-
 	// notify the rider that a driver is found
-	if err := t.rabbitmq.PublishMessage(ctx, contracts.DriverCmdTripRequest, contracts.AmqpMessage{
-		OwnerID: suitableIDs[0],
-		Data:    marshalledEvent,
-	}); err != nil {
+	if err := messaging.Publish(ctx, t.rabbitmq, contracts.DriverCmdTripRequest, driverID, payload); err != nil {
 		log.Printf("failed to publish message to exchange: %v", err)
 		return err
 	}
 
+	t.service.RecordOffer(driverID, payload.Trip)
+
 	return nil
 }
@@ -2,6 +2,7 @@ package domain
 
 import (
 	"context"
+	"errors"
 	"ride-sharing/shared/types"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -9,6 +10,15 @@ import (
 	pb "ride-sharing/shared/proto/trip"
 )
 
+var (
+	ErrFareNotFound = errors.New("fare does not exist")
+	ErrFareNotOwned = errors.New("fare does not belong to the user")
+	// the coordinates are invalid or cannot be joined by road
+	ErrInvalidRoute = errors.New("invalid route")
+	// the routing API could not be reached or failed, the call may be retried
+	ErrRoutingUnavailable = errors.New("routing unavailable")
+)
+
 type TripModel struct {
 	ID       primitive.ObjectID
 	UserID   string
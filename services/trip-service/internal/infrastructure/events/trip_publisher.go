@@ -2,30 +2,21 @@ package events
 
 import (
 	"context"
-	"encoding/json"
 	"ride-sharing/services/trip-service/internal/domain"
 	"ride-sharing/shared/contracts"
 	"ride-sharing/shared/messaging"
 )
 
 type TripEventPublisher struct {
-	rabbitmq *messaging.RabbitMQ
+	rabbitmq messaging.Broker
 }
 
-func NewTripEventPublisher(rabbitmq *messaging.RabbitMQ) *TripEventPublisher {
+func NewTripEventPublisher(rabbitmq messaging.Broker) *TripEventPublisher {
 	return &TripEventPublisher{rabbitmq: rabbitmq}
 }
 
 func (p *TripEventPublisher) PublishTripCreated(ctx context.Context, trip *domain.TripModel) error {
 	payload := messaging.TripEventData{Trip: trip.ToProto()}
 
-	tripJson, err := json.Marshal(payload)
-	if err != nil {
-		return err
-	}
-
-	return p.rabbitmq.PublishMessage(ctx, contracts.TripEventCreated, contracts.AmqpMessage{
-		OwnerID: trip.UserID,
-		Data:    tripJson,
-	})
+	return messaging.Publish(ctx, p.rabbitmq, contracts.TripEventCreated, trip.UserID, payload)
 }
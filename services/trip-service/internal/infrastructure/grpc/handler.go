@@ -2,9 +2,11 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"log"
 	"ride-sharing/services/trip-service/internal/domain"
 	"ride-sharing/services/trip-service/internal/infrastructure/events"
+	"ride-sharing/shared/auth"
 	pb "ride-sharing/shared/proto/trip"
 	"ride-sharing/shared/types"
 
@@ -30,17 +32,28 @@ func NewGRPCHandler(server *grpc.Server, service domain.TripService, publisher *
 }
 
 func (h *gRPCHandler) PreviewTrip(ctx context.Context, req *pb.PreviewTripRequest) (*pb.PreviewTripResponse, error) {
+	if err := auth.CheckUser(ctx, req.GetUserID()); err != nil {
+		return nil, err
+	}
+
 	pickup := &types.Coordinate{
-		Latitude:  req.GetStartLocation().Latitude,
-		Longitude: req.GetStartLocation().Longitude,
+		Latitude:  req.GetStartLocation().GetLatitude(),
+		Longitude: req.GetStartLocation().GetLongitude(),
 	}
 	destination := &types.Coordinate{
-		Latitude:  req.GetEndLocation().Latitude,
-		Longitude: req.GetEndLocation().Longitude,
+		Latitude:  req.GetEndLocation().GetLatitude(),
+		Longitude: req.GetEndLocation().GetLongitude(),
 	}
 
 	route, err := h.service.GetRoute(ctx, pickup, destination)
-	if err != nil {
+	switch {
+	case errors.Is(err, domain.ErrInvalidRoute):
+		return nil, status.Errorf(codes.InvalidArgument, "failed to get route: %v", err)
+	case errors.Is(err, domain.ErrRoutingUnavailable):
+		log.Println("error get route: ", err)
+		// the routing API is an external dependency, the call may be retried
+		return nil, status.Errorf(codes.Unavailable, "failed to get route: %v", err)
+	case err != nil:
 		log.Println("error get route: ", err)
 		return nil, status.Errorf(codes.Internal, "failed to get route: %v", err)
 	}
@@ -58,8 +71,17 @@ func (h *gRPCHandler) PreviewTrip(ctx context.Context, req *pb.PreviewTripReques
 }
 
 func (h *gRPCHandler) CreateTrip(ctx context.Context, req *pb.CreateTripRequest) (*pb.CreateTripResponse, error) {
+	if err := auth.CheckUser(ctx, req.GetUserID()); err != nil {
+		return nil, err
+	}
+
 	rideFare, err := h.service.GetAndValidateFare(ctx, req.RideFareID, req.UserID)
-	if err != nil {
+	switch {
+	case errors.Is(err, domain.ErrFareNotFound):
+		return nil, status.Errorf(codes.NotFound, "failed to validate the fare: %v", err)
+	case errors.Is(err, domain.ErrFareNotOwned):
+		return nil, status.Errorf(codes.PermissionDenied, "failed to validate the fare: %v", err)
+	case err != nil:
 		return nil, status.Errorf(codes.Internal, "failed to validate the fare: %v", err)
 	}
 
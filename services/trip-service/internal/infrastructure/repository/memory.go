@@ -32,7 +32,7 @@ func (r *MemoryRepository) SaveRideFare(ctx context.Context, fare *domain.RideFa
 func (r *MemoryRepository) GetRideByFareID(ctx context.Context, id string) (*domain.RideFareModel, error) {
 	fare, exixst := r.rideFares[id]
 	if !exixst {
-		return nil, fmt.Errorf("fare does not exsist with id %v", id)
+		return nil, fmt.Errorf("%w: %v", domain.ErrFareNotFound, id)
 	}
 
 	return fare, nil
@@ -17,11 +17,14 @@ import (
 
 type TripServiceImpl struct {
 	repository domain.TripRepository
+	// base URL of the OSRM routing API
+	routingURL string
 }
 
 func NewTripServiceImpl(repository domain.TripRepository) *TripServiceImpl {
 	return &TripServiceImpl{
 		repository: repository,
+		routingURL: "http://router.project-osrm.org",
 	}
 }
 
@@ -42,20 +45,38 @@ func (s *TripServiceImpl) CreateTrip(ctx context.Context, fare *domain.RideFareM
 	return newTrip, nil
 }
 func (s *TripServiceImpl) GetRoute(ctx context.Context, pickup, destination *types.Coordinate) (*tripTypes.OSRMAPIResponse, error) {
+	if err := validateCoordinate(pickup); err != nil {
+		return nil, fmt.Errorf("%w: pickup %v", domain.ErrInvalidRoute, err)
+	}
+	if err := validateCoordinate(destination); err != nil {
+		return nil, fmt.Errorf("%w: destination %v", domain.ErrInvalidRoute, err)
+	}
+
 	url := fmt.Sprintf(
-		"http://router.project-osrm.org/route/v1/driving/%f,%f;%f,%f?overview=full&geometries=geojson",
+		"%s/route/v1/driving/%f,%f;%f,%f?overview=full&geometries=geojson",
+		s.routingURL,
 		pickup.Longitude, pickup.Latitude,
 		destination.Longitude, destination.Latitude,
 	)
-	resp, err := http.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the route request: %v", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read route from ORSM API: %v", err)
+		return nil, fmt.Errorf("%w: failed to read route from OSRM API: %v", domain.ErrRoutingUnavailable, err)
 	}
 	defer resp.Body.Close()
 
+	// rate limited or failing, unlike the 4xx answers to invalid queries
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		return nil, fmt.Errorf("%w: OSRM API answered %s", domain.ErrRoutingUnavailable, resp.Status)
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read the response: %v", err)
+		return nil, fmt.Errorf("%w: failed to read the response: %v", domain.ErrRoutingUnavailable, err)
 	}
 
 	var routeResponse tripTypes.OSRMAPIResponse
@@ -63,9 +84,24 @@ func (s *TripServiceImpl) GetRoute(ctx context.Context, pickup, destination *typ
 		return nil, fmt.Errorf("failed to unmarshal the response: %v", err)
 	}
 
+	if routeResponse.Code != "Ok" || len(routeResponse.Routes) == 0 {
+		return nil, fmt.Errorf("%w: %s %s", domain.ErrInvalidRoute, routeResponse.Code, routeResponse.Message)
+	}
+
 	return &routeResponse, nil
 }
 
+func validateCoordinate(c *types.Coordinate) error {
+	if c.Latitude < -90 || c.Latitude > 90 {
+		return fmt.Errorf("latitude %f is out of range", c.Latitude)
+	}
+	if c.Longitude < -180 || c.Longitude > 180 {
+		return fmt.Errorf("longitude %f is out of range", c.Longitude)
+	}
+
+	return nil
+}
+
 func (s *TripServiceImpl) EstimatePackagesPriceWithRoute(route *tripTypes.OSRMAPIResponse) []*domain.RideFareModel {
 	baseFares := getBaseFares()
 	estimatedPrice := make([]*domain.RideFareModel, len(baseFares))
@@ -103,15 +139,15 @@ func (s *TripServiceImpl) GenerateTripFares(ctx context.Context, rideFares []*do
 func (s *TripServiceImpl) GetAndValidateFare(ctx context.Context, fareID, userID string) (*domain.RideFareModel, error) {
 	fare, err := s.repository.GetRideByFareID(ctx, fareID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get trip fare: %v", err)
+		return nil, fmt.Errorf("failed to get trip fare: %w", err)
 	}
 
 	if fare == nil {
-		return nil, fmt.Errorf("fare does not exist")
+		return nil, domain.ErrFareNotFound
 	}
 
 	if fare.UserID != userID {
-		return nil, fmt.Errorf("fares does not belong to the user")
+		return nil, domain.ErrFareNotOwned
 	}
 
 	return fare, nil
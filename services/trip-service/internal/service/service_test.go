@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ride-sharing/services/trip-service/internal/domain"
+	"ride-sharing/shared/types"
+)
+
+func TestGetRouteErrors(t *testing.T) {
+	sanFrancisco := &types.Coordinate{Latitude: 37.7749, Longitude: -122.4194}
+	oakland := &types.Coordinate{Latitude: 37.8044, Longitude: -122.2712}
+
+	tests := []struct {
+		name        string
+		destination *types.Coordinate
+		status      int
+		body        string
+		wantErr     error
+	}{
+		{
+			name:        "route",
+			destination: oakland,
+			status:      http.StatusOK,
+			body:        `{"code":"Ok","routes":[{"distance":1000,"duration":60,"geometry":{"coordinates":[[-122.4,37.7]]}}]}`,
+		},
+		{
+			name:        "no route",
+			destination: oakland,
+			status:      http.StatusBadRequest,
+			body:        `{"code":"NoRoute","message":"Impossible route between points"}`,
+			wantErr:     domain.ErrInvalidRoute,
+		},
+		{
+			name:        "invalid query",
+			destination: oakland,
+			status:      http.StatusBadRequest,
+			body:        `{"code":"InvalidQuery","message":"Query string malformed"}`,
+			wantErr:     domain.ErrInvalidRoute,
+		},
+		{
+			name:        "latitude out of range",
+			destination: &types.Coordinate{Latitude: 137.8, Longitude: -122.2},
+			wantErr:     domain.ErrInvalidRoute,
+		},
+		{
+			name:        "rate limited",
+			destination: oakland,
+			status:      http.StatusTooManyRequests,
+			wantErr:     domain.ErrRoutingUnavailable,
+		},
+		{
+			name:        "server error",
+			destination: oakland,
+			status:      http.StatusBadGateway,
+			wantErr:     domain.ErrRoutingUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.status == 0 {
+					t.Error("routing API called for invalid coordinates")
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer routing.Close()
+
+			service := NewTripServiceImpl(nil)
+			service.routingURL = routing.URL
+
+			route, err := service.GetRoute(context.Background(), sanFrancisco, tt.destination)
+			if tt.wantErr == nil {
+				if err != nil || len(route.Routes) != 1 {
+					t.Fatalf("GetRoute() = %v, %v, want a route", route, err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetRoute() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetRouteUnreachable(t *testing.T) {
+	routing := httptest.NewServer(http.NotFoundHandler())
+	routing.Close()
+
+	service := NewTripServiceImpl(nil)
+	service.routingURL = routing.URL
+
+	_, err := service.GetRoute(context.Background(), &types.Coordinate{}, &types.Coordinate{})
+	if !errors.Is(err, domain.ErrRoutingUnavailable) {
+		t.Fatalf("GetRoute() error = %v, want %v", err, domain.ErrRoutingUnavailable)
+	}
+}
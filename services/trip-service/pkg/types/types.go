@@ -3,7 +3,10 @@ package types
 import pb "ride-sharing/shared/proto/trip"
 
 type OSRMAPIResponse struct {
-	Routes []struct {
+	// "Ok", or the reason no route was returned, e.g. "NoRoute"
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Routes  []struct {
 		Distance float64 `json:"distance"`
 		Duration float64 `json:"duration"`
 		Geometry struct {
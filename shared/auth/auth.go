@@ -0,0 +1,64 @@
+/*
+Package auth verifies the JWTs presented to the api-gateway and carries the
+caller's identity from the gateway to the backend services.
+*/
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	RoleRider  = "rider"
+	RoleDriver = "driver"
+	RoleAdmin  = "admin"
+)
+
+var (
+	ErrMissingToken = errors.New("missing bearer token")
+	ErrInvalidToken = errors.New("invalid token")
+	ErrForbidden    = errors.New("role not allowed")
+)
+
+// Claims are the claims of an access token, the subject being the user ID.
+type Claims struct {
+	Role string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// Identity is the authenticated caller of a request.
+type Identity struct {
+	UserID string
+	Role   string
+}
+
+// HasRole reports whether the identity holds one of roles.
+func (i Identity) HasRole(roles ...string) bool {
+	return slices.Contains(roles, i.Role)
+}
+
+// CanActAs reports whether the identity may act on behalf of userID: users
+// act for themselves, admins for anyone.
+func (i Identity) CanActAs(userID string) bool {
+	return i.Role == RoleAdmin || i.UserID == userID
+}
+
+func isValidRole(role string) bool {
+	return role == RoleRider || role == RoleDriver || role == RoleAdmin
+}
+
+type identityKey struct{}
+
+func NewContext(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// FromContext returns the identity of the caller, if the request carried one.
+func FromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// metadata keys the gateway uses to pass the verified identity on; services
+// trust them as they are only reachable from inside the cluster
+const (
+	metadataUserID = "x-user-id"
+	metadataRole   = "x-user-role"
+)
+
+// UnaryClientInterceptor attaches the identity found in the call context to
+// the outgoing metadata.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if identity, ok := FromContext(ctx); ok {
+			ctx = metadata.AppendToOutgoingContext(ctx, metadataUserID, identity.UserID, metadataRole, identity.Role)
+		}
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// UnaryServerInterceptor puts the identity found in the incoming metadata in
+// the handler context. Calls without one, like the ones between services, are
+// passed through unchanged.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		userIDs, roles := md.Get(metadataUserID), md.Get(metadataRole)
+		if len(userIDs) == 0 && len(roles) == 0 {
+			return handler(ctx, req)
+		}
+
+		if len(userIDs) != 1 || len(roles) != 1 || userIDs[0] == "" || !isValidRole(roles[0]) {
+			return nil, status.Error(codes.Unauthenticated, "malformed caller identity")
+		}
+
+		return handler(NewContext(ctx, Identity{UserID: userIDs[0], Role: roles[0]}), req)
+	}
+}
+
+// CheckUser rejects calls made on behalf of another user than userID. Calls
+// without identity are allowed.
+func CheckUser(ctx context.Context, userID string) error {
+	identity, ok := FromContext(ctx)
+	if !ok || identity.CanActAs(userID) {
+		return nil
+	}
+
+	return status.Errorf(codes.PermissionDenied, "user %s cannot act on behalf of %s", identity.UserID, userID)
+}
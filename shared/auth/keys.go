@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeySet holds the keys tokens may be signed with: HMAC secrets for HS256 and
+// RSA public keys for RS256, both looked up by the "kid" header. A token
+// without kid uses the key registered under the empty kid.
+//
+// RSA keys come from local files or from the JWKS of the auth service, the
+// latter being replaced as a whole on every refresh to follow key rotation.
+type KeySet struct {
+	mu     sync.RWMutex
+	hmac   map[string][]byte
+	rsa    map[string]*rsa.PublicKey
+	jwks   map[string]*rsa.PublicKey
+	issuer string
+}
+
+func NewKeySet(issuer string) *KeySet {
+	return &KeySet{
+		hmac:   make(map[string][]byte),
+		rsa:    make(map[string]*rsa.PublicKey),
+		jwks:   make(map[string]*rsa.PublicKey),
+		issuer: issuer,
+	}
+}
+
+func (k *KeySet) AddHMAC(kid string, secret []byte) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.hmac[kid] = secret
+}
+
+func (k *KeySet) AddRSA(kid string, key *rsa.PublicKey) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.rsa[kid] = key
+}
+
+// SetJWKS replaces the keys previously taken from a JWKS. Keys that are not
+// RS256 signing keys are skipped.
+func (k *KeySet) SetJWKS(jwks JWKS) error {
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Alg != "RS256" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+
+		key, err := jwk.RSAPublicKey()
+		if err != nil {
+			return fmt.Errorf("failed to parse key %q: %v", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.jwks = keys
+	return nil
+}
+
+// LoadKeySet builds a key set from an HMAC secret and a directory of PEM
+// encoded RSA public keys named <kid>.pem, either of them may be empty.
+func LoadKeySet(hmacSecret, publicKeysDir, issuer string) (*KeySet, error) {
+	keys := NewKeySet(issuer)
+
+	if hmacSecret != "" {
+		keys.AddHMAC("", []byte(hmacSecret))
+	}
+
+	if publicKeysDir != "" {
+		files, err := filepath.Glob(filepath.Join(publicKeysDir, "*.pem"))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read public key: %v", err)
+			}
+
+			key, err := jwt.ParseRSAPublicKeyFromPEM(data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse public key %s: %v", file, err)
+			}
+
+			keys.AddRSA(strings.TrimSuffix(filepath.Base(file), ".pem"), key)
+		}
+	}
+
+	return keys, nil
+}
+
+// Verify checks the signature and expiry of an access token and returns the
+// identity it was issued to.
+func (k *KeySet) Verify(token string) (Identity, error) {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"HS256", "RS256"}),
+		jwt.WithExpirationRequired(),
+	}
+	if k.issuer != "" {
+		options = append(options, jwt.WithIssuer(k.issuer))
+	}
+
+	var claims Claims
+	if _, err := jwt.ParseWithClaims(token, &claims, k.keyFunc, options...); err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if claims.Subject == "" || !isValidRole(claims.Role) {
+		return Identity{}, fmt.Errorf("%w: missing subject or unknown role", ErrInvalidToken)
+	}
+
+	return Identity{UserID: claims.Subject, Role: claims.Role}, nil
+}
+
+func (k *KeySet) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	switch token.Method.Alg() {
+	case "HS256":
+		if secret, ok := k.hmac[kid]; ok {
+			return secret, nil
+		}
+	case "RS256":
+		if key, ok := k.rsa[kid]; ok {
+			return key, nil
+		}
+		if key, ok := k.jwks[kid]; ok {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no %s key for kid %q", token.Method.Alg(), kid)
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testIssuer = "auth-service"
+
+var testSecret = []byte("test-secret")
+
+// newTestKeySet trusts testSecret without kid, an RSA key under rs-1 and
+// another one from the JWKS under jwks-1.
+func newTestKeySet(t *testing.T) (keys *KeySet, rsaKey, jwksKey *rsa.PrivateKey) {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwksKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys = NewKeySet(testIssuer)
+	keys.AddHMAC("", testSecret)
+	keys.AddRSA("rs-1", &rsaKey.PublicKey)
+	if err := keys.SetJWKS(JWKS{Keys: []JWK{NewRSAJWK("jwks-1", &jwksKey.PublicKey)}}); err != nil {
+		t.Fatalf("SetJWKS() error = %v", err)
+	}
+
+	return keys, rsaKey, jwksKey
+}
+
+func testClaims(userID, role string) Claims {
+	return Claims{
+		Role: role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID,
+			Issuer:    testIssuer,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any, claims Claims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign %s token: %v", method.Alg(), err)
+	}
+	return signed
+}
+
+func TestKeySetVerify(t *testing.T) {
+	keys, rsaKey, jwksKey := newTestKeySet(t)
+
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	rider := testClaims("rider-1", RoleRider)
+
+	expired := rider
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	noExpiry := rider
+	noExpiry.ExpiresAt = nil
+	otherIssuer := rider
+	otherIssuer.Issuer = "someone-else"
+
+	tests := []struct {
+		name    string
+		token   string
+		want    Identity
+		wantErr error
+	}{
+		{"HS256", sign(t, jwt.SigningMethodHS256, "", testSecret, rider), Identity{UserID: "rider-1", Role: RoleRider}, nil},
+		{"RS256", sign(t, jwt.SigningMethodRS256, "rs-1", rsaKey, testClaims("driver-1", RoleDriver)), Identity{UserID: "driver-1", Role: RoleDriver}, nil},
+		{"RS256 from the JWKS", sign(t, jwt.SigningMethodRS256, "jwks-1", jwksKey, rider), Identity{UserID: "rider-1", Role: RoleRider}, nil},
+
+		// alg confusion: the public key used as an HMAC secret
+		{"HS256 signed with the RSA public key", sign(t, jwt.SigningMethodHS256, "rs-1", publicPEM, rider), Identity{}, ErrInvalidToken},
+		{"RS256 under the kid of the HMAC secret", sign(t, jwt.SigningMethodRS256, "", rsaKey, rider), Identity{}, ErrInvalidToken},
+		{"none", sign(t, jwt.SigningMethodNone, "", jwt.UnsafeAllowNoneSignatureType, rider), Identity{}, ErrInvalidToken},
+
+		{"unknown kid", sign(t, jwt.SigningMethodRS256, "rs-2", rsaKey, rider), Identity{}, ErrInvalidToken},
+		{"RS256 signed by another key", sign(t, jwt.SigningMethodRS256, "rs-1", jwksKey, rider), Identity{}, ErrInvalidToken},
+		{"HS256 signed by another secret", sign(t, jwt.SigningMethodHS256, "", []byte("guessed"), rider), Identity{}, ErrInvalidToken},
+		{"expired", sign(t, jwt.SigningMethodHS256, "", testSecret, expired), Identity{}, ErrInvalidToken},
+		{"without expiry", sign(t, jwt.SigningMethodHS256, "", testSecret, noExpiry), Identity{}, ErrInvalidToken},
+		{"other issuer", sign(t, jwt.SigningMethodHS256, "", testSecret, otherIssuer), Identity{}, ErrInvalidToken},
+		{"without subject", sign(t, jwt.SigningMethodHS256, "", testSecret, testClaims("", RoleRider)), Identity{}, ErrInvalidToken},
+		{"unknown role", sign(t, jwt.SigningMethodHS256, "", testSecret, testClaims("rider-1", "root")), Identity{}, ErrInvalidToken},
+		{"not a JWT", "not-a-token", Identity{}, ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := keys.Verify(tt.token)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("Verify() = %+v, %v, want %+v, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeySetJWKSRotation(t *testing.T) {
+	keys, _, jwksKey := newTestKeySet(t)
+	token := sign(t, jwt.SigningMethodRS256, "jwks-1", jwksKey, testClaims("rider-1", RoleRider))
+
+	// the auth service rotated its key out
+	if err := keys.SetJWKS(JWKS{}); err != nil {
+		t.Fatalf("SetJWKS() error = %v", err)
+	}
+	if _, err := keys.Verify(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify() with a rotated out key error = %v, want %v", err, ErrInvalidToken)
+	}
+}
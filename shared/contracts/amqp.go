@@ -1,11 +1,41 @@
 package contracts
 
-// AmqpMessage is the message structure for AMQP.
+import "time"
+
+// AmqpMessage is the envelope of every AMQP message. Data holds the JSON of
+// the payload, whose schema is identified by Type and Version.
+//
+// Messages published before the envelope only carry OwnerID and Data, they
+// are read as version 1.0 of the type given by their routing key.
 type AmqpMessage struct {
-	OwnerID string `json:"ownerId"`
-	Data    []byte `json:"data"`
+	EventID string `json:"eventId,omitempty"`
+	// routing key the event was published with
+	Type string `json:"type,omitempty"`
+	// schema version of Data, "<major>.<minor>"
+	Version   string    `json:"version,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	// ID of the first event of the chain that led to this one
+	CorrelationID string `json:"correlationId,omitempty"`
+	// service that published the event
+	Producer string `json:"producer,omitempty"`
+	OwnerID  string `json:"ownerId"`
+	Data     []byte `json:"data"`
+}
+
+// EventSchemaVersion is the version of the payload schema of an event type.
+// Bump the minor version for compatible changes, like new optional fields,
+// and the major version for breaking ones: consumers reject the majors they
+// do not know.
+func EventSchemaVersion(eventType string) string {
+	if version, ok := eventSchemaVersions[eventType]; ok {
+		return version
+	}
+	return "1.0"
 }
 
+// versions of the event types past 1.0
+var eventSchemaVersions = map[string]string{}
+
 // Routing keys - using consistent event/command patterns
 const (
 	// Trip events (trip.event.*)
@@ -18,8 +48,10 @@ const (
 	DriverCmdTripRequest = "driver.cmd.trip_request"
 	DriverCmdTripAccept  = "driver.cmd.trip_accept"
 	DriverCmdTripDecline = "driver.cmd.trip_decline"
+	DriverCmdTripCancel  = "driver.cmd.trip_cancel"
 	DriverCmdLocation    = "driver.cmd.location"
 	DriverCmdRegister    = "driver.cmd.register"
+	DriverCmdDestination = "driver.cmd.destination"
 
 	// Payment events (payment.event.*)
 	PaymentEventSessionCreated = "payment.event.session_created"
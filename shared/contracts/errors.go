@@ -0,0 +1,109 @@
+package contracts
+
+import (
+	"net/http"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Machine-readable error codes of APIError. Clients may switch on them, so
+// they never change once released.
+const (
+	ErrCodeMalformedRequest   = "malformed_request"
+	ErrCodeInvalidArgument    = "invalid_argument"
+	ErrCodeUnauthenticated    = "unauthenticated"
+	ErrCodePermissionDenied   = "permission_denied"
+	ErrCodeNotFound           = "not_found"
+	ErrCodeAlreadyExists      = "already_exists"
+	ErrCodeFailedPrecondition = "failed_precondition"
+	ErrCodeResourceExhausted  = "resource_exhausted"
+	ErrCodeCanceled           = "canceled"
+	ErrCodeUnimplemented      = "unimplemented"
+	ErrCodeUnavailable        = "unavailable"
+	ErrCodeTimeout            = "timeout"
+	ErrCodeInternal           = "internal"
+)
+
+type errorMapping struct {
+	httpStatus int
+	code       string
+}
+
+// grpcErrors maps the status codes of the backend services to the HTTP status
+// and error code the gateway answers with. Codes missing here are internal
+// errors.
+var grpcErrors = map[codes.Code]errorMapping{
+	codes.InvalidArgument:    {http.StatusBadRequest, ErrCodeInvalidArgument},
+	codes.OutOfRange:         {http.StatusBadRequest, ErrCodeInvalidArgument},
+	codes.Unauthenticated:    {http.StatusUnauthorized, ErrCodeUnauthenticated},
+	codes.PermissionDenied:   {http.StatusForbidden, ErrCodePermissionDenied},
+	codes.NotFound:           {http.StatusNotFound, ErrCodeNotFound},
+	codes.AlreadyExists:      {http.StatusConflict, ErrCodeAlreadyExists},
+	codes.Aborted:            {http.StatusConflict, ErrCodeFailedPrecondition},
+	codes.FailedPrecondition: {http.StatusConflict, ErrCodeFailedPrecondition},
+	codes.ResourceExhausted:  {http.StatusTooManyRequests, ErrCodeResourceExhausted},
+	codes.Canceled:           {499, ErrCodeCanceled},
+	codes.Unimplemented:      {http.StatusNotImplemented, ErrCodeUnimplemented},
+	codes.Unavailable:        {http.StatusServiceUnavailable, ErrCodeUnavailable},
+	codes.DeadlineExceeded:   {http.StatusGatewayTimeout, ErrCodeTimeout},
+}
+
+// HTTPError returns the HTTP status and error code for a gRPC status code.
+// Internal and unknown failures of a backend are reported as 502.
+func HTTPError(code codes.Code) (int, string) {
+	if mapping, ok := grpcErrors[code]; ok {
+		return mapping.httpStatus, mapping.code
+	}
+
+	return http.StatusBadGateway, ErrCodeInternal
+}
+
+// IsClientError reports whether the caller can act on the message of an error
+// with this code, other messages are internal and not shown to clients.
+func IsClientError(code codes.Code) bool {
+	httpStatus, _ := HTTPError(code)
+	return httpStatus < http.StatusInternalServerError
+}
+
+// InvalidArgumentError builds an InvalidArgument status carrying the failed
+// fields, for the gateway to return them as APIError details.
+func InvalidArgumentError(message string, fields ...FieldError) error {
+	st := status.New(codes.InvalidArgument, message)
+	if len(fields) == 0 {
+		return st.Err()
+	}
+
+	violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(fields))
+	for _, field := range fields {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       field.Field,
+			Description: field.Message,
+		})
+	}
+
+	detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// FieldErrors extracts the failed fields attached by InvalidArgumentError.
+func FieldErrors(st *status.Status) []FieldError {
+	var fields []FieldError
+	for _, detail := range st.Details() {
+		badRequest, ok := detail.(*errdetails.BadRequest)
+		if !ok {
+			continue
+		}
+
+		for _, violation := range badRequest.GetFieldViolations() {
+			fields = append(fields, FieldError{Field: violation.GetField(), Message: violation.GetDescription()})
+		}
+	}
+
+	return fields
+}
@@ -8,6 +8,13 @@ type APIResponse struct {
 
 // APIError is the error structure for the API.
 type APIError struct {
-	Code    string `json:"code"`
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+// FieldError tells which field of the request failed validation and why.
+type FieldError struct {
+	Field   string `json:"field"`
 	Message string `json:"message"`
 }
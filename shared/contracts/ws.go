@@ -2,6 +2,10 @@ package contracts
 
 import "encoding/json"
 
+// WSMessageTypeError is the type of the message sent to a client whose message
+// could not be processed, with an APIError as data.
+const WSMessageTypeError = "error"
+
 // WSMessage is the message structure for the WebSocket.
 type WSMessage struct {
 	Type string `json:"type"`
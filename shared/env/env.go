@@ -6,6 +6,7 @@ package env
 import (
 	"os"
 	"strconv"
+	"time"
 )
 
 func GetString(key, fallback string) string {
@@ -44,3 +45,17 @@ func GetBool(key string, fallback bool) bool {
 
 	return boolVal
 }
+
+func GetDuration(key string, fallback time.Duration) time.Duration {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+
+	durationVal, err := time.ParseDuration(val)
+	if err != nil {
+		return fallback
+	}
+
+	return durationVal
+}
@@ -0,0 +1,171 @@
+/*
+Package matching provides assignment solvers used to pair pending trips with
+available drivers, given a cost matrix of trips (rows) by drivers (columns).
+*/
+package matching
+
+import "math"
+
+// Infeasible marks a trip/driver pair that must never be assigned, e.g. when the
+// driver's vehicle does not serve the trip's package.
+const Infeasible = 1e12
+
+// Unassigned is returned for a row that could not be paired with any column.
+const Unassigned = -1
+
+// Greedy assigns each row, in order, to the cheapest column that is still free.
+// It mirrors first-come matching and is the baseline for Optimal.
+func Greedy(cost [][]float64) []int {
+	assignment := make([]int, len(cost))
+	taken := make(map[int]bool)
+
+	for i, row := range cost {
+		assignment[i] = Unassigned
+		best := Infeasible
+
+		for j, c := range row {
+			if !taken[j] && c < best {
+				best = c
+				assignment[i] = j
+			}
+		}
+
+		if assignment[i] != Unassigned {
+			taken[assignment[i]] = true
+		}
+	}
+
+	return assignment
+}
+
+// Optimal solves the assignment problem with the Hungarian algorithm so the
+// total cost over all assigned rows is minimal. The matrix may be rectangular;
+// rows left without a feasible column are Unassigned.
+func Optimal(cost [][]float64) []int {
+	rows := len(cost)
+	if rows == 0 {
+		return []int{}
+	}
+	cols := len(cost[0])
+
+	assignment := make([]int, rows)
+	for i := range assignment {
+		assignment[i] = Unassigned
+	}
+	if cols == 0 {
+		return assignment
+	}
+
+	// the solver below needs rows <= cols, so work on the transpose otherwise
+	if rows > cols {
+		transposed := make([][]float64, cols)
+		for j := range transposed {
+			transposed[j] = make([]float64, rows)
+			for i := range rows {
+				transposed[j][i] = cost[i][j]
+			}
+		}
+
+		for j, i := range hungarian(transposed) {
+			if i != Unassigned && cost[i][j] < Infeasible {
+				assignment[i] = j
+			}
+		}
+		return assignment
+	}
+
+	for i, j := range hungarian(cost) {
+		if j != Unassigned && cost[i][j] < Infeasible {
+			assignment[i] = j
+		}
+	}
+	return assignment
+}
+
+// hungarian is the O(n^2*m) potentials-based Hungarian algorithm for an n x m
+// matrix with n <= m. It returns the column assigned to each row.
+func hungarian(cost [][]float64) []int {
+	n, m := len(cost), len(cost[0])
+
+	// potentials and matching are 1-indexed, index 0 is a virtual row/column
+	u := make([]float64, n+1)
+	v := make([]float64, m+1)
+	p := make([]int, m+1)
+	way := make([]int, m+1)
+
+	for i := 1; i <= n; i++ {
+		p[0] = i
+		j0 := 0
+		minv := make([]float64, m+1)
+		used := make([]bool, m+1)
+		for j := range minv {
+			minv[j] = math.Inf(1)
+		}
+
+		for {
+			used[j0] = true
+			i0 := p[j0]
+			delta := math.Inf(1)
+			j1 := 0
+
+			for j := 1; j <= m; j++ {
+				if used[j] {
+					continue
+				}
+				cur := cost[i0-1][j-1] - u[i0] - v[j]
+				if cur < minv[j] {
+					minv[j] = cur
+					way[j] = j0
+				}
+				if minv[j] < delta {
+					delta = minv[j]
+					j1 = j
+				}
+			}
+
+			for j := 0; j <= m; j++ {
+				if used[j] {
+					u[p[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+
+			j0 = j1
+			if p[j0] == 0 {
+				break
+			}
+		}
+
+		for j0 != 0 {
+			j1 := way[j0]
+			p[j0] = p[j1]
+			j0 = j1
+		}
+	}
+
+	assignment := make([]int, n)
+	for i := range assignment {
+		assignment[i] = Unassigned
+	}
+	for j := 1; j <= m; j++ {
+		if p[j] != 0 {
+			assignment[p[j]-1] = j - 1
+		}
+	}
+
+	return assignment
+}
+
+// TotalCost sums the cost of every assigned row.
+func TotalCost(cost [][]float64, assignment []int) float64 {
+	total := 0.0
+	for i, j := range assignment {
+		if j != Unassigned {
+			total += cost[i][j]
+		}
+	}
+
+	return total
+}
@@ -0,0 +1,285 @@
+package matching
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+const averageSpeedKMH = 30.0
+
+// San Francisco bounding box, same area as the driver service predefined routes
+const (
+	minLat, maxLat = 37.70, 37.81
+	minLon, maxLon = -122.51, -122.38
+)
+
+// inf keeps the tables readable
+const inf = Infeasible
+
+func TestGreedy(t *testing.T) {
+	tests := []struct {
+		name string
+		cost [][]float64
+		want []int
+	}{
+		{
+			name: "first come first served",
+			cost: [][]float64{
+				{1, 2},
+				{1, 10},
+			},
+			want: []int{0, 1},
+		},
+		{
+			name: "infeasible pairs",
+			cost: [][]float64{
+				{inf, 3},
+				{inf, 1},
+			},
+			want: []int{1, Unassigned},
+		},
+		{
+			name: "more trips than drivers",
+			cost: [][]float64{
+				{5},
+				{1},
+			},
+			want: []int{0, Unassigned},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Greedy(tt.cost); !slices.Equal(got, tt.want) {
+				t.Errorf("Greedy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptimal(t *testing.T) {
+	tests := []struct {
+		name string
+		cost [][]float64
+		want []int
+	}{
+		{
+			name: "empty",
+			cost: [][]float64{},
+			want: []int{},
+		},
+		{
+			name: "no drivers",
+			cost: [][]float64{{}, {}},
+			want: []int{Unassigned, Unassigned},
+		},
+		{
+			name: "beats first come first served",
+			cost: [][]float64{
+				{1, 2},
+				{1, 10},
+			},
+			want: []int{1, 0},
+		},
+		{
+			name: "more drivers than trips",
+			cost: [][]float64{
+				{4, 1, 3},
+				{5, 0, 5},
+			},
+			want: []int{2, 1},
+		},
+		{
+			name: "more trips than drivers",
+			cost: [][]float64{
+				{5},
+				{1},
+				{3},
+			},
+			want: []int{Unassigned, 0, Unassigned},
+		},
+		{
+			name: "infeasible pairs stay unassigned",
+			cost: [][]float64{
+				{inf, 3},
+				{inf, 1},
+			},
+			want: []int{Unassigned, 1},
+		},
+		{
+			name: "every pair infeasible",
+			cost: [][]float64{
+				{inf, inf},
+				{inf, inf},
+			},
+			want: []int{Unassigned, Unassigned},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Optimal(tt.cost); !slices.Equal(got, tt.want) {
+				t.Errorf("Optimal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestOptimalMatchesBruteForce compares Optimal with every possible assignment
+// of small random matrices, some pairs infeasible.
+func TestOptimalMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 1))
+
+	for range 200 {
+		rows, cols := 1+rng.IntN(5), 1+rng.IntN(5)
+		cost := make([][]float64, rows)
+		for i := range cost {
+			cost[i] = make([]float64, cols)
+			for j := range cost[i] {
+				cost[i][j] = float64(rng.IntN(100))
+				if rng.IntN(5) == 0 {
+					cost[i][j] = Infeasible
+				}
+			}
+		}
+
+		got := Optimal(cost)
+		assertValidAssignment(t, cost, got)
+
+		wantAssigned, wantCost := bruteForce(cost, 0, make([]bool, cols))
+		if assigned(got) != wantAssigned || math.Abs(TotalCost(cost, got)-wantCost) > 1e-9 {
+			t.Fatalf("Optimal(%v) = %v assigning %d rows for %v, want %d rows for %v",
+				cost, got, assigned(got), TotalCost(cost, got), wantAssigned, wantCost)
+		}
+	}
+}
+
+// TestOptimalSavesPickupTime checks that batch matching never does worse than
+// first-come matching on random rush-hour scenarios, and reports the saving.
+func TestOptimalSavesPickupTime(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 1))
+
+	var greedyTotal, optimalTotal float64
+	for range 100 {
+		cost := randomCostMatrix(rng, 50, 60)
+
+		greedy, optimal := Greedy(cost), Optimal(cost)
+		if assigned(optimal) != assigned(greedy) {
+			t.Fatalf("Optimal assigned %d trips, Greedy %d", assigned(optimal), assigned(greedy))
+		}
+		if TotalCost(cost, optimal) > TotalCost(cost, greedy)+1e-6 {
+			t.Fatalf("Optimal total pickup time %v is above Greedy %v", TotalCost(cost, optimal), TotalCost(cost, greedy))
+		}
+
+		greedyTotal += TotalCost(cost, greedy)
+		optimalTotal += TotalCost(cost, optimal)
+	}
+
+	t.Logf("batch matching saves %.1f%% of the total pickup time", 100*(1-optimalTotal/greedyTotal))
+}
+
+func BenchmarkGreedy(b *testing.B) {
+	cost := randomCostMatrix(rand.New(rand.NewPCG(1, 1)), 50, 60)
+	b.ResetTimer()
+
+	for range b.N {
+		Greedy(cost)
+	}
+}
+
+func BenchmarkOptimal(b *testing.B) {
+	cost := randomCostMatrix(rand.New(rand.NewPCG(1, 1)), 50, 60)
+	b.ResetTimer()
+
+	for range b.N {
+		Optimal(cost)
+	}
+}
+
+// bruteForce returns the most rows that can be assigned from row i on, and the
+// lowest total cost doing so.
+func bruteForce(cost [][]float64, i int, taken []bool) (int, float64) {
+	if i == len(cost) {
+		return 0, 0
+	}
+
+	// leave the row unassigned
+	bestAssigned, bestCost := bruteForce(cost, i+1, taken)
+
+	for j, c := range cost[i] {
+		if taken[j] || c >= Infeasible {
+			continue
+		}
+
+		taken[j] = true
+		n, total := bruteForce(cost, i+1, taken)
+		taken[j] = false
+
+		n, total = n+1, total+c
+		if n > bestAssigned || (n == bestAssigned && total < bestCost) {
+			bestAssigned, bestCost = n, total
+		}
+	}
+
+	return bestAssigned, bestCost
+}
+
+func assertValidAssignment(t *testing.T, cost [][]float64, assignment []int) {
+	t.Helper()
+
+	taken := make(map[int]bool)
+	for i, j := range assignment {
+		if j == Unassigned {
+			continue
+		}
+		if cost[i][j] >= Infeasible {
+			t.Fatalf("row %d assigned to infeasible column %d", i, j)
+		}
+		if taken[j] {
+			t.Fatalf("column %d assigned twice in %v", j, assignment)
+		}
+		taken[j] = true
+	}
+}
+
+func assigned(assignment []int) int {
+	count := 0
+	for _, j := range assignment {
+		if j != Unassigned {
+			count++
+		}
+	}
+
+	return count
+}
+
+// randomCostMatrix places trips and drivers uniformly in the city and returns
+// the pickup time in seconds for every pair.
+func randomCostMatrix(rng *rand.Rand, trips, drivers int) [][]float64 {
+	driverLocations := make([][2]float64, drivers)
+	for j := range driverLocations {
+		driverLocations[j] = randomLocation(rng)
+	}
+
+	cost := make([][]float64, trips)
+	for i := range cost {
+		pickup := randomLocation(rng)
+		cost[i] = make([]float64, drivers)
+
+		for j, d := range driverLocations {
+			distance := HaversineKM(d[0], d[1], pickup[0], pickup[1])
+			cost[i][j] = PickupSeconds(distance, averageSpeedKMH)
+		}
+	}
+
+	return cost
+}
+
+func randomLocation(rng *rand.Rand) [2]float64 {
+	return [2]float64{
+		minLat + rng.Float64()*(maxLat-minLat),
+		minLon + rng.Float64()*(maxLon-minLon),
+	}
+}
@@ -0,0 +1,26 @@
+package matching
+
+import "math"
+
+const earthRadiusKM = 6371.0
+
+// HaversineKM returns the great-circle distance in kilometers between two points.
+func HaversineKM(lat1, lon1, lat2, lon2 float64) float64 {
+	dLat := toRadians(lat2 - lat1)
+	dLon := toRadians(lon2 - lon1)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadiusKM * math.Asin(math.Sqrt(a))
+}
+
+// PickupSeconds estimates the time a driver needs to cover distanceKM at the
+// given average speed.
+func PickupSeconds(distanceKM, averageSpeedKMH float64) float64 {
+	return distanceKM / averageSpeedKMH * 3600
+}
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
@@ -0,0 +1,65 @@
+package messaging
+
+import (
+	"context"
+	"ride-sharing/shared/contracts"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Broker is what the services publish and consume messages through: RabbitMQ
+// in deployments, MemoryBroker in tests and local runs without a broker.
+type Broker interface {
+	// PublishMessage publishes message with its Data as is, to PaymentExchange
+	// for payment.* routing keys and to TripExchange otherwise.
+	PublishMessage(ctx context.Context, routingKey string, message contracts.AmqpMessage) error
+	// PublishEvent publishes message like PublishMessage with payload encoded
+	// by the codec of the broker as its data, see Publish.
+	PublishEvent(ctx context.Context, routingKey string, message contracts.AmqpMessage, payload any) error
+	// ConsumeMessages registers handler for the messages of queue, a message
+	// is acked once handler returns and retried later when it fails.
+	ConsumeMessages(queue string, handler MessageHandler, opts ...ConsumerOption) error
+	// DeclareBroadcastQueue declares a queue of this client receiving every
+	// message broadcast to GatewayBroadcastExchange.
+	DeclareBroadcastQueue() (string, error)
+	BroadcastDelivery(ctx context.Context, msg amqp.Delivery) error
+	// Shutdown stops consuming and waits for the messages being handled.
+	Shutdown(ctx context.Context) error
+	Close()
+}
+
+var (
+	_ Broker = (*RabbitMQ)(nil)
+	_ Broker = (*MemoryBroker)(nil)
+)
+
+// Option configures a Broker.
+type Option func(*options)
+
+type options struct {
+	codec    Codec
+	topology Topology
+}
+
+// WithCodec publishes the messages with codec instead of JSONCodec.
+func WithCodec(codec Codec) Option {
+	return func(o *options) {
+		o.codec = codec
+	}
+}
+
+// WithTopology declares topology instead of DefaultTopology, usually the part
+// of it the service needs, see Topology.For.
+func WithTopology(topology Topology) Option {
+	return func(o *options) {
+		o.topology = topology
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{codec: JSONCodec, topology: DefaultTopology}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
@@ -0,0 +1,226 @@
+package messaging
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"ride-sharing/shared/contracts"
+	pbe "ride-sharing/shared/proto/events"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// content types of the message bodies, set in the ContentType property of each
+// message so consumers decode it whatever the codec of its publisher
+const (
+	ContentTypeJSON      = "application/json"
+	ContentTypeProtobuf  = "application/x-protobuf"
+	ContentTypeProtoJSON = "application/x-protojson"
+
+	// messages published before the codecs, their body is JSON
+	contentTypeLegacy = "text/plain"
+)
+
+// ErrUnsupportedContentType is a message no codec can decode. Retrying cannot
+// help, such messages are parked right away.
+var ErrUnsupportedContentType = errors.New("unsupported content type")
+
+// Codec encodes the envelope of a message together with its payload.
+type Codec interface {
+	ContentType() string
+	// Encode returns the body of message carrying payload, a nil payload
+	// keeps the data of message
+	Encode(message contracts.AmqpMessage, payload any) ([]byte, error)
+	// Decode reads the envelope of body, its Data is decoded by DecodePayload
+	Decode(body []byte) (contracts.AmqpMessage, error)
+	DecodePayload(data []byte, v any) error
+}
+
+var (
+	// JSONCodec writes the envelope and payload as JSON, the default.
+	JSONCodec Codec = jsonCodec{}
+	// ProtobufCodec writes an events.Envelope in the protobuf binary format,
+	// the payload must be a protobuf message or a ProtoPayload.
+	ProtobufCodec Codec = protoCodec{}
+	// ProtoJSONCodec writes the same envelope as ProtobufCodec as protojson.
+	ProtoJSONCodec Codec = protoCodec{json: true}
+)
+
+// CodecFor returns the codec of the messages of contentType.
+func CodecFor(contentType string) (Codec, error) {
+	switch contentType {
+	case ContentTypeJSON, contentTypeLegacy, "":
+		return JSONCodec, nil
+	case ContentTypeProtobuf:
+		return ProtobufCodec, nil
+	case ContentTypeProtoJSON:
+		return ProtoJSONCodec, nil
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnsupportedContentType, contentType)
+}
+
+// DecodePayload decodes the data of an envelope received with contentType.
+func DecodePayload(contentType string, data []byte, v any) error {
+	codec, err := CodecFor(contentType)
+	if err != nil {
+		return err
+	}
+
+	return codec.DecodePayload(data, v)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string {
+	return ContentTypeJSON
+}
+
+func (jsonCodec) Encode(message contracts.AmqpMessage, payload any) ([]byte, error) {
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal payload: %v", err)
+		}
+		message.Data = data
+	}
+
+	return json.Marshal(message)
+}
+
+func (jsonCodec) Decode(body []byte) (contracts.AmqpMessage, error) {
+	var message contracts.AmqpMessage
+	err := json.Unmarshal(body, &message)
+	return message, err
+}
+
+func (jsonCodec) DecodePayload(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// ProtoPayload is a payload published as its protobuf counterpart.
+type ProtoPayload interface {
+	protoEncoder
+	// FromProto sets the payload from a message of the type ToProto returns
+	FromProto(proto.Message) error
+}
+
+// protoEncoder is implemented by the payload values, FromProto needs a pointer
+type protoEncoder interface {
+	ToProto() proto.Message
+}
+
+// protoCodec carries the payload in an Any holding its protobuf binary
+// encoding, in both formats: the payload type is named in the envelope and
+// consumers decode it the same way.
+type protoCodec struct {
+	json bool
+}
+
+func (c protoCodec) ContentType() string {
+	if c.json {
+		return ContentTypeProtoJSON
+	}
+	return ContentTypeProtobuf
+}
+
+func (c protoCodec) Encode(message contracts.AmqpMessage, payload any) ([]byte, error) {
+	envelope := &pbe.Envelope{
+		EventID:       message.EventID,
+		Type:          message.Type,
+		Version:       message.Version,
+		Timestamp:     timestamppb.New(message.Timestamp),
+		CorrelationID: message.CorrelationID,
+		Producer:      message.Producer,
+		OwnerID:       message.OwnerID,
+	}
+
+	if payload != nil {
+		msg, err := toProto(payload)
+		if err != nil {
+			return nil, err
+		}
+		if envelope.Data, err = anypb.New(msg); err != nil {
+			return nil, fmt.Errorf("failed to marshal payload: %v", err)
+		}
+	} else if len(message.Data) > 0 {
+		return nil, fmt.Errorf("raw data cannot be published as %s", c.ContentType())
+	}
+
+	if c.json {
+		return protojson.Marshal(envelope)
+	}
+	return proto.Marshal(envelope)
+}
+
+func (c protoCodec) Decode(body []byte) (contracts.AmqpMessage, error) {
+	envelope := &pbe.Envelope{}
+
+	var err error
+	if c.json {
+		err = protojson.Unmarshal(body, envelope)
+	} else {
+		err = proto.Unmarshal(body, envelope)
+	}
+	if err != nil {
+		return contracts.AmqpMessage{}, err
+	}
+
+	message := contracts.AmqpMessage{
+		EventID:       envelope.EventID,
+		Type:          envelope.Type,
+		Version:       envelope.Version,
+		CorrelationID: envelope.CorrelationID,
+		Producer:      envelope.Producer,
+		OwnerID:       envelope.OwnerID,
+	}
+	if envelope.Timestamp != nil {
+		message.Timestamp = envelope.Timestamp.AsTime()
+	}
+	if envelope.Data != nil {
+		message.Data = envelope.Data.Value
+	}
+
+	return message, nil
+}
+
+func (protoCodec) DecodePayload(data []byte, v any) error {
+	switch p := v.(type) {
+	case proto.Message:
+		return proto.Unmarshal(data, p)
+	case ProtoPayload:
+		msg := p.ToProto().ProtoReflect().New().Interface()
+		if err := proto.Unmarshal(data, msg); err != nil {
+			return err
+		}
+		return p.FromProto(msg)
+	}
+
+	// a pointer to a protobuf message pointer, e.g. the payload of Event[*pb.Trip]
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && rv.Elem().Kind() == reflect.Pointer {
+		if _, ok := rv.Elem().Interface().(proto.Message); ok {
+			if rv.Elem().IsNil() {
+				rv.Elem().Set(reflect.New(rv.Elem().Type().Elem()))
+			}
+			return proto.Unmarshal(data, rv.Elem().Interface().(proto.Message))
+		}
+	}
+
+	return fmt.Errorf("%T is not a protobuf payload", v)
+}
+
+func toProto(payload any) (proto.Message, error) {
+	switch p := payload.(type) {
+	case proto.Message:
+		return p, nil
+	case protoEncoder:
+		return p.ToProto(), nil
+	}
+
+	return nil, fmt.Errorf("%T is not a protobuf payload", payload)
+}
@@ -0,0 +1,164 @@
+package messaging
+
+import (
+	"errors"
+	"math/rand/v2"
+	"ride-sharing/shared/contracts"
+	pb "ride-sharing/shared/proto/trip"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// San Francisco bounding box, same area as the driver service predefined routes
+const (
+	minLat, maxLat = 37.70, 37.81
+	minLon, maxLon = -122.51, -122.38
+)
+
+var codecs = []Codec{JSONCodec, ProtobufCodec, ProtoJSONCodec}
+
+func TestCodecRoundTrip(t *testing.T) {
+	message, payload := tripCreatedMessage(rand.New(rand.NewPCG(1, 1)), 50)
+
+	for _, codec := range codecs {
+		t.Run(codec.ContentType(), func(t *testing.T) {
+			body, err := codec.Encode(message, payload)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+
+			envelope, err := codec.Decode(body)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if envelope.EventID != message.EventID || envelope.Type != message.Type ||
+				envelope.Version != message.Version || !envelope.Timestamp.Equal(message.Timestamp) ||
+				envelope.OwnerID != message.OwnerID || envelope.CorrelationID != message.CorrelationID {
+				t.Errorf("Decode() = %+v, want the envelope of %+v", envelope, message)
+			}
+
+			var got TripEventData
+			if err := codec.DecodePayload(envelope.Data, &got); err != nil {
+				t.Fatalf("DecodePayload() error = %v", err)
+			}
+			if !proto.Equal(got.Trip, payload.Trip) {
+				t.Errorf("DecodePayload() = %v, want %v", got.Trip, payload.Trip)
+			}
+		})
+	}
+}
+
+func TestCodecFor(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        Codec
+	}{
+		{"", JSONCodec},
+		{"text/plain", JSONCodec},
+		{ContentTypeJSON, JSONCodec},
+		{ContentTypeProtobuf, ProtobufCodec},
+		{ContentTypeProtoJSON, ProtoJSONCodec},
+	}
+
+	for _, tt := range tests {
+		if got, err := CodecFor(tt.contentType); err != nil || got != tt.want {
+			t.Errorf("CodecFor(%q) = %v, %v, want %v", tt.contentType, got, err, tt.want)
+		}
+	}
+
+	if _, err := CodecFor("application/xml"); !errors.Is(err, ErrUnsupportedContentType) {
+		t.Errorf("CodecFor(application/xml) error = %v, want %v", err, ErrUnsupportedContentType)
+	}
+}
+
+// BenchmarkCodecEncode and BenchmarkCodecDecode compare the codecs on a trip
+// event carrying its route geometry, the size of the body is reported as B/msg.
+func BenchmarkCodecEncode(b *testing.B) {
+	message, payload := tripCreatedMessage(rand.New(rand.NewPCG(1, 1)), 500)
+
+	for _, codec := range codecs {
+		b.Run(codec.ContentType(), func(b *testing.B) {
+			body, err := codec.Encode(message, payload)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+
+			for range b.N {
+				codec.Encode(message, payload)
+			}
+			b.ReportMetric(float64(len(body)), "B/msg")
+		})
+	}
+}
+
+func BenchmarkCodecDecode(b *testing.B) {
+	message, payload := tripCreatedMessage(rand.New(rand.NewPCG(1, 1)), 500)
+
+	for _, codec := range codecs {
+		b.Run(codec.ContentType(), func(b *testing.B) {
+			body, err := codec.Encode(message, payload)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+
+			// read the envelope and its payload, as consumers do
+			for range b.N {
+				envelope, err := codec.Decode(body)
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				var got TripEventData
+				if err := codec.DecodePayload(envelope.Data, &got); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(len(body)), "B/msg")
+		})
+	}
+}
+
+// tripCreatedMessage returns a trip created event with a route of the given
+// number of random points.
+func tripCreatedMessage(rng *rand.Rand, points int) (contracts.AmqpMessage, TripEventData) {
+	coordinates := make([]*pb.Coordinate, points)
+	for i := range coordinates {
+		coordinates[i] = &pb.Coordinate{
+			Latitude:  minLat + rng.Float64()*(maxLat-minLat),
+			Longitude: minLon + rng.Float64()*(maxLon-minLon),
+		}
+	}
+
+	payload := TripEventData{Trip: &pb.Trip{
+		Id:     "trip-1",
+		UserID: "rider-1",
+		Status: "pending",
+		SelectedRideFare: &pb.RideFare{
+			Id:                "fare-1",
+			UserID:            "rider-1",
+			PackageSlug:       "sedan",
+			TotalPriceInCents: 2350,
+		},
+		Route: &pb.Route{
+			Geometry: []*pb.Geometry{{Coordinates: coordinates}},
+			Distance: 5230.4,
+			Duration: 812.7,
+		},
+	}}
+
+	message := contracts.AmqpMessage{
+		EventID:       "event-1",
+		Type:          contracts.TripEventCreated,
+		Version:       contracts.EventSchemaVersion(contracts.TripEventCreated),
+		Timestamp:     time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		CorrelationID: "correlation-1",
+		Producer:      "trip-service",
+		OwnerID:       payload.Trip.UserID,
+	}
+
+	return message, payload
+}
@@ -0,0 +1,155 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+var (
+	// ErrUnroutable is a mandatory message no queue was bound to receive.
+	ErrUnroutable = errors.New("message routed to no queue")
+	// ErrNacked is a message the broker failed to take responsibility for.
+	ErrNacked = errors.New("message rejected by the broker")
+	// ErrConfirmTimeout is a message the broker did not confirm in time, it
+	// may or may not have been routed.
+	ErrConfirmTimeout = errors.New("publish not confirmed in time")
+)
+
+// confirmedChannel publishes mandatory messages on a channel in confirm mode
+// and reports the outcome of each of them.
+//
+// The broker sends basic.return before the basic.ack of an unroutable message.
+// Both are received by a single goroutine through unbuffered channels, the
+// client library dispatching them in order, so a return is always recorded
+// before the ack of its message is handled.
+type confirmedChannel struct {
+	ch *amqp.Channel
+
+	// keeps the next sequence number and the publish together
+	publishMu sync.Mutex
+
+	mu       sync.Mutex
+	pending  map[uint64]pendingPublish
+	returned map[string]amqp.Return
+	closed   bool
+}
+
+type pendingPublish struct {
+	messageID string
+	result    chan error
+}
+
+func newConfirmedChannel(ch *amqp.Channel) (*confirmedChannel, error) {
+	if err := ch.Confirm(false); err != nil {
+		return nil, fmt.Errorf("failed to put the channel in confirm mode: %v", err)
+	}
+
+	c := &confirmedChannel{
+		ch:       ch,
+		pending:  make(map[uint64]pendingPublish),
+		returned: make(map[string]amqp.Return),
+	}
+
+	returns := ch.NotifyReturn(make(chan amqp.Return))
+	confirms := ch.NotifyPublish(make(chan amqp.Confirmation))
+	go c.route(returns, confirms)
+
+	return c, nil
+}
+
+// publish sends msg as mandatory, msg.MessageId must be set. The returned
+// channel receives the outcome once the broker confirms the message, or
+// amqp.ErrClosed if the channel closes first.
+func (c *confirmedChannel) publish(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) (<-chan error, error) {
+	c.publishMu.Lock()
+	defer c.publishMu.Unlock()
+
+	result := make(chan error, 1)
+	tag := c.ch.GetNextPublishSeqNo()
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, amqp.ErrClosed
+	}
+	c.pending[tag] = pendingPublish{messageID: msg.MessageId, result: result}
+	c.mu.Unlock()
+
+	err := c.ch.PublishWithContext(ctx,
+		exchange,   // exchange
+		routingKey, // routing key
+		true,       // mandatory
+		false,      // immediate
+		msg,
+	)
+	if err != nil {
+		c.mu.Lock()
+		delete(c.pending, tag)
+		c.mu.Unlock()
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (c *confirmedChannel) route(returns <-chan amqp.Return, confirms <-chan amqp.Confirmation) {
+	// the library closes both when the channel closes, keep reading the other
+	// one so that it never blocks on a send
+	for returns != nil || confirms != nil {
+		select {
+		case ret, ok := <-returns:
+			if !ok {
+				returns = nil
+				continue
+			}
+
+			c.mu.Lock()
+			c.returned[ret.MessageId] = ret
+			c.mu.Unlock()
+
+		case confirmation, ok := <-confirms:
+			if !ok {
+				confirms = nil
+				continue
+			}
+
+			c.confirm(confirmation)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	for tag, p := range c.pending {
+		p.result <- amqp.ErrClosed
+		delete(c.pending, tag)
+	}
+}
+
+func (c *confirmedChannel) confirm(confirmation amqp.Confirmation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, ok := c.pending[confirmation.DeliveryTag]
+	if !ok {
+		return
+	}
+	delete(c.pending, confirmation.DeliveryTag)
+
+	ret, returned := c.returned[p.messageID]
+	delete(c.returned, p.messageID)
+
+	switch {
+	case !confirmation.Ack:
+		p.result <- ErrNacked
+	case returned:
+		p.result <- fmt.Errorf("%w: %d %s", ErrUnroutable, ret.ReplyCode, ret.ReplyText)
+	default:
+		p.result <- nil
+	}
+}
@@ -0,0 +1,216 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type consumer struct {
+	queue   string
+	handler MessageHandler
+	// nil for queues without retry queues, like the broadcast queues
+	retry       *RetryPolicy
+	concurrency int
+	prefetch    int
+	// tag of the subscription on the current channel
+	tag string
+	// nil unless the consumer drops duplicates
+	dedup *Deduplicator
+}
+
+// ConsumerOption tunes a consumer, by default it handles one message at a time.
+type ConsumerOption func(*consumer)
+
+// WithConcurrency handles up to n messages at the same time.
+func WithConcurrency(n int) ConsumerOption {
+	return func(c *consumer) {
+		c.concurrency = max(1, n)
+	}
+}
+
+// WithPrefetch lets the broker send n unacked messages ahead, it defaults to
+// the concurrency: a smaller value leaves workers idle.
+func WithPrefetch(n int) ConsumerOption {
+	return func(c *consumer) {
+		c.prefetch = max(1, n)
+	}
+}
+
+func newConsumer(queue string, handler MessageHandler, retry *RetryPolicy, opts []ConsumerOption) *consumer {
+	c := &consumer{
+		queue:       queue,
+		handler:     handler,
+		retry:       retry,
+		concurrency: 1,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.prefetch == 0 {
+		c.prefetch = c.concurrency
+	}
+
+	return c
+}
+
+// ConsumeMessages registers handler for the messages of queueName. The consumer
+// is resumed after every reconnection. Messages handler fails on are retried
+// later, see retryOrPark. The context of handler is cancelled when Shutdown
+// gives up waiting for it.
+func (r *RabbitMQ) ConsumeMessages(queueName string, handler MessageHandler, opts ...ConsumerOption) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.draining {
+		return fmt.Errorf("failed to consume %s: rabbitmq client shutting down", queueName)
+	}
+
+	c := newConsumer(queueName, handler, r.topology.retryPolicy(queueName), opts)
+
+	// while reconnecting the consumer starts with the new channel
+	if r.ch != nil {
+		if err := r.consume(r.ch, c); err != nil {
+			return err
+		}
+	}
+
+	r.consumers = append(r.consumers, c)
+	return nil
+}
+
+// consume subscribes c on ch and starts its workers, r.mu must be held.
+func (r *RabbitMQ) consume(ch *amqp.Channel, c *consumer) error {
+	// applies to the consumers started next on the channel, not the channel
+	if err := ch.Qos(
+		c.prefetch, // prefetch count
+		0,          // prefetch size
+		false,      // global
+	); err != nil {
+		return fmt.Errorf("failed to set Qos: %v", err)
+	}
+
+	c.tag = c.queue + "." + uuid.NewString()
+
+	msgs, err := ch.Consume(
+		c.queue, // queue
+		c.tag,   // consumer
+		false,   // auto-ack
+		false,   // exclusive
+		false,   // no-local
+		false,   // no-wait
+		nil,     // args
+	)
+	if err != nil {
+		return err
+	}
+
+	var workers sync.WaitGroup
+	for range c.concurrency {
+		workers.Add(1)
+		r.workers.Add(1)
+
+		go func() {
+			defer r.workers.Done()
+			defer workers.Done()
+
+			for msg := range msgs {
+				r.handle(c, msg)
+			}
+		}()
+	}
+
+	go func() {
+		workers.Wait()
+		// cancelled by Shutdown, or the channel closed: unacked messages go
+		// back to the queue and the consumer is resumed once reconnected
+		log.Printf("stopped consuming %s", c.queue)
+	}()
+
+	return nil
+}
+
+func (r *RabbitMQ) handle(c *consumer, msg amqp.Delivery) {
+	log.Printf("received a message %v", msg)
+
+	// handlers tell the message type by the routing key
+	msg.RoutingKey = originalRoutingKey(msg)
+
+	if err := c.dispatch(r.handlerCtx, msg); err != nil {
+		log.Printf("failed to handle the message: %v", err)
+		// scheduling the retry must outlive a cancelled handler
+		r.retryOrPark(context.Background(), c.queue, msg, c.retryPolicy(err))
+		return
+	}
+
+	if err := msg.Ack(false); err != nil {
+		log.Printf("ERROR: failed to ack message %v", err)
+	}
+}
+
+// retryPolicy is the policy the failure err of the handler is retried with,
+// nil when the message cannot be retried.
+func (c *consumer) retryPolicy(err error) *RetryPolicy {
+	if undecodable(err) {
+		return nil
+	}
+	return c.retry
+}
+
+// dispatch hands msg to the handler of c, through its deduplicator if any,
+// within a consumer span continuing the trace of the publisher.
+func (c *consumer) dispatch(ctx context.Context, msg amqp.Delivery) (err error) {
+	ctx, span := startConsumeSpan(ctx, c.queue, msg)
+	defer func() { endSpan(span, err) }()
+
+	if c.dedup != nil {
+		return c.dedup.handle(ctx, c.queue, msg, c.handler)
+	}
+	return c.handler(ctx, msg)
+}
+
+// Shutdown stops every consumer, waits for the messages already received to
+// be handled and closes the connection. When ctx is done first, the context of
+// the running handlers is cancelled and the connection closed anyway: the
+// messages they hold are redelivered.
+func (r *RabbitMQ) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	r.draining = true
+	if r.ch != nil {
+		for _, c := range r.consumers {
+			// the workers still get the messages prefetched before the cancel
+			if err := r.ch.Cancel(c.tag, false); err != nil {
+				log.Printf("failed to stop consuming %s: %v", c.queue, err)
+			}
+		}
+	}
+	r.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		r.workers.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+		log.Println("rabbitmq consumers drained")
+	case <-ctx.Done():
+		r.cancelHandlers()
+		err = fmt.Errorf("failed to drain the rabbitmq consumers: %v", ctx.Err())
+	}
+
+	r.Close()
+	return err
+}
+
+// undecodable tells the errors of messages no retry can make readable.
+func undecodable(err error) bool {
+	return errors.Is(err, ErrUnsupportedVersion) || errors.Is(err, ErrUnsupportedContentType)
+}
@@ -0,0 +1,186 @@
+package messaging
+
+import (
+	"container/list"
+	"context"
+	"log"
+	"ride-sharing/shared/metrics"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// how long a handler holds the claim of a message, a duplicate received after
+// it expired is handled again in case the handler crashed
+const dedupClaimLease = time.Minute
+
+var duplicatesDropped = metrics.NewCounter(
+	"messaging_duplicates_dropped_total",
+	"Redelivered messages dropped as handled already, per queue.",
+	"queue",
+)
+
+// DedupStore remembers the messages handled by the consumers, keyed by queue
+// and message ID. Shared stores let every replica drop the duplicates of a
+// message handled by another, and keep them across restarts.
+type DedupStore interface {
+	// Claim marks key as being handled for lease. It returns false when key
+	// was handled already, or is claimed by a handler whose lease is running.
+	Claim(ctx context.Context, key string, lease time.Duration) (bool, error)
+	// Complete marks key as handled for ttl.
+	Complete(ctx context.Context, key string, ttl time.Duration) error
+	// Release drops the claim of a handler that failed, so that the retry of
+	// the message is handled.
+	Release(ctx context.Context, key string) error
+}
+
+// Deduplicator drops the messages its consumers handled already, making
+// redeliveries after a crash or a lost ack harmless. Messages without a
+// MessageId, published before the envelope, are always handled.
+type Deduplicator struct {
+	store DedupStore
+	ttl   time.Duration
+}
+
+// NewDeduplicator remembers the handled messages in store for ttl, which
+// should outlast the retries and redeliveries of a message.
+func NewDeduplicator(store DedupStore, ttl time.Duration) *Deduplicator {
+	return &Deduplicator{
+		store: store,
+		ttl:   ttl,
+	}
+}
+
+// WithDeduplication skips the messages of the queue d has seen handled.
+func WithDeduplication(d *Deduplicator) ConsumerOption {
+	return func(c *consumer) {
+		c.dedup = d
+	}
+}
+
+// handle runs handler on msg unless it is a duplicate.
+func (d *Deduplicator) handle(ctx context.Context, queue string, msg amqp.Delivery, handler MessageHandler) error {
+	if msg.MessageId == "" {
+		return handler(ctx, msg)
+	}
+
+	key := queue + "/" + msg.MessageId
+	claimed, err := d.store.Claim(ctx, key, dedupClaimLease)
+	if err != nil {
+		// handling a message twice is better than not at all
+		log.Printf("failed to check message %s for duplicates: %v", key, err)
+		return handler(ctx, msg)
+	}
+	if !claimed {
+		count := duplicatesDropped.Inc(queue)
+		log.Printf("dropping duplicate message %s of %s (%d duplicates)", msg.MessageId, queue, count)
+		return nil
+	}
+
+	if err := handler(ctx, msg); err != nil {
+		// the handler context may be cancelled, the retry must still be handled
+		if err := d.store.Release(context.Background(), key); err != nil {
+			log.Printf("failed to release message %s: %v", key, err)
+		}
+		return err
+	}
+
+	if err := d.store.Complete(context.Background(), key, d.ttl); err != nil {
+		log.Printf("failed to mark message %s as handled: %v", key, err)
+	}
+
+	return nil
+}
+
+type dedupEntry struct {
+	key     string
+	expires time.Time
+	handled bool
+}
+
+// MemoryDedupStore keeps the keys of a single replica, up to a maximum: when
+// it is full the oldest key is forgotten before its TTL, letting a late
+// duplicate of its message through.
+type MemoryDedupStore struct {
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// entries by claim time, oldest first
+	order *list.List
+}
+
+func NewMemoryDedupStore(maxEntries int) *MemoryDedupStore {
+	return &MemoryDedupStore{
+		maxEntries: max(1, maxEntries),
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+func (s *MemoryDedupStore) Claim(ctx context.Context, key string, lease time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if elem, ok := s.entries[key]; ok {
+		if now.Before(elem.Value.(*dedupEntry).expires) {
+			return false, nil
+		}
+		s.remove(elem)
+	}
+
+	s.sweep(now)
+	for s.order.Len() >= s.maxEntries {
+		s.remove(s.order.Front())
+	}
+
+	s.entries[key] = s.order.PushBack(&dedupEntry{key: key, expires: now.Add(lease)})
+	return true, nil
+}
+
+func (s *MemoryDedupStore) Complete(ctx context.Context, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.entries[key]
+	if !ok {
+		// evicted while being handled
+		elem = s.order.PushBack(&dedupEntry{key: key})
+		s.entries[key] = elem
+	}
+
+	entry := elem.Value.(*dedupEntry)
+	entry.expires = time.Now().Add(ttl)
+	entry.handled = true
+
+	return nil
+}
+
+func (s *MemoryDedupStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.entries[key]; ok && !elem.Value.(*dedupEntry).handled {
+		s.remove(elem)
+	}
+
+	return nil
+}
+
+// sweep forgets the expired keys at the front of the list, the claim order is
+// close enough to the expiry order for the entries of a same TTL.
+func (s *MemoryDedupStore) sweep(now time.Time) {
+	for elem := s.order.Front(); elem != nil; elem = s.order.Front() {
+		if now.Before(elem.Value.(*dedupEntry).expires) {
+			return
+		}
+		s.remove(elem)
+	}
+}
+
+func (s *MemoryDedupStore) remove(elem *list.Element) {
+	s.order.Remove(elem)
+	delete(s.entries, elem.Value.(*dedupEntry).key)
+}
@@ -0,0 +1,130 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"ride-sharing/shared/contracts"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestMemoryDedupStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDedupStore(10)
+
+	if claimed, _ := store.Claim(ctx, "q/1", time.Minute); !claimed {
+		t.Fatal("Claim() of a new key = false")
+	}
+	// another replica handling the same message
+	if claimed, _ := store.Claim(ctx, "q/1", time.Minute); claimed {
+		t.Fatal("Claim() of a claimed key = true")
+	}
+
+	// a failed handler lets the retry through
+	store.Release(ctx, "q/1")
+	if claimed, _ := store.Claim(ctx, "q/1", time.Minute); !claimed {
+		t.Fatal("Claim() of a released key = false")
+	}
+
+	store.Complete(ctx, "q/1", time.Hour)
+	store.Release(ctx, "q/1")
+	if claimed, _ := store.Claim(ctx, "q/1", time.Minute); claimed {
+		t.Fatal("Claim() of a handled key = true, releasing it after completion must not forget it")
+	}
+}
+
+func TestMemoryDedupStoreExpiry(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDedupStore(10)
+
+	// the handler holding the claim crashed
+	store.Claim(ctx, "q/claimed", time.Millisecond)
+	store.Claim(ctx, "q/handled", time.Minute)
+	store.Complete(ctx, "q/handled", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	for _, key := range []string{"q/claimed", "q/handled"} {
+		if claimed, _ := store.Claim(ctx, key, time.Minute); !claimed {
+			t.Errorf("Claim(%s) after expiry = false", key)
+		}
+	}
+}
+
+func TestMemoryDedupStoreEviction(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDedupStore(2)
+
+	for _, key := range []string{"q/1", "q/2", "q/3"} {
+		store.Claim(ctx, key, time.Minute)
+		store.Complete(ctx, key, time.Hour)
+	}
+
+	// the oldest key is forgotten to make room
+	if claimed, _ := store.Claim(ctx, "q/1", time.Minute); !claimed {
+		t.Error("Claim() of the evicted key = false")
+	}
+	if claimed, _ := store.Claim(ctx, "q/3", time.Minute); claimed {
+		t.Error("Claim() of the newest key = true")
+	}
+	if len(store.entries) > 2 {
+		t.Errorf("store holds %d keys, want at most 2", len(store.entries))
+	}
+}
+
+func TestDeduplicatorDropsRedeliveries(t *testing.T) {
+	broker := NewMemoryBroker("test", WithTopology(testTopology))
+	defer broker.Close()
+	dedup := NewDeduplicator(NewMemoryDedupStore(100), time.Hour)
+	// counted since the process started
+	dropped := duplicatesDropped.Values()["trips"]
+
+	handled := make(chan amqp.Delivery, 10)
+	failures := 1
+	err := broker.ConsumeMessages("trips", func(ctx context.Context, msg amqp.Delivery) error {
+		handled <- msg
+		if msg.MessageId == "failing" && failures > 0 {
+			failures--
+			return errors.New("handler failed")
+		}
+		return nil
+	}, WithDeduplication(dedup))
+	if err != nil {
+		t.Fatalf("ConsumeMessages() error = %v", err)
+	}
+
+	// the same message published twice, e.g. after a lost publisher confirm,
+	// then one whose first attempt fails
+	for _, id := range []string{"twice", "twice", "failing", ""} {
+		if err := broker.route(context.Background(), TripExchange, contracts.TripEventCreated, amqp.Delivery{MessageId: id, Body: []byte("{}")}); err != nil {
+			t.Fatalf("route() error = %v", err)
+		}
+	}
+
+	var got []string
+	for range 4 {
+		got = append(got, receive(t, handled).MessageId)
+	}
+	select {
+	case msg := <-handled:
+		t.Fatalf("handled %v after %v", msg.MessageId, got)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// the retry of the failed message and the message without ID are handled
+	want := map[string]int{"twice": 1, "failing": 2, "": 1}
+	counts := make(map[string]int)
+	for _, id := range got {
+		counts[id]++
+	}
+	for id, n := range want {
+		if counts[id] != n {
+			t.Errorf("message %q handled %d times, want %d", id, counts[id], n)
+		}
+	}
+
+	if duplicates := duplicatesDropped.Values(); duplicates["trips"]-dropped != 1 {
+		t.Errorf("duplicates dropped = %v, want 1 more for trips than %d", duplicates, dropped)
+	}
+}
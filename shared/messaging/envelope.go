@@ -0,0 +1,145 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ride-sharing/shared/contracts"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ErrUnsupportedVersion is an event whose major schema version the consumer
+// does not know. Retrying cannot help, such messages are parked right away.
+var ErrUnsupportedVersion = errors.New("unsupported event version")
+
+// Event is a payload received with its envelope.
+type Event[T any] struct {
+	contracts.AmqpMessage
+	Payload T
+}
+
+type EventHandler[T any] func(ctx context.Context, event Event[T]) error
+
+type correlationIDKey struct{}
+
+// WithCorrelationID makes the events published with ctx part of the chain of
+// correlationID. Subscribe handlers receive the ID of the event they handle.
+func WithCorrelationID(ctx context.Context, correlationID string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, correlationID)
+}
+
+func CorrelationID(ctx context.Context) string {
+	correlationID, _ := ctx.Value(correlationIDKey{}).(string)
+	return correlationID
+}
+
+// Publish sends payload as the data of an event of type routingKey.
+// The payload is encoded by the codec of the broker, see WithCodec.
+func Publish[T any](ctx context.Context, b Broker, routingKey, ownerID string, payload T) error {
+	return b.PublishEvent(ctx, routingKey, contracts.AmqpMessage{OwnerID: ownerID}, payload)
+}
+
+// Subscribe consumes queue, handing the payload of each event to handler.
+// Events without data get the zero payload.
+func Subscribe[T any](b Broker, queue string, handler EventHandler[T], opts ...ConsumerOption) error {
+	return b.ConsumeMessages(queue, func(ctx context.Context, msg amqp.Delivery) error {
+		envelope, err := DecodeEnvelope(msg)
+		if err != nil {
+			return err
+		}
+
+		event := Event[T]{AmqpMessage: envelope}
+		if len(envelope.Data) > 0 {
+			if err := DecodePayload(msg.ContentType, envelope.Data, &event.Payload); err != nil {
+				return fmt.Errorf("failed to unmarshal %s payload: %v", envelope.Type, err)
+			}
+		}
+
+		return handler(WithCorrelationID(ctx, envelope.CorrelationID), event)
+	}, opts...)
+}
+
+// DecodeEnvelope reads the envelope of a delivery with the codec of its content
+// type and checks that its consumer can read the version of the payload.
+func DecodeEnvelope(msg amqp.Delivery) (contracts.AmqpMessage, error) {
+	codec, err := CodecFor(msg.ContentType)
+	if err != nil {
+		return contracts.AmqpMessage{}, err
+	}
+
+	envelope, err := codec.Decode(msg.Body)
+	if err != nil {
+		return envelope, fmt.Errorf("failed to unmarshal message: %v", err)
+	}
+
+	// messages published before the envelope
+	if envelope.Type == "" {
+		envelope.Type = msg.RoutingKey
+	}
+	if envelope.Version == "" {
+		envelope.Version = "1.0"
+	}
+	if envelope.CorrelationID == "" {
+		envelope.CorrelationID = envelope.EventID
+	}
+
+	if err := checkVersion(envelope.Type, envelope.Version); err != nil {
+		return envelope, err
+	}
+
+	return envelope, nil
+}
+
+// checkVersion accepts the versions sharing the major of the version the
+// consumer was built with, newer minors only add to the schema.
+func checkVersion(eventType, version string) error {
+	major, err := majorVersion(version)
+	if err != nil {
+		return fmt.Errorf("%w: %s version %q", ErrUnsupportedVersion, eventType, version)
+	}
+
+	known, _ := majorVersion(contracts.EventSchemaVersion(eventType))
+	if major != known {
+		return fmt.Errorf("%w: %s version %s, expected %d.x", ErrUnsupportedVersion, eventType, version, known)
+	}
+
+	return nil
+}
+
+func majorVersion(version string) (int, error) {
+	major, _, _ := strings.Cut(version, ".")
+	return strconv.Atoi(major)
+}
+
+// newEnvelope fills the fields of message the publisher left empty.
+func newEnvelope(ctx context.Context, producer, routingKey string, message contracts.AmqpMessage) contracts.AmqpMessage {
+	if message.EventID == "" {
+		message.EventID = uuid.NewString()
+	}
+	if message.Type == "" {
+		message.Type = routingKey
+	}
+	if message.Version == "" {
+		message.Version = contracts.EventSchemaVersion(message.Type)
+	}
+	if message.Timestamp.IsZero() {
+		message.Timestamp = time.Now().UTC()
+	}
+	if message.CorrelationID == "" {
+		message.CorrelationID = CorrelationID(ctx)
+	}
+	if message.CorrelationID == "" {
+		// the event starts a chain
+		message.CorrelationID = message.EventID
+	}
+	if message.Producer == "" {
+		message.Producer = producer
+	}
+
+	return message
+}
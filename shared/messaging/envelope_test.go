@@ -0,0 +1,61 @@
+package messaging
+
+import (
+	"errors"
+	"ride-sharing/shared/contracts"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestCheckVersion(t *testing.T) {
+	// consumers of this build know the 1.x trip created events
+	tests := []struct {
+		version string
+		wantErr error
+	}{
+		{"1.0", nil},
+		{"1", nil},
+		{"1.3", nil},
+		{"1.10.2", nil},
+		{"2.0", ErrUnsupportedVersion},
+		{"0.9", ErrUnsupportedVersion},
+		{"v1.0", ErrUnsupportedVersion},
+		{"latest", ErrUnsupportedVersion},
+	}
+
+	for _, tt := range tests {
+		if err := checkVersion(contracts.TripEventCreated, tt.version); !errors.Is(err, tt.wantErr) {
+			t.Errorf("checkVersion(%q) error = %v, want %v", tt.version, err, tt.wantErr)
+		}
+	}
+}
+
+func TestDecodeEnvelopeVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		wantErr error
+	}{
+		{"published before the envelope", "", nil},
+		{"newer minor", "1.1", nil},
+		{"unknown major", "2.0", ErrUnsupportedVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := JSONCodec.Encode(contracts.AmqpMessage{Type: contracts.TripEventCreated, Version: tt.version}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = DecodeEnvelope(amqp.Delivery{ContentType: ContentTypeJSON, RoutingKey: contracts.TripEventCreated, Body: body})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("DecodeEnvelope() error = %v, want %v", err, tt.wantErr)
+			}
+			if undecodable(err) != (tt.wantErr != nil) {
+				t.Errorf("undecodable(%v) = %v, the message must be parked without retries only when its version is unsupported", err, undecodable(err))
+			}
+		})
+	}
+}
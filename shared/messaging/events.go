@@ -1,11 +1,97 @@
 package messaging
 
-import pb "ride-sharing/shared/proto/trip"
+import (
+	"fmt"
+	pbd "ride-sharing/shared/proto/driver"
+	pb "ride-sharing/shared/proto/trip"
+	"ride-sharing/shared/types"
+
+	"google.golang.org/protobuf/proto"
+)
 
 const (
-	FindAvailableDriversQueue = "find_available_drivers"
+	FindAvailableDriversQueue       = "find_available_drivers"
+	DriverOfferOutcomesQueue        = "driver_offer_outcomes"
+	DriverLocationQueue             = "driver_location"
+	NotifyNewTripQueue              = "notify_new_trip"
+	NotifyDriverAssignmentQueue     = "notify_driver_assignment"
+	NotifyDriverNoDriversFoundQueue = "notify_driver_no_drivers_found"
+	DriverCmdTripRequestQueue       = "driver_cmd_trip_request"
+	NotifyPaymentStatusQueue        = "notify_payment_status"
 )
 
 type TripEventData struct {
 	Trip *pb.Trip `json:"trip"`
 }
+
+// DriverTripResponseData is the payload of a driver accepting, declining or
+// cancelling a trip.
+type DriverTripResponseData struct {
+	Driver  *pbd.Driver `json:"driver"`
+	TripID  string      `json:"tripID"`
+	RiderID string      `json:"riderID"`
+}
+
+// DriverLocationData is the payload of a driver reporting its location.
+type DriverLocationData struct {
+	Location types.Coordinate `json:"location"`
+	Geohash  string           `json:"geohash"`
+}
+
+func (d TripEventData) ToProto() proto.Message {
+	if d.Trip == nil {
+		return &pb.Trip{}
+	}
+	return d.Trip
+}
+
+func (d *TripEventData) FromProto(msg proto.Message) error {
+	trip, ok := msg.(*pb.Trip)
+	if !ok {
+		return fmt.Errorf("unexpected trip event payload %T", msg)
+	}
+	d.Trip = trip
+	return nil
+}
+
+func (d DriverTripResponseData) ToProto() proto.Message {
+	return &pbd.DriverTripResponse{
+		Driver:  d.Driver,
+		TripID:  d.TripID,
+		RiderID: d.RiderID,
+	}
+}
+
+func (d *DriverTripResponseData) FromProto(msg proto.Message) error {
+	response, ok := msg.(*pbd.DriverTripResponse)
+	if !ok {
+		return fmt.Errorf("unexpected trip response payload %T", msg)
+	}
+	d.Driver = response.Driver
+	d.TripID = response.TripID
+	d.RiderID = response.RiderID
+	return nil
+}
+
+func (d DriverLocationData) ToProto() proto.Message {
+	return &pbd.DriverLocationUpdate{
+		Location: &pbd.Location{
+			Latitude:  d.Location.Latitude,
+			Longitude: d.Location.Longitude,
+		},
+		Geohash: d.Geohash,
+	}
+}
+
+func (d *DriverLocationData) FromProto(msg proto.Message) error {
+	update, ok := msg.(*pbd.DriverLocationUpdate)
+	if !ok {
+		return fmt.Errorf("unexpected location payload %T", msg)
+	}
+	d.Location = types.Coordinate{
+		Latitude:  update.Location.GetLatitude(),
+		Longitude: update.Location.GetLongitude(),
+	}
+	d.Geohash = update.Geohash
+	return nil
+}
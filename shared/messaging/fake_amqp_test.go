@@ -0,0 +1,619 @@
+package messaging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAMQPServer speaks enough AMQP 0-9-1 for RabbitMQ to run against it: the
+// connection handshake, declarations, publishes in confirm mode routed by the
+// topic and fanout bindings, and consumers limited by their prefetch. Messages
+// unacked when a connection drops go back to their queue, like on a broker
+// restart.
+type fakeAMQPServer struct {
+	t        *testing.T
+	listener net.Listener
+
+	mu       sync.Mutex
+	conns    map[*fakeAMQPConn]bool
+	queues   map[string]*fakeQueue
+	bindings []fakeBinding
+	// kind of each exchange declared
+	exchanges map[string]string
+	// methods received, e.g. "queue.declare trips", in order
+	calls       []string
+	connections int
+	acked       int
+}
+
+type fakeBinding struct {
+	queue, exchange, pattern string
+}
+
+type fakeQueue struct {
+	name      string
+	messages  []*fakeMessage
+	consumers []*fakeConsumer
+}
+
+type fakeMessage struct {
+	exchange, routingKey string
+	// content header frame payload, sent back as is on delivery
+	header      []byte
+	body        []byte
+	redelivered bool
+}
+
+type fakeConsumer struct {
+	tag     string
+	queue   string
+	channel *fakeChannel
+}
+
+type fakeAMQPConn struct {
+	server *fakeAMQPServer
+	conn   net.Conn
+	writer *bufio.Writer
+
+	writeMu sync.Mutex
+	// guarded by the server mutex
+	channels map[uint16]*fakeChannel
+}
+
+type fakeChannel struct {
+	id       uint16
+	conn     *fakeAMQPConn
+	confirm  bool
+	prefetch int
+	// sequence number of the last publish, for the confirms
+	published uint64
+	// delivery tag of the last delivery
+	delivered uint64
+	unacked   map[uint64]*fakeUnacked
+	// publish waiting for its content
+	publishing *fakeMessage
+	mandatory  bool
+}
+
+type fakeUnacked struct {
+	queue   *fakeQueue
+	message *fakeMessage
+}
+
+func newFakeAMQPServer(t *testing.T) *fakeAMQPServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &fakeAMQPServer{
+		t:         t,
+		listener:  listener,
+		conns:     make(map[*fakeAMQPConn]bool),
+		queues:    make(map[string]*fakeQueue),
+		exchanges: make(map[string]string),
+	}
+	go s.accept()
+	t.Cleanup(func() {
+		listener.Close()
+		s.dropConnections()
+	})
+
+	return s
+}
+
+func (s *fakeAMQPServer) uri() string {
+	return "amqp://guest:guest@" + s.listener.Addr().String() + "/"
+}
+
+func (s *fakeAMQPServer) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		c := &fakeAMQPConn{server: s, conn: conn, writer: bufio.NewWriter(conn), channels: make(map[uint16]*fakeChannel)}
+		s.mu.Lock()
+		s.conns[c] = true
+		s.connections++
+		s.mu.Unlock()
+
+		go c.serve()
+	}
+}
+
+// dropConnections closes every client connection without a word, as a broker
+// going down does.
+func (s *fakeAMQPServer) dropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for c := range s.conns {
+		c.conn.Close()
+	}
+}
+
+// count returns how many times call was received.
+func (s *fakeAMQPServer) count(call string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, c := range s.calls {
+		if c == call {
+			n++
+		}
+	}
+	return n
+}
+
+// ready returns the number of messages of queue waiting for a consumer.
+func (s *fakeAMQPServer) ready(queue string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if q, ok := s.queues[queue]; ok {
+		return len(q.messages)
+	}
+	return 0
+}
+
+func (s *fakeAMQPServer) state() (connections, acked int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.connections, s.acked
+}
+
+// waitFor fails the test unless cond, evaluated with the server locked,
+// becomes true within a few seconds.
+func (s *fakeAMQPServer) waitFor(what string, cond func() bool) {
+	s.t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		s.mu.Lock()
+		ok := cond()
+		s.mu.Unlock()
+		if ok {
+			return
+		}
+		if time.Now().After(deadline) {
+			s.t.Fatalf("fake broker never saw %s", what)
+		}
+	}
+}
+
+func (c *fakeAMQPConn) serve() {
+	defer c.closed()
+
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(c.conn, header); err != nil || string(header) != "AMQP\x00\x00\x09\x01" {
+		return
+	}
+
+	// connection.start
+	c.send(0, 10, 10, func(w *fakeWriter) {
+		w.octet(0)
+		w.octet(9)
+		w.long(0) // empty server properties
+		w.longstr("PLAIN")
+		w.longstr("en_US")
+	})
+
+	reader := bufio.NewReader(c.conn)
+	for {
+		kind, channel, payload, err := readFrame(reader)
+		if err != nil {
+			return
+		}
+
+		c.server.mu.Lock()
+		err = c.handle(kind, channel, payload)
+		c.server.mu.Unlock()
+		if err != nil {
+			c.server.t.Errorf("fake broker: %v", err)
+			return
+		}
+	}
+}
+
+// closed forgets the connection, its consumers and requeues its unacked
+// messages.
+func (c *fakeAMQPConn) closed() {
+	c.conn.Close()
+
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+
+	delete(c.server.conns, c)
+	for _, ch := range c.channels {
+		c.server.closeChannel(ch)
+	}
+}
+
+func (s *fakeAMQPServer) closeChannel(ch *fakeChannel) {
+	delete(ch.conn.channels, ch.id)
+
+	for _, q := range s.queues {
+		q.consumers = slices.DeleteFunc(q.consumers, func(c *fakeConsumer) bool { return c.channel == ch })
+	}
+
+	tags := make([]uint64, 0, len(ch.unacked))
+	for tag := range ch.unacked {
+		tags = append(tags, tag)
+	}
+	slices.Sort(tags)
+	for _, tag := range slices.Backward(tags) {
+		u := ch.unacked[tag]
+		u.message.redelivered = true
+		u.queue.messages = append([]*fakeMessage{u.message}, u.queue.messages...)
+	}
+	ch.unacked = nil
+
+	s.dispatch()
+}
+
+// handle processes a frame, s.mu is held.
+func (c *fakeAMQPConn) handle(kind byte, channel uint16, payload []byte) error {
+	s := c.server
+	ch := c.channels[channel]
+
+	switch kind {
+	case 2: // content header
+		if ch == nil || ch.publishing == nil {
+			return fmt.Errorf("content header without publish on channel %d", channel)
+		}
+		ch.publishing.header = payload
+		if binary.BigEndian.Uint64(payload[4:12]) == 0 {
+			c.published(ch)
+		}
+		return nil
+	case 3: // content body
+		if ch == nil || ch.publishing == nil {
+			return fmt.Errorf("content body without publish on channel %d", channel)
+		}
+		ch.publishing.body = append(ch.publishing.body, payload...)
+		if uint64(len(ch.publishing.body)) >= binary.BigEndian.Uint64(ch.publishing.header[4:12]) {
+			c.published(ch)
+		}
+		return nil
+	case 8: // heartbeat
+		return nil
+	}
+
+	r := &fakeReader{b: payload}
+	class, method := r.short(), r.short()
+	if channel != 0 && ch == nil && !(class == 20 && method == 10) {
+		return fmt.Errorf("method %d.%d on closed channel %d", class, method, channel)
+	}
+
+	switch [2]uint16{class, method} {
+	case [2]uint16{10, 11}: // connection.start-ok
+		c.send(0, 10, 30, func(w *fakeWriter) {
+			w.short(2047)
+			w.long(131072)
+			w.short(0)
+		})
+	case [2]uint16{10, 31}: // connection.tune-ok
+	case [2]uint16{10, 40}: // connection.open
+		c.send(0, 10, 41, func(w *fakeWriter) { w.shortstr("") })
+	case [2]uint16{10, 50}: // connection.close
+		c.send(0, 10, 51, nil)
+		c.conn.Close()
+
+	case [2]uint16{20, 10}: // channel.open
+		c.channels[channel] = &fakeChannel{id: channel, conn: c, unacked: make(map[uint64]*fakeUnacked)}
+		c.send(channel, 20, 11, func(w *fakeWriter) { w.longstr("") })
+	case [2]uint16{20, 40}: // channel.close
+		s.closeChannel(ch)
+		c.send(channel, 20, 41, nil)
+	case [2]uint16{20, 41}: // channel.close-ok
+
+	case [2]uint16{40, 10}: // exchange.declare
+		r.short()
+		name := r.shortstr()
+		s.exchanges[name] = r.shortstr()
+		s.calls = append(s.calls, "exchange.declare "+name)
+		c.send(channel, 40, 11, nil)
+
+	case [2]uint16{50, 10}: // queue.declare
+		r.short()
+		name := r.shortstr()
+		s.calls = append(s.calls, "queue.declare "+name)
+		q := s.queue(name)
+		c.send(channel, 50, 11, func(w *fakeWriter) {
+			w.shortstr(name)
+			w.long(uint32(len(q.messages)))
+			w.long(uint32(len(q.consumers)))
+		})
+	case [2]uint16{50, 20}: // queue.bind
+		r.short()
+		b := fakeBinding{queue: r.shortstr(), exchange: r.shortstr(), pattern: r.shortstr()}
+		s.calls = append(s.calls, strings.Join([]string{"queue.bind", b.queue, b.exchange, b.pattern}, " "))
+		if !slices.Contains(s.bindings, b) {
+			s.bindings = append(s.bindings, b)
+		}
+		c.send(channel, 50, 21, nil)
+
+	case [2]uint16{60, 10}: // basic.qos
+		r.long()
+		ch.prefetch = int(r.short())
+		c.send(channel, 60, 11, nil)
+	case [2]uint16{60, 20}: // basic.consume
+		r.short()
+		queue, tag := r.shortstr(), r.shortstr()
+		s.calls = append(s.calls, "basic.consume "+queue)
+		q := s.queue(queue)
+		q.consumers = append(q.consumers, &fakeConsumer{tag: tag, queue: queue, channel: ch})
+		c.send(channel, 60, 21, func(w *fakeWriter) { w.shortstr(tag) })
+		s.dispatch()
+	case [2]uint16{60, 30}: // basic.cancel
+		tag := r.shortstr()
+		for _, q := range s.queues {
+			q.consumers = slices.DeleteFunc(q.consumers, func(c *fakeConsumer) bool {
+				if c.tag == tag {
+					s.calls = append(s.calls, "basic.cancel "+c.queue)
+					return true
+				}
+				return false
+			})
+		}
+		c.send(channel, 60, 31, func(w *fakeWriter) { w.shortstr(tag) })
+	case [2]uint16{60, 40}: // basic.publish
+		r.short()
+		ch.publishing = &fakeMessage{exchange: r.shortstr(), routingKey: r.shortstr()}
+		ch.mandatory = r.octet()&1 != 0
+	case [2]uint16{60, 80}: // basic.ack
+		c.settle(ch, r.longlong(), r.octet()&1 != 0, false)
+	case [2]uint16{60, 120}: // basic.nack
+		tag, bits := r.longlong(), r.octet()
+		c.settle(ch, tag, bits&1 != 0, bits&2 != 0)
+
+	case [2]uint16{85, 10}: // confirm.select
+		ch.confirm = true
+		c.send(channel, 85, 11, nil)
+
+	default:
+		return fmt.Errorf("unexpected method %d.%d", class, method)
+	}
+
+	return nil
+}
+
+// published routes the message whose content was received, then confirms it.
+func (c *fakeAMQPConn) published(ch *fakeChannel) {
+	s := c.server
+	msg := ch.publishing
+	ch.publishing = nil
+
+	var routed []string
+	if msg.exchange == "" {
+		if _, ok := s.queues[msg.routingKey]; ok {
+			routed = append(routed, msg.routingKey)
+		}
+	}
+	fanout := s.exchanges[msg.exchange] == "fanout"
+	for _, b := range s.bindings {
+		if b.exchange == msg.exchange && (fanout || topicMatches(b.pattern, msg.routingKey)) && !slices.Contains(routed, b.queue) {
+			routed = append(routed, b.queue)
+		}
+	}
+	for _, name := range routed {
+		q := s.queues[name]
+		q.messages = append(q.messages, &fakeMessage{exchange: msg.exchange, routingKey: msg.routingKey, header: msg.header, body: msg.body})
+	}
+
+	if len(routed) == 0 && ch.mandatory {
+		c.send(ch.id, 60, 50, func(w *fakeWriter) {
+			w.short(312)
+			w.shortstr("NO_ROUTE")
+			w.shortstr(msg.exchange)
+			w.shortstr(msg.routingKey)
+		}, msg.header, msg.body)
+	}
+
+	if ch.confirm {
+		ch.published++
+		c.send(ch.id, 60, 80, func(w *fakeWriter) {
+			w.longlong(ch.published)
+			w.octet(0)
+		})
+	}
+
+	s.dispatch()
+}
+
+// settle acks or nacks the deliveries up to tag, or tag alone.
+func (c *fakeAMQPConn) settle(ch *fakeChannel, tag uint64, multiple, requeue bool) {
+	s := c.server
+
+	for t, u := range ch.unacked {
+		if t != tag && !(multiple && t < tag) {
+			continue
+		}
+
+		delete(ch.unacked, t)
+		if requeue {
+			u.message.redelivered = true
+			u.queue.messages = append(u.queue.messages, u.message)
+		} else {
+			s.acked++
+		}
+	}
+
+	s.dispatch()
+}
+
+// dispatch delivers the ready messages to the consumers having room for them.
+func (s *fakeAMQPServer) dispatch() {
+	for _, q := range s.queues {
+		for len(q.messages) > 0 {
+			i := slices.IndexFunc(q.consumers, func(c *fakeConsumer) bool {
+				return c.channel.prefetch == 0 || len(c.channel.unacked) < c.channel.prefetch
+			})
+			if i < 0 {
+				break
+			}
+
+			consumer := q.consumers[i]
+			// round robin
+			q.consumers = append(slices.Delete(q.consumers, i, i+1), consumer)
+
+			msg := q.messages[0]
+			q.messages = q.messages[1:]
+
+			ch := consumer.channel
+			ch.delivered++
+			ch.unacked[ch.delivered] = &fakeUnacked{queue: q, message: msg}
+			ch.conn.send(ch.id, 60, 60, func(w *fakeWriter) {
+				w.shortstr(consumer.tag)
+				w.longlong(ch.delivered)
+				if msg.redelivered {
+					w.octet(1)
+				} else {
+					w.octet(0)
+				}
+				w.shortstr(msg.exchange)
+				w.shortstr(msg.routingKey)
+			}, msg.header, msg.body)
+		}
+	}
+}
+
+func (s *fakeAMQPServer) queue(name string) *fakeQueue {
+	q, ok := s.queues[name]
+	if !ok {
+		q = &fakeQueue{name: name}
+		s.queues[name] = q
+	}
+	return q
+}
+
+// send writes a method frame, followed by the content frames of a message
+// when header is set. Write errors are left to the read loop to notice.
+func (c *fakeAMQPConn) send(channel uint16, class, method uint16, args func(*fakeWriter), content ...[]byte) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	w := &fakeWriter{}
+	w.short(class)
+	w.short(method)
+	if args != nil {
+		args(w)
+	}
+	writeFrame(c.writer, 1, channel, w.b.Bytes())
+
+	if len(content) == 2 {
+		writeFrame(c.writer, 2, channel, content[0])
+		if len(content[1]) > 0 {
+			writeFrame(c.writer, 3, channel, content[1])
+		}
+	}
+
+	c.writer.Flush()
+}
+
+func readFrame(r io.Reader) (kind byte, channel uint16, payload []byte, err error) {
+	header := make([]byte, 7)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, 0, nil, err
+	}
+
+	payload = make([]byte, binary.BigEndian.Uint32(header[3:7])+1)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, 0, nil, err
+	}
+	if payload[len(payload)-1] != 0xCE {
+		return 0, 0, nil, fmt.Errorf("bad frame end")
+	}
+
+	return header[0], binary.BigEndian.Uint16(header[1:3]), payload[:len(payload)-1], nil
+}
+
+func writeFrame(w io.Writer, kind byte, channel uint16, payload []byte) {
+	header := make([]byte, 7)
+	header[0] = kind
+	binary.BigEndian.PutUint16(header[1:3], channel)
+	binary.BigEndian.PutUint32(header[3:7], uint32(len(payload)))
+
+	w.Write(header)
+	w.Write(payload)
+	w.Write([]byte{0xCE})
+}
+
+type fakeReader struct {
+	b []byte
+}
+
+func (r *fakeReader) octet() byte {
+	v := r.b[0]
+	r.b = r.b[1:]
+	return v
+}
+
+func (r *fakeReader) short() uint16 {
+	v := binary.BigEndian.Uint16(r.b)
+	r.b = r.b[2:]
+	return v
+}
+
+func (r *fakeReader) long() uint32 {
+	v := binary.BigEndian.Uint32(r.b)
+	r.b = r.b[4:]
+	return v
+}
+
+func (r *fakeReader) longlong() uint64 {
+	v := binary.BigEndian.Uint64(r.b)
+	r.b = r.b[8:]
+	return v
+}
+
+func (r *fakeReader) shortstr() string {
+	n := int(r.octet())
+	v := string(r.b[:n])
+	r.b = r.b[n:]
+	return v
+}
+
+type fakeWriter struct {
+	b bytes.Buffer
+}
+
+func (w *fakeWriter) octet(v byte) {
+	w.b.WriteByte(v)
+}
+
+func (w *fakeWriter) short(v uint16) {
+	w.b.Write(binary.BigEndian.AppendUint16(nil, v))
+}
+
+func (w *fakeWriter) long(v uint32) {
+	w.b.Write(binary.BigEndian.AppendUint32(nil, v))
+}
+
+func (w *fakeWriter) longlong(v uint64) {
+	w.b.Write(binary.BigEndian.AppendUint64(nil, v))
+}
+
+func (w *fakeWriter) shortstr(v string) {
+	w.octet(byte(len(v)))
+	w.b.WriteString(v)
+}
+
+func (w *fakeWriter) longstr(v string) {
+	w.long(uint32(len(v)))
+	w.b.WriteString(v)
+}
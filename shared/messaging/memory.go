@@ -0,0 +1,494 @@
+package messaging
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"ride-sharing/shared/contracts"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// MemoryBroker is a Broker keeping its queues in process, so that services
+// and whole flows of trip, driver and gateway events run without RabbitMQ. It
+// declares the queues of its topology and mirrors the semantics of RabbitMQ:
+//
+//   - TripExchange and PaymentExchange route like topic exchanges: a binding
+//     pattern matches the routing keys word by word, * matching one word and #
+//     zero or more
+//   - publishing a message no queue is bound to receive fails with ErrUnroutable
+//   - deliveries are acked or nacked through amqp.Delivery, nacked ones are
+//     requeued with Redelivered set or parked for good
+//   - a message whose handler fails is retried until it failed the attempts of
+//     the retry policy of its queue, right away instead of after the delays
+//     of the retry queues, then parked
+//
+// Prefetch has no effect, each worker of a consumer takes one message at a
+// time. Messages are lost when the broker is closed.
+type MemoryBroker struct {
+	producer string
+	codec    Codec
+
+	mu sync.Mutex
+	// signalled when messages are queued and when the consumers stop
+	cond     *sync.Cond
+	queues   map[string]*memoryQueue
+	bindings []memoryBinding
+	draining bool
+	closed   bool
+
+	// handlerCtx is cancelled when the in-flight messages failed to drain in time
+	handlerCtx     context.Context
+	cancelHandlers context.CancelFunc
+	workers        sync.WaitGroup
+}
+
+type memoryBinding struct {
+	exchange string
+	pattern  string
+	queue    string
+}
+
+// memoryQueue is a queue of a MemoryBroker, guarded by the broker mutex. It
+// is the acknowledger of the messages it delivers.
+type memoryQueue struct {
+	broker *MemoryBroker
+	name   string
+	// nil for queues without a parking queue, rejected messages are dropped
+	retry *RetryPolicy
+
+	ready   []amqp.Delivery
+	unacked map[uint64]amqp.Delivery
+	parked  []amqp.Delivery
+	nextTag uint64
+}
+
+// NewMemoryBroker returns a broker with the queues of its topology declared,
+// see WithTopology.
+func NewMemoryBroker(producer string, opts ...Option) *MemoryBroker {
+	o := newOptions(opts)
+	b := &MemoryBroker{
+		producer: producer,
+		codec:    o.codec,
+		queues:   make(map[string]*memoryQueue),
+	}
+	b.cond = sync.NewCond(&b.mu)
+	b.handlerCtx, b.cancelHandlers = context.WithCancel(context.Background())
+
+	for _, spec := range o.topology.Queues {
+		if !spec.PerReplica {
+			b.DeclareQueue(spec)
+		}
+	}
+
+	return b
+}
+
+// DeclareQueue declares the queue of spec and binds it to its exchange.
+// Declaring a queue again only adds bindings.
+func (b *MemoryBroker) DeclareQueue(spec QueueSpec) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.declare(spec.Name, spec.Retry)
+	for _, pattern := range spec.RoutingKeys {
+		b.bindings = append(b.bindings, memoryBinding{exchange: spec.Exchange, pattern: pattern, queue: spec.Name})
+	}
+}
+
+func (b *MemoryBroker) declare(name string, retry *RetryPolicy) *memoryQueue {
+	if q, ok := b.queues[name]; ok {
+		return q
+	}
+
+	q := &memoryQueue{
+		broker:  b,
+		name:    name,
+		retry:   retry,
+		unacked: make(map[uint64]amqp.Delivery),
+	}
+	b.queues[name] = q
+	return q
+}
+
+func (b *MemoryBroker) DeclareBroadcastQueue() (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return "", fmt.Errorf("failed to declare broadcast queue: %v", ErrClosed)
+	}
+
+	name := GatewayBroadcastExchange + "." + uuid.NewString()
+	b.declare(name, nil)
+	// the fanout exchange ignores the routing key
+	b.bindings = append(b.bindings, memoryBinding{exchange: GatewayBroadcastExchange, pattern: "#", queue: name})
+
+	return name, nil
+}
+
+func (b *MemoryBroker) PublishMessage(ctx context.Context, routingKey string, message contracts.AmqpMessage) error {
+	return b.PublishEvent(ctx, routingKey, message, nil)
+}
+
+func (b *MemoryBroker) PublishEvent(ctx context.Context, routingKey string, message contracts.AmqpMessage, payload any) error {
+	message = newEnvelope(ctx, b.producer, routingKey, message)
+
+	body, err := b.codec.Encode(message, payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s message: %v", routingKey, err)
+	}
+
+	return b.publish(ctx, exchangeFor(routingKey), routingKey, amqp.Delivery{
+		MessageId:     message.EventID,
+		CorrelationId: message.CorrelationID,
+		Type:          message.Type,
+		AppId:         message.Producer,
+		Timestamp:     message.Timestamp,
+		ContentType:   b.codec.ContentType(),
+		Body:          body,
+		DeliveryMode:  amqp.Persistent,
+	})
+}
+
+func (b *MemoryBroker) BroadcastDelivery(ctx context.Context, msg amqp.Delivery) error {
+	return b.publish(ctx, GatewayBroadcastExchange, msg.RoutingKey, amqp.Delivery{
+		Headers:       msg.Headers,
+		MessageId:     msg.MessageId,
+		CorrelationId: msg.CorrelationId,
+		Type:          msg.Type,
+		AppId:         msg.AppId,
+		Timestamp:     msg.Timestamp,
+		ContentType:   msg.ContentType,
+		Body:          msg.Body,
+		DeliveryMode:  amqp.Persistent,
+	})
+}
+
+// publish routes msg within a producer span, like RabbitMQ does.
+func (b *MemoryBroker) publish(ctx context.Context, exchange, routingKey string, msg amqp.Delivery) (err error) {
+	span, headers := startPublishSpan(ctx, exchange, routingKey, msg.MessageId, msg.Headers)
+	defer func() { endSpan(span, err) }()
+	msg.Headers = headers
+
+	return b.route(ctx, exchange, routingKey, msg)
+}
+
+// route queues msg on every queue bound to exchange with a pattern matching
+// routingKey.
+func (b *MemoryBroker) route(ctx context.Context, exchange, routingKey string, msg amqp.Delivery) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to publish %s: %w", routingKey, err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return fmt.Errorf("failed to publish %s: %w", routingKey, ErrClosed)
+	}
+
+	var queues []*memoryQueue
+	for _, binding := range b.bindings {
+		q := b.queues[binding.queue]
+		if binding.exchange == exchange && topicMatches(binding.pattern, routingKey) && !slices.Contains(queues, q) {
+			queues = append(queues, q)
+		}
+	}
+	if len(queues) == 0 {
+		return fmt.Errorf("failed to publish %s: %w", routingKey, ErrUnroutable)
+	}
+
+	msg.Exchange = exchange
+	msg.RoutingKey = routingKey
+	for _, q := range queues {
+		copied := msg
+		copied.Headers = copyHeaders(msg.Headers)
+		q.ready = append(q.ready, copied)
+	}
+	b.cond.Broadcast()
+
+	return nil
+}
+
+// ConsumeMessages starts the workers of a consumer of queue, which must be
+// declared.
+func (b *MemoryBroker) ConsumeMessages(queueName string, handler MessageHandler, opts ...ConsumerOption) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.draining || b.closed {
+		return fmt.Errorf("failed to consume %s: memory broker shutting down", queueName)
+	}
+
+	q, ok := b.queues[queueName]
+	if !ok {
+		return fmt.Errorf("failed to consume %s: queue not declared", queueName)
+	}
+
+	c := newConsumer(queueName, handler, q.retry, opts)
+	c.tag = c.queue + "." + uuid.NewString()
+
+	for range c.concurrency {
+		b.workers.Add(1)
+
+		go func() {
+			defer b.workers.Done()
+
+			for {
+				msg, ok := b.next(q, c.tag)
+				if !ok {
+					return
+				}
+				b.handle(c, q, msg)
+			}
+		}()
+	}
+
+	return nil
+}
+
+// next waits for a message of q, it returns false once the consumers stop.
+func (b *MemoryBroker) next(q *memoryQueue, consumerTag string) (amqp.Delivery, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for len(q.ready) == 0 && !b.draining && !b.closed {
+		b.cond.Wait()
+	}
+	if b.draining || b.closed {
+		return amqp.Delivery{}, false
+	}
+
+	msg := q.ready[0]
+	q.ready = q.ready[1:]
+
+	q.nextTag++
+	msg.DeliveryTag = q.nextTag
+	msg.ConsumerTag = consumerTag
+	msg.Acknowledger = q
+	q.unacked[msg.DeliveryTag] = msg
+
+	return msg, true
+}
+
+func (b *MemoryBroker) handle(c *consumer, q *memoryQueue, msg amqp.Delivery) {
+	if err := c.dispatch(b.handlerCtx, msg); err != nil {
+		log.Printf("failed to handle the message: %v", err)
+		b.retryOrPark(q, msg, c.retryPolicy(err))
+		return
+	}
+
+	if err := msg.Ack(false); err != nil {
+		log.Printf("ERROR: failed to ack message %v", err)
+	}
+}
+
+// retryOrPark queues a failed message again with its attempt counted, like
+// the retry queues of RabbitMQ do after their delay.
+func (b *MemoryBroker) retryOrPark(q *memoryQueue, msg amqp.Delivery, policy *RetryPolicy) {
+	attempt := retryCount(msg) + 1
+
+	if policy == nil || attempt >= policy.MaxAttempts {
+		log.Printf("rejecting message of %s after %d attempts", q.name, attempt)
+		if err := msg.Nack(false, false); err != nil {
+			log.Printf("ERROR: failed to nack message %v", err)
+		}
+		return
+	}
+
+	retry := msg
+	retry.Headers = copyHeaders(msg.Headers)
+	retry.Headers[RetryCountHeader] = int32(attempt)
+	retry.Redelivered = false
+
+	b.mu.Lock()
+	q.ready = append(q.ready, retry)
+	b.cond.Broadcast()
+	b.mu.Unlock()
+
+	log.Printf("retrying message of %s (attempt %d/%d)", q.name, attempt+1, policy.MaxAttempts)
+	if err := msg.Ack(false); err != nil {
+		log.Printf("ERROR: failed to ack message %v", err)
+	}
+}
+
+func (q *memoryQueue) Ack(tag uint64, multiple bool) error {
+	q.broker.mu.Lock()
+	defer q.broker.mu.Unlock()
+
+	_, err := q.settle(tag, multiple)
+	return err
+}
+
+// Nack requeues the messages at the head of the queue, or parks them.
+func (q *memoryQueue) Nack(tag uint64, multiple, requeue bool) error {
+	q.broker.mu.Lock()
+	defer q.broker.mu.Unlock()
+
+	settled, err := q.settle(tag, multiple)
+	if err != nil {
+		return err
+	}
+
+	var requeued []amqp.Delivery
+	for _, msg := range settled {
+		msg.Acknowledger = nil
+		switch {
+		case requeue:
+			msg.Redelivered = true
+			requeued = append(requeued, msg)
+		case q.retry != nil:
+			msg.Headers = copyHeaders(msg.Headers)
+			msg.Headers[OriginalRoutingKeyHeader] = msg.RoutingKey
+			q.parked = append(q.parked, msg)
+		}
+	}
+
+	q.ready = append(requeued, q.ready...)
+	q.broker.cond.Broadcast()
+
+	return nil
+}
+
+func (q *memoryQueue) Reject(tag uint64, requeue bool) error {
+	return q.Nack(tag, false, requeue)
+}
+
+// settle removes the unacked messages settled by tag, in delivery order.
+func (q *memoryQueue) settle(tag uint64, multiple bool) ([]amqp.Delivery, error) {
+	msg, ok := q.unacked[tag]
+	if !ok {
+		return nil, fmt.Errorf("unknown delivery tag %d on %s", tag, q.name)
+	}
+
+	if !multiple {
+		delete(q.unacked, tag)
+		return []amqp.Delivery{msg}, nil
+	}
+
+	var settled []amqp.Delivery
+	for t, msg := range q.unacked {
+		if t <= tag {
+			settled = append(settled, msg)
+			delete(q.unacked, t)
+		}
+	}
+	slices.SortFunc(settled, func(a, b amqp.Delivery) int {
+		return cmp.Compare(a.DeliveryTag, b.DeliveryTag)
+	})
+
+	return settled, nil
+}
+
+// ParkedMessages returns up to limit messages of the parking queue of queue,
+// leaving them parked.
+func (b *MemoryBroker) ParkedMessages(queue string, limit int) ([]amqp.Delivery, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q, ok := b.queues[queue]
+	if !ok || q.retry == nil {
+		return nil, fmt.Errorf("failed to read parking queue of %s: queue not declared", queue)
+	}
+
+	return slices.Clone(q.parked[:min(limit, len(q.parked))]), nil
+}
+
+// ReplayParked moves up to limit parked messages of queue back to queue with
+// a fresh attempt count and returns how many were replayed.
+func (b *MemoryBroker) ReplayParked(ctx context.Context, queue string, limit int) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q, ok := b.queues[queue]
+	if !ok || q.retry == nil {
+		return 0, fmt.Errorf("failed to read parking queue of %s: queue not declared", queue)
+	}
+
+	replayed := min(limit, len(q.parked))
+	for _, msg := range q.parked[:replayed] {
+		msg.Headers = copyHeaders(msg.Headers)
+		delete(msg.Headers, RetryCountHeader)
+		msg.Redelivered = false
+		q.ready = append(q.ready, msg)
+	}
+	q.parked = q.parked[replayed:]
+	b.cond.Broadcast()
+
+	return replayed, nil
+}
+
+// Shutdown stops every consumer and waits for the messages being handled.
+// When ctx is done first, the context of the running handlers is cancelled.
+func (b *MemoryBroker) Shutdown(ctx context.Context) error {
+	b.mu.Lock()
+	b.draining = true
+	b.cond.Broadcast()
+	b.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		b.workers.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		b.cancelHandlers()
+		err = fmt.Errorf("failed to drain the memory broker consumers: %v", ctx.Err())
+	}
+
+	b.Close()
+	return err
+}
+
+// Close stops the consumers right away and cancels the running handlers.
+func (b *MemoryBroker) Close() {
+	b.mu.Lock()
+	b.closed = true
+	b.cond.Broadcast()
+	b.mu.Unlock()
+
+	b.cancelHandlers()
+}
+
+// topicMatches tells whether routingKey matches the binding pattern of a topic
+// exchange. Both are words separated by dots, * in pattern matches exactly one
+// word and # zero or more.
+func topicMatches(pattern, routingKey string) bool {
+	return matchWords(strings.Split(pattern, "."), strings.Split(routingKey, "."))
+}
+
+func matchWords(pattern, words []string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case "#":
+			for i := range len(words) + 1 {
+				if matchWords(pattern[1:], words[i:]) {
+					return true
+				}
+			}
+			return false
+		case "*":
+			if len(words) == 0 {
+				return false
+			}
+		default:
+			if len(words) == 0 || words[0] != pattern[0] {
+				return false
+			}
+		}
+
+		pattern, words = pattern[1:], words[1:]
+	}
+
+	return len(words) == 0
+}
@@ -0,0 +1,360 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"ride-sharing/shared/contracts"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestTopicMatches(t *testing.T) {
+	tests := []struct {
+		pattern    string
+		routingKey string
+		want       bool
+	}{
+		{"trip.event.created", "trip.event.created", true},
+		{"trip.event.created", "trip.event.cancelled", false},
+		{"trip.event.created", "trip.event", false},
+		{"trip.event", "trip.event.created", false},
+
+		// * matches exactly one word
+		{"trip.*.created", "trip.event.created", true},
+		{"trip.event.*", "trip.event.created", true},
+		{"trip.event.*", "trip.event", false},
+		{"trip.event.*", "trip.event.created.v2", false},
+		{"*", "trip", true},
+		{"*", "trip.event", false},
+		{"*.*", "trip.event", true},
+
+		// # matches zero or more words
+		{"#", "trip.event.created", true},
+		{"#", "trip", true},
+		{"trip.#", "trip.event.created", true},
+		{"trip.#", "trip", true},
+		{"trip.#", "driver.cmd.location", false},
+		{"#.created", "trip.event.created", true},
+		{"#.created", "created", true},
+		{"#.created", "trip.event.cancelled", false},
+		{"trip.#.created", "trip.created", true},
+		{"trip.#.created", "trip.event.v2.created", true},
+		{"trip.#.created", "trip.event.created.late", false},
+		{"#.event.#", "trip.event.created", true},
+		{"#.*", "trip", true},
+		{"#.*", "", true},
+		{"*.#.*", "trip", false},
+	}
+
+	for _, tt := range tests {
+		if got := topicMatches(tt.pattern, tt.routingKey); got != tt.want {
+			t.Errorf("topicMatches(%q, %q) = %v, want %v", tt.pattern, tt.routingKey, got, tt.want)
+		}
+	}
+}
+
+// testTopology has a queue bound with wildcards with a retry policy of three
+// attempts, and one without retry policy.
+var testTopology = Topology{
+	Queues: []QueueSpec{
+		{
+			Name:        "trips",
+			Exchange:    TripExchange,
+			RoutingKeys: []string{"trip.event.*"},
+			Retry:       &RetryPolicy{MaxAttempts: 3},
+		},
+		{
+			Name:        "everything",
+			Exchange:    TripExchange,
+			RoutingKeys: []string{"#"},
+		},
+	},
+}
+
+func TestMemoryBrokerRouting(t *testing.T) {
+	broker := NewMemoryBroker("test", WithTopology(testTopology))
+	defer broker.Close()
+	ctx := context.Background()
+
+	if err := broker.PublishMessage(ctx, contracts.TripEventCreated, contracts.AmqpMessage{OwnerID: "rider-1"}); err != nil {
+		t.Fatalf("PublishMessage() error = %v", err)
+	}
+	if err := broker.PublishMessage(ctx, contracts.DriverCmdLocation, contracts.AmqpMessage{OwnerID: "driver-1"}); err != nil {
+		t.Fatalf("PublishMessage() error = %v", err)
+	}
+
+	if got := readyMessages(broker, "trips"); len(got) != 1 || got[0].RoutingKey != contracts.TripEventCreated {
+		t.Errorf("trips queue holds %v, want the created event", routingKeys(got))
+	}
+	if got := readyMessages(broker, "everything"); len(got) != 2 {
+		t.Errorf("everything queue holds %v, want both events", routingKeys(got))
+	}
+
+	unroutable := NewMemoryBroker("test", WithTopology(Topology{Queues: testTopology.Queues[:1]}))
+	defer unroutable.Close()
+	if err := unroutable.PublishMessage(ctx, contracts.DriverCmdLocation, contracts.AmqpMessage{}); !errors.Is(err, ErrUnroutable) {
+		t.Errorf("PublishMessage() of an unbound routing key error = %v, want %v", err, ErrUnroutable)
+	}
+
+	broker.Close()
+	if err := broker.PublishMessage(ctx, contracts.TripEventCreated, contracts.AmqpMessage{}); !errors.Is(err, ErrClosed) {
+		t.Errorf("PublishMessage() after Close error = %v, want %v", err, ErrClosed)
+	}
+}
+
+func TestMemoryBrokerPaymentExchange(t *testing.T) {
+	broker := NewMemoryBroker("test")
+	defer broker.Close()
+
+	if err := broker.PublishMessage(context.Background(), contracts.PaymentEventSessionCreated, contracts.AmqpMessage{OwnerID: "rider-1"}); err != nil {
+		t.Fatalf("PublishMessage() error = %v", err)
+	}
+
+	got := readyMessages(broker, NotifyPaymentStatusQueue)
+	if len(got) != 1 || got[0].Exchange != PaymentExchange {
+		t.Errorf("%s holds %v, want the session created through %s", NotifyPaymentStatusQueue, got, PaymentExchange)
+	}
+	for name := range broker.queues {
+		if name != NotifyPaymentStatusQueue && len(readyMessages(broker, name)) > 0 {
+			t.Errorf("%s received a payment event", name)
+		}
+	}
+}
+
+func TestMemoryBrokerAckNack(t *testing.T) {
+	broker := NewMemoryBroker("test", WithTopology(testTopology))
+	defer broker.Close()
+	q := broker.queues["trips"]
+
+	for range 3 {
+		if err := broker.PublishMessage(context.Background(), contracts.TripEventCreated, contracts.AmqpMessage{}); err != nil {
+			t.Fatalf("PublishMessage() error = %v", err)
+		}
+	}
+
+	first, _ := broker.next(q, "test")
+	second, _ := broker.next(q, "test")
+	third, _ := broker.next(q, "test")
+
+	// acked messages are gone, settling them twice fails
+	if err := first.Ack(false); err != nil {
+		t.Fatalf("Ack() error = %v", err)
+	}
+	if err := first.Ack(false); err == nil {
+		t.Error("Ack() of a settled message succeeded")
+	}
+
+	// requeued messages go back to the head of the queue, redelivered
+	if err := second.Nack(false, true); err != nil {
+		t.Fatalf("Nack(requeue) error = %v", err)
+	}
+	redelivered, _ := broker.next(q, "test")
+	if redelivered.MessageId != second.MessageId || !redelivered.Redelivered {
+		t.Errorf("next() = %s redelivered %v, want %s redelivered", redelivered.MessageId, redelivered.Redelivered, second.MessageId)
+	}
+
+	// rejected messages are parked with their routing key
+	if err := third.Nack(false, false); err != nil {
+		t.Fatalf("Nack() error = %v", err)
+	}
+	parked, err := broker.ParkedMessages("trips", 10)
+	if err != nil {
+		t.Fatalf("ParkedMessages() error = %v", err)
+	}
+	if len(parked) != 1 || parked[0].MessageId != third.MessageId || originalRoutingKey(parked[0]) != contracts.TripEventCreated {
+		t.Errorf("ParkedMessages() = %v, want %s", parked, third.MessageId)
+	}
+
+	// multiple settles every message up to the tag
+	if err := broker.PublishMessage(context.Background(), contracts.TripEventCreated, contracts.AmqpMessage{}); err != nil {
+		t.Fatalf("PublishMessage() error = %v", err)
+	}
+	last, _ := broker.next(q, "test")
+	if err := last.Ack(true); err != nil {
+		t.Fatalf("Ack(multiple) error = %v", err)
+	}
+	if len(q.unacked) != 0 {
+		t.Errorf("%d messages left unacked", len(q.unacked))
+	}
+}
+
+func TestMemoryBrokerRetriesThenParks(t *testing.T) {
+	broker := NewMemoryBroker("test", WithTopology(testTopology))
+	defer broker.Close()
+
+	handled := make(chan amqp.Delivery, 10)
+	var fail sync.Mutex
+	failing := true
+	err := broker.ConsumeMessages("trips", func(ctx context.Context, msg amqp.Delivery) error {
+		handled <- msg
+
+		fail.Lock()
+		defer fail.Unlock()
+		if failing {
+			return errors.New("handler failed")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ConsumeMessages() error = %v", err)
+	}
+
+	if err := broker.PublishMessage(context.Background(), contracts.TripEventCreated, contracts.AmqpMessage{}); err != nil {
+		t.Fatalf("PublishMessage() error = %v", err)
+	}
+
+	// every attempt of the retry policy, counted
+	for attempt := range 3 {
+		msg := receive(t, handled)
+		if got := retryCount(msg); got != attempt {
+			t.Errorf("attempt %d delivered with retry count %d", attempt+1, got)
+		}
+	}
+	waitParked(t, broker, "trips", 1)
+
+	// replayed messages start over and reach the fixed handler
+	fail.Lock()
+	failing = false
+	fail.Unlock()
+
+	if replayed, err := broker.ReplayParked(context.Background(), "trips", 10); err != nil || replayed != 1 {
+		t.Fatalf("ReplayParked() = %d, %v, want 1", replayed, err)
+	}
+	if msg := receive(t, handled); retryCount(msg) != 0 {
+		t.Errorf("replayed message delivered with retry count %d", retryCount(msg))
+	}
+	waitParked(t, broker, "trips", 0)
+}
+
+func TestMemoryBrokerParksUndecodableMessages(t *testing.T) {
+	broker := NewMemoryBroker("test", WithTopology(testTopology))
+	defer broker.Close()
+
+	handled := make(chan Event[TripEventData], 10)
+	err := Subscribe(broker, "trips", func(ctx context.Context, event Event[TripEventData]) error {
+		handled <- event
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	// no retry can make it readable
+	err = broker.route(context.Background(), TripExchange, contracts.TripEventCreated, amqp.Delivery{
+		MessageId:   "unreadable",
+		ContentType: "application/xml",
+		Body:        []byte("<trip/>"),
+	})
+	if err != nil {
+		t.Fatalf("route() error = %v", err)
+	}
+
+	waitParked(t, broker, "trips", 1)
+	select {
+	case event := <-handled:
+		t.Fatalf("handler received %v", event)
+	default:
+	}
+}
+
+func TestMemoryBrokerShutdownDrains(t *testing.T) {
+	broker := NewMemoryBroker("test", WithTopology(testTopology))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var handled bool
+	err := broker.ConsumeMessages("trips", func(ctx context.Context, msg amqp.Delivery) error {
+		close(started)
+		<-release
+		handled = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ConsumeMessages() error = %v", err)
+	}
+
+	if err := broker.PublishMessage(context.Background(), contracts.TripEventCreated, contracts.AmqpMessage{}); err != nil {
+		t.Fatalf("PublishMessage() error = %v", err)
+	}
+	<-started
+
+	shutdown := make(chan error)
+	go func() {
+		shutdown <- broker.Shutdown(context.Background())
+	}()
+
+	close(release)
+	if err := <-shutdown; err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if !handled {
+		t.Error("Shutdown() returned before the message was handled")
+	}
+}
+
+func TestMemoryBrokerShutdownCancelsHandlers(t *testing.T) {
+	broker := NewMemoryBroker("test", WithTopology(testTopology))
+
+	started := make(chan struct{})
+	err := broker.ConsumeMessages("trips", func(ctx context.Context, msg amqp.Delivery) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err != nil {
+		t.Fatalf("ConsumeMessages() error = %v", err)
+	}
+
+	if err := broker.PublishMessage(context.Background(), contracts.TripEventCreated, contracts.AmqpMessage{}); err != nil {
+		t.Fatalf("PublishMessage() error = %v", err)
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := broker.Shutdown(ctx); err == nil {
+		t.Fatal("Shutdown() of a stuck handler succeeded")
+	}
+}
+
+func readyMessages(b *MemoryBroker, queue string) []amqp.Delivery {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.queues[queue].ready
+}
+
+func routingKeys(msgs []amqp.Delivery) []string {
+	keys := make([]string, len(msgs))
+	for i, msg := range msgs {
+		keys[i] = msg.RoutingKey
+	}
+	return keys
+}
+
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(time.Second):
+		t.Fatal("nothing received")
+		var zero T
+		return zero
+	}
+}
+
+func waitParked(t *testing.T, b *MemoryBroker, queue string, n int) {
+	t.Helper()
+
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if parked, _ := b.ParkedMessages(queue, n+1); len(parked) == n {
+			return
+		}
+	}
+	t.Fatalf("%s never had %d parked messages", queue, n)
+}
@@ -0,0 +1,265 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// queueDeclaration is a queue of the topology as MigrateQueues declares it.
+type queueDeclaration struct {
+	name        string
+	args        amqp.Table
+	exchange    string
+	routingKeys []string
+}
+
+// migratingQueueName is the queue holding the messages of queue while it is
+// recreated.
+func migratingQueueName(queue string) string {
+	return queue + ".migrating"
+}
+
+// MigrateQueues recreates the queues of t the broker refuses to declare with
+// PRECONDITION_FAILED because they were declared before with other arguments,
+// e.g. a queue given a retry policy since, or a retry queue whose delay
+// changed. The messages of such a queue wait in a temporary queue bound like
+// it while it is recreated, then move back in. It returns the queues
+// recreated.
+//
+// The consumers of the queues must be stopped first, the messages they hold
+// could not be requeued. A migration that failed halfway is resumed by running
+// it again.
+func MigrateQueues(ctx context.Context, uri string, t Topology) ([]string, error) {
+	conn, err := amqp.Dial(uri)
+	if err != nil {
+		return nil, fmt.Errorf("fail to connect to rabbitmq: %v", err)
+	}
+	defer conn.Close()
+
+	if err := withConnectionChannel(conn, func(ch *amqp.Channel) error {
+		return declareExchanges(ch, t)
+	}); err != nil {
+		return nil, err
+	}
+
+	var migrated []string
+	for _, q := range t.Queues {
+		if q.PerReplica {
+			continue
+		}
+
+		for _, d := range queueDeclarations(q, t.DeadLetterExchange) {
+			recreated, err := migrateQueue(ctx, conn, d)
+			if err != nil {
+				return migrated, err
+			}
+			if recreated {
+				migrated = append(migrated, d.name)
+			}
+		}
+	}
+
+	// the parking queues and the bindings, as the services declare them
+	err = withConnectionChannel(conn, func(ch *amqp.Channel) error {
+		return declareTopology(ch, t)
+	})
+
+	return migrated, err
+}
+
+// queueDeclarations returns the retry queues of q, then q itself: q must not
+// be recreated before the queues its messages are retried through.
+func queueDeclarations(q QueueSpec, deadLetterExchange string) []queueDeclaration {
+	var declarations []queueDeclaration
+
+	if q.Retry != nil {
+		for attempt := 1; attempt < q.Retry.MaxAttempts; attempt++ {
+			declarations = append(declarations, queueDeclaration{
+				name: RetryQueueName(q.Name, attempt),
+				args: retryQueueArguments(q.Name, *q.Retry, attempt),
+			})
+		}
+	}
+
+	return append(declarations, queueDeclaration{
+		name:        q.Name,
+		args:        queueArguments(q, deadLetterExchange),
+		exchange:    q.Exchange,
+		routingKeys: q.RoutingKeys,
+	})
+}
+
+// migrateQueue declares the queue of d, recreating it when the broker refuses
+// the declaration. It returns whether the queue was recreated.
+func migrateQueue(ctx context.Context, conn *amqp.Connection, d queueDeclaration) (bool, error) {
+	// a refused declaration closes the channel
+	err := withConnectionChannel(conn, func(ch *amqp.Channel) error {
+		_, err := ch.QueueDeclare(d.name, true, false, false, false, d.args)
+		return err
+	})
+	if err != nil && !isPreconditionFailed(err) {
+		return false, fmt.Errorf("failed to declare queue %s: %v", d.name, err)
+	}
+
+	recreate := err != nil
+	err = withConnectionChannel(conn, func(ch *amqp.Channel) error {
+		publisher, err := newConfirmedChannel(ch)
+		if err != nil {
+			return err
+		}
+
+		temp := migratingQueueName(d.name)
+		if recreate {
+			log.Printf("recreating queue %s with its new arguments", d.name)
+
+			if err := declareQueue(ch, temp, nil, d.exchange, d.routingKeys); err != nil {
+				return err
+			}
+			// temp receives the messages routed meanwhile
+			if err := drainQueue(ctx, ch, publisher, d.name, temp, d.exchange, d.routingKeys); err != nil {
+				return err
+			}
+			if err := declareQueue(ch, d.name, d.args, d.exchange, d.routingKeys); err != nil {
+				return err
+			}
+		} else if !queueExists(conn, temp) {
+			return nil
+		}
+
+		// also resumes a migration that failed after recreating the queue
+		return drainQueue(ctx, ch, publisher, temp, d.name, d.exchange, d.routingKeys)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to migrate queue %s: %v", d.name, err)
+	}
+
+	return recreate, nil
+}
+
+// declareQueue declares a durable queue bound to exchange with routingKeys.
+func declareQueue(ch *amqp.Channel, name string, args amqp.Table, exchange string, routingKeys []string) error {
+	if _, err := ch.QueueDeclare(name, true, false, false, false, args); err != nil {
+		return fmt.Errorf("failed to declare queue %s: %v", name, err)
+	}
+
+	for _, key := range routingKeys {
+		if err := ch.QueueBind(name, key, exchange, false, nil); err != nil {
+			return fmt.Errorf("failed to bind queue to %s : %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// drainQueue unbinds from, moves its messages to the queue to and deletes it.
+// Deleting fails while from still receives messages through other bindings.
+func drainQueue(ctx context.Context, ch *amqp.Channel, publisher *confirmedChannel, from, to, exchange string, routingKeys []string) error {
+	for _, key := range routingKeys {
+		if err := ch.QueueUnbind(from, key, exchange, nil); err != nil {
+			return fmt.Errorf("failed to unbind queue %s: %v", from, err)
+		}
+	}
+
+	moved, err := moveMessages(ctx, ch, publisher, from, to)
+	if err != nil {
+		return err
+	}
+
+	if _, err := ch.QueueDelete(from, false, true, false); err != nil {
+		return fmt.Errorf("failed to delete queue %s after moving %d messages: %v", from, moved, err)
+	}
+
+	log.Printf("moved %d messages from %s to %s", moved, from, to)
+	return nil
+}
+
+// moveMessages moves the messages of from to the queue to, through the default
+// exchange, keeping their routing key and attempt count. It returns how many
+// were moved.
+func moveMessages(ctx context.Context, ch *amqp.Channel, publisher *confirmedChannel, from, to string) (int, error) {
+	moved := 0
+
+	for {
+		msg, ok, err := ch.Get(from, false)
+		if err != nil {
+			return moved, fmt.Errorf("failed to read queue %s: %v", from, err)
+		}
+		if !ok {
+			return moved, nil
+		}
+
+		move := amqp.Publishing{
+			Headers:         copyHeaders(msg.Headers),
+			ContentType:     msg.ContentType,
+			ContentEncoding: msg.ContentEncoding,
+			DeliveryMode:    amqp.Persistent,
+			CorrelationId:   msg.CorrelationId,
+			MessageId:       msg.MessageId,
+			Timestamp:       msg.Timestamp,
+			Type:            msg.Type,
+			AppId:           msg.AppId,
+			Body:            msg.Body,
+		}
+		move.Headers[OriginalRoutingKeyHeader] = originalRoutingKey(msg)
+		if move.MessageId == "" {
+			// the publisher tells the returned messages by their ID
+			move.MessageId = uuid.NewString()
+		}
+
+		confirmed, err := publisher.publish(ctx, "", to, move)
+		if err != nil {
+			msg.Nack(false, true)
+			return moved, fmt.Errorf("failed to move a message of %s: %v", from, err)
+		}
+
+		select {
+		case err = <-confirmed:
+		case <-time.After(publishConfirmTimeout):
+			err = ErrConfirmTimeout
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		if err != nil {
+			msg.Nack(false, true)
+			return moved, fmt.Errorf("failed to move a message of %s: %v", from, err)
+		}
+
+		if err := msg.Ack(false); err != nil {
+			return moved, fmt.Errorf("failed to ack a moved message of %s: %v", from, err)
+		}
+		moved++
+	}
+}
+
+// queueExists declares name passively, on a channel of its own since a
+// missing queue closes it.
+func queueExists(conn *amqp.Connection, name string) bool {
+	err := withConnectionChannel(conn, func(ch *amqp.Channel) error {
+		_, err := ch.QueueDeclarePassive(name, true, false, false, false, nil)
+		return err
+	})
+	return err == nil
+}
+
+func withConnectionChannel(conn *amqp.Connection, fn func(ch *amqp.Channel) error) error {
+	ch, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("fail to create channel rabbitmq: %v", err)
+	}
+	defer ch.Close()
+
+	return fn(ch)
+}
+
+// isPreconditionFailed tells the errors of a queue declared again with other
+// arguments than the existing one.
+func isPreconditionFailed(err error) bool {
+	var amqpErr *amqp.Error
+	return errors.As(err, &amqpErr) && amqpErr.Code == amqp.PreconditionFailed
+}
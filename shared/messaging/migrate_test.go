@@ -0,0 +1,64 @@
+package messaging
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestQueueDeclarations(t *testing.T) {
+	q := QueueSpec{
+		Name:        "find_available_drivers",
+		Exchange:    TripExchange,
+		RoutingKeys: []string{"trip.event.created"},
+		Retry:       &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second},
+	}
+
+	declarations := queueDeclarations(q, DeadLetterExchange)
+
+	// the retry queues come first
+	want := []string{"find_available_drivers.retry.1", "find_available_drivers.retry.2", "find_available_drivers"}
+	if len(declarations) != len(want) {
+		t.Fatalf("queueDeclarations() = %v, want %v", declarations, want)
+	}
+	for i, d := range declarations {
+		if d.name != want[i] {
+			t.Errorf("declaration %d = %s, want %s", i, d.name, want[i])
+		}
+	}
+
+	retry := declarations[1]
+	if retry.args["x-message-ttl"] != int64(2000) || retry.args["x-dead-letter-routing-key"] != q.Name || retry.exchange != "" {
+		t.Errorf("retry queue declared with %v on %q", retry.args, retry.exchange)
+	}
+
+	queue := declarations[2]
+	if queue.args["x-dead-letter-exchange"] != DeadLetterExchange || queue.exchange != TripExchange || len(queue.routingKeys) != 1 {
+		t.Errorf("queue declared with %v on %q bound with %v", queue.args, queue.exchange, queue.routingKeys)
+	}
+
+	if got := queueDeclarations(QueueSpec{Name: "no_retry"}, DeadLetterExchange); len(got) != 1 || got[0].args != nil {
+		t.Errorf("queueDeclarations() without retry policy = %v", got)
+	}
+}
+
+func TestIsPreconditionFailed(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{&amqp.Error{Code: amqp.PreconditionFailed, Reason: "inequivalent arg 'x-dead-letter-exchange'"}, true},
+		{fmt.Errorf("declare: %w", &amqp.Error{Code: amqp.PreconditionFailed}), true},
+		{&amqp.Error{Code: amqp.NotFound}, false},
+		{amqp.ErrClosed, false},
+	}
+
+	for _, tt := range tests {
+		if got := isPreconditionFailed(tt.err); got != tt.want {
+			t.Errorf("isPreconditionFailed(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -2,94 +2,301 @@ package messaging
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/metrics"
+	"ride-sharing/shared/retry"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/google/uuid"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 const (
 	TripExchange = "trip"
+	// PaymentExchange carries the payment events and commands, so that the
+	// payment service owns its routing apart from the trip flow.
+	PaymentExchange = "payment"
+	// GatewayBroadcastExchange fans messages out to every api-gateway replica,
+	// for users connected to another replica than the one consuming the message.
+	GatewayBroadcastExchange = "gateway_broadcast"
 )
 
+// exchangeFor returns the exchange the messages of routingKey are published to.
+func exchangeFor(routingKey string) string {
+	if strings.HasPrefix(routingKey, "payment.") {
+		return PaymentExchange
+	}
+	return TripExchange
+}
+
+// how the connection is re-established after the broker went away, rounds of
+// retries run until it is back or the client is closed
+var reconnectConfig = retry.Config{
+	MaxRetries:  10,
+	InitialWait: 500 * time.Millisecond,
+	MaxWait:     30 * time.Second,
+}
+
+// how long a publisher waits for the broker to confirm a message
+const publishConfirmTimeout = 5 * time.Second
+
+var ErrClosed = errors.New("rabbitmq client closed")
+
+var publishFailures = metrics.NewCounter(
+	"rabbitmq_publish_failures_total",
+	"Messages the broker did not take responsibility for, per routing key.",
+	"routing_key",
+)
+
+// RabbitMQ is a connection to the broker that survives broker restarts: when
+// the connection or its channel closes, it reconnects with backoff, redeclares
+// the topology and the broadcast queues, and resumes every registered
+// consumer. Publishing blocks until the connection is back or the context of
+// the publisher is done.
+//
+// Messages are published as mandatory on a channel of their own in confirm
+// mode: a publish only succeeds once the broker routed the message to a queue
+// and took responsibility for it.
 type RabbitMQ struct {
-	conn *amqp.Connection
-	Ch   *amqp.Channel
+	uri string
+	// service named as the producer of the events it publishes
+	producer string
+	// encodes the messages published, consumers decode any content type
+	codec Codec
+	// exchanges and queues declared on every connection
+	topology Topology
+
+	mu        sync.Mutex
+	conn      *amqp.Connection
+	ch        *amqp.Channel
+	publisher *confirmedChannel
+	// closed once connected, replaced while reconnecting
+	ready     chan struct{}
+	consumers []*consumer
+	// set by Shutdown, consumers are not resumed anymore
+	draining        bool
+	broadcastQueues []string
+
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// handlerCtx is cancelled when the in-flight messages failed to drain in time
+	handlerCtx     context.Context
+	cancelHandlers context.CancelFunc
+	workers        sync.WaitGroup
 }
 
 type MessageHandler func(context.Context, amqp.Delivery) error
 
-func NewRabbitMQ(uri string) (*RabbitMQ, error) {
-	conn, err := amqp.Dial(uri)
+func NewRabbitMQ(uri, producer string, opts ...Option) (*RabbitMQ, error) {
+	o := newOptions(opts)
+	rmq := &RabbitMQ{
+		uri:      uri,
+		producer: producer,
+		codec:    o.codec,
+		topology: o.topology,
+		ready:    make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	rmq.handlerCtx, rmq.cancelHandlers = context.WithCancel(context.Background())
+
+	if err := retry.WithBackoff(context.Background(), retry.DefaultConfig(), rmq.connect); err != nil {
+		return nil, err
+	}
+
+	return rmq, nil
+}
+
+// connect dials the broker, declares the topology on a new channel and resumes
+// the consumers registered so far.
+func (r *RabbitMQ) connect() error {
+	conn, err := amqp.Dial(r.uri)
 	if err != nil {
-		return nil, fmt.Errorf("fail to connect to rabbitmq: %v", err)
+		return fmt.Errorf("fail to connect to rabbitmq: %v", err)
 	}
 
 	ch, err := conn.Channel()
 	if err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("fail to create channel rabbitmq: %v", err)
+		return fmt.Errorf("fail to create channel rabbitmq: %v", err)
 	}
 
-	rmq := &RabbitMQ{conn: conn, Ch: ch}
-
-	if err := rmq.setupExchangesAndQueues(); err != nil {
-		rmq.Close()
-		return nil, fmt.Errorf("fail to setup exchanges and queues rabbitmq: %v", err)
+	if err := declareTopology(ch, r.topology); err != nil {
+		conn.Close()
+		return fmt.Errorf("fail to setup exchanges and queues rabbitmq: %v", err)
 	}
 
-	return rmq, nil
-}
-
-func (r *RabbitMQ) setupExchangesAndQueues() error {
-	err := r.Ch.ExchangeDeclare(
-		TripExchange, //name
-		"topic",      //type
-		true,         //durable
-		false,        //auto-deleted
-		false,        //internal
-		false,        //no-wait
-		nil,          //arguments
-	)
+	// publishing on its own channel keeps the consumers out of its flow control
+	pubCh, err := conn.Channel()
 	if err != nil {
-		return fmt.Errorf("failed to declare exchange %v : %v", TripExchange, err)
+		conn.Close()
+		return fmt.Errorf("fail to create publishing channel rabbitmq: %v", err)
 	}
 
-	if err := r.declareAndBindQueue(
-		FindAvailableDriversQueue,
-		[]string{
-			contracts.TripEventCreated, contracts.TripEventDriverNotInterested,
-		},
-		TripExchange,
-	); err != nil {
+	publisher, err := newConfirmedChannel(pubCh)
+	if err != nil {
+		conn.Close()
 		return err
 	}
 
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	select {
+	case <-r.done:
+		conn.Close()
+		return ErrClosed
+	default:
+	}
+
+	// exclusive queues died with the previous connection
+	for _, queue := range r.broadcastQueues {
+		if err := declareBroadcastQueue(ch, queue); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+
+	for _, c := range r.consumers {
+		if r.draining {
+			break
+		}
+		if err := r.consume(ch, c); err != nil {
+			conn.Close()
+			return fmt.Errorf("failed to resume consuming %s: %v", c.queue, err)
+		}
+	}
+
+	r.conn, r.ch, r.publisher = conn, ch, publisher
+	close(r.ready)
+
+	go r.watch(conn, ch, pubCh)
+
 	return nil
 }
 
-func (r *RabbitMQ) declareAndBindQueue(queueName string, messageTypes []string, exchange string) error {
-	q, err := r.Ch.QueueDeclare(
-		queueName, //name
+// watch waits for the connection to close and reconnects, unless the client
+// was closed. A closed channel, e.g. after a channel level error, closes the
+// connection too so that everything is recovered the same way.
+func (r *RabbitMQ) watch(conn *amqp.Connection, ch, pubCh *amqp.Channel) {
+	connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+	chClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+	pubChClosed := pubCh.NotifyClose(make(chan *amqp.Error, 1))
+
+	var closeErr *amqp.Error
+	select {
+	case closeErr = <-connClosed:
+	case closeErr = <-chClosed:
+		conn.Close()
+	case closeErr = <-pubChClosed:
+		conn.Close()
+	}
+
+	select {
+	case <-r.done:
+		return
+	default:
+	}
+
+	log.Printf("rabbitmq connection lost: %v, reconnecting", closeErr)
+
+	r.mu.Lock()
+	r.conn, r.ch, r.publisher = nil, nil, nil
+	r.ready = make(chan struct{})
+	r.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-r.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		err := retry.WithBackoff(ctx, reconnectConfig, r.connect)
+		if err == nil {
+			log.Println("rabbitmq connection restored")
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Printf("failed to reconnect to rabbitmq, still trying: %v", err)
+	}
+}
+
+// publishingChannel returns the current publishing channel, waiting for the
+// connection to be back while reconnecting.
+func (r *RabbitMQ) publishingChannel(ctx context.Context) (*confirmedChannel, error) {
+	for {
+		r.mu.Lock()
+		publisher, ready := r.publisher, r.ready
+		r.mu.Unlock()
+
+		if publisher != nil && !publisher.ch.IsClosed() {
+			return publisher, nil
+		}
+
+		// a closed channel not yet noticed by watch, ready is still closed
+		var notice <-chan time.Time
+		if publisher != nil {
+			notice = time.After(100 * time.Millisecond)
+			ready = nil
+		}
+
+		select {
+		case <-notice:
+		case <-ready:
+		case <-r.done:
+			return nil, ErrClosed
+		case <-ctx.Done():
+			return nil, fmt.Errorf("rabbitmq unavailable: %v", ctx.Err())
+		}
+	}
+}
+
+// declareAndBindQueue declares a queue with a retry policy along with its
+// retry and parking queues, it dead-letters the messages rejected for good to
+// its parking queue. Queues declared before with other dead-letter arguments
+// are refused by the broker, see MigrateQueues.
+func declareAndBindQueue(ch *amqp.Channel, spec QueueSpec, deadLetterExchange string) error {
+	q, err := ch.QueueDeclare(
+		spec.Name, //name
 		true,      //durable
 		false,     //delete when unused
 		false,     //exclusive
 		false,     //no-wait
-		nil,       //arguments
+		queueArguments(spec, deadLetterExchange),
 	)
+	if isPreconditionFailed(err) {
+		return fmt.Errorf("failed to declare queue %s, declared before with other arguments: migrate it with tools/migrate-queues: %v", spec.Name, err)
+	}
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("failed to declare queue %s: %v", spec.Name, err)
+	}
+
+	if spec.Retry != nil {
+		if err := declareRetryQueues(ch, spec.Name, *spec.Retry, deadLetterExchange); err != nil {
+			return err
+		}
 	}
 
-	for _, msg := range messageTypes {
-		err = r.Ch.QueueBind(
-			q.Name,   //queue name
-			msg,      //routing key
-			exchange, //exchange
-			false,    //no-wait
-			nil,      //arguments
+	for _, key := range spec.RoutingKeys {
+		err = ch.QueueBind(
+			q.Name,        //queue name
+			key,           //routing key
+			spec.Exchange, //exchange
+			false,         //no-wait
+			nil,           //arguments
 		)
 		if err != nil {
 			return fmt.Errorf("failed to bind queue to %s : %v", q.Name, err)
@@ -99,84 +306,166 @@ func (r *RabbitMQ) declareAndBindQueue(queueName string, messageTypes []string,
 	return nil
 }
 
-func (r *RabbitMQ) ConsumeMessages(queueName string, handler MessageHandler) error {
-	// fair dispatch
-	err := r.Ch.Qos(
-		1,     // prefetch count
-		0,     // prefetch size
-		false, // global
+// PublishMessage publishes message with its Data as is, which only the JSON
+// codec can carry. See Publish to send a typed payload.
+func (r *RabbitMQ) PublishMessage(ctx context.Context, routingKey string, message contracts.AmqpMessage) error {
+	return r.publish(ctx, exchangeFor(routingKey), routingKey, message, nil)
+}
+
+func (r *RabbitMQ) PublishEvent(ctx context.Context, routingKey string, message contracts.AmqpMessage, payload any) error {
+	return r.publish(ctx, exchangeFor(routingKey), routingKey, message, payload)
+}
+
+// BroadcastDelivery forwards a received message to every replica listening on
+// a broadcast queue. The body is sent untouched with its content type and
+// headers, which carry the trace context, and the routing key is kept so
+// consumers can tell the message type.
+func (r *RabbitMQ) BroadcastDelivery(ctx context.Context, msg amqp.Delivery) error {
+	return r.publishBody(ctx, GatewayBroadcastExchange, msg.RoutingKey, amqp.Publishing{
+		Headers:       msg.Headers,
+		MessageId:     msg.MessageId,
+		CorrelationId: msg.CorrelationId,
+		Type:          msg.Type,
+		AppId:         msg.AppId,
+		Timestamp:     msg.Timestamp,
+		ContentType:   msg.ContentType,
+		Body:          msg.Body,
+		DeliveryMode:  amqp.Persistent,
+	})
+}
+
+// DeclareBroadcastQueue declares a queue exclusive to this connection and
+// deleted with it, that receives every broadcast message. The queue is named
+// by the client so that it can be declared again after a reconnection;
+// messages broadcast during the outage are lost.
+func (r *RabbitMQ) DeclareBroadcastQueue() (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.ch == nil {
+		return "", fmt.Errorf("failed to declare broadcast queue: rabbitmq not connected")
+	}
+
+	name := GatewayBroadcastExchange + "." + uuid.NewString()
+	if err := declareBroadcastQueue(r.ch, name); err != nil {
+		return "", err
+	}
+
+	r.broadcastQueues = append(r.broadcastQueues, name)
+	return name, nil
+}
+
+func declareBroadcastQueue(ch *amqp.Channel, name string) error {
+	q, err := ch.QueueDeclare(
+		name,  //name
+		false, //durable
+		true,  //delete when unused
+		true,  //exclusive
+		false, //no-wait
+		nil,   //arguments
 	)
 	if err != nil {
-		return fmt.Errorf("failed to set Qos: %v", err)
+		return fmt.Errorf("failed to declare broadcast queue: %v", err)
 	}
 
-	msgs, err := r.Ch.Consume(
-		queueName, // queue
-		"",        // consumer
-		false,     // auto-ack
-		false,     // exclusive
-		false,     // no-local
-		false,     // no-wait
-		nil,       // args
+	err = ch.QueueBind(
+		q.Name,                   //queue name
+		"",                       //routing key
+		GatewayBroadcastExchange, //exchange
+		false,                    //no-wait
+		nil,                      //arguments
 	)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to bind queue to %s : %v", q.Name, err)
 	}
 
-	ctx := context.Background()
+	return nil
+}
 
-	go func() {
-		for msg := range msgs {
-			log.Printf("received a message %v", msg)
-			if err := handler(ctx, msg); err != nil {
-				log.Printf("failed to handle the message: %v", err)
+// publish blocks while reconnecting, then waits for the broker to confirm the
+// message. A message whose channel closed before it was confirmed is sent again
+// on the new channel, consumers may see it twice.
+func (r *RabbitMQ) publish(ctx context.Context, exchange, routingKey string, message contracts.AmqpMessage, payload any) error {
+	message = newEnvelope(ctx, r.producer, routingKey, message)
 
-				if err := msg.Nack(false, false); err != nil {
-					log.Printf("ERROR: failed to nack message %v", err)
-				}
+	body, err := r.codec.Encode(message, payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s message: %v", routingKey, err)
+	}
 
-				continue
-			}
+	return r.publishBody(ctx, exchange, routingKey, amqp.Publishing{
+		MessageId:     message.EventID,
+		CorrelationId: message.CorrelationID,
+		Type:          message.Type,
+		AppId:         message.Producer,
+		Timestamp:     message.Timestamp,
+		ContentType:   r.codec.ContentType(),
+		Body:          body,
+		DeliveryMode:  amqp.Persistent,
+	})
+}
 
-			if err := msg.Ack(false); err != nil {
-				log.Printf("ERROR: failed to ack message %v", err)
-			}
-		}
-	}()
+// publishBody publishes msg within a producer span, whose trace context goes
+// along in the headers of the message.
+func (r *RabbitMQ) publishBody(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) (err error) {
+	log.Printf("publishing message with routing key: %s", routingKey)
+
+	span, headers := startPublishSpan(ctx, exchange, routingKey, msg.MessageId, msg.Headers)
+	defer func() { endSpan(span, err) }()
+	msg.Headers = headers
+
+	if err := r.publishConfirmed(ctx, exchange, routingKey, msg); err != nil {
+		count := publishFailures.Inc(routingKey)
+		log.Printf("failed to publish %s (%d failures): %v", routingKey, count, err)
+		return fmt.Errorf("failed to publish %s: %w", routingKey, err)
+	}
 
 	return nil
 }
 
-func (r *RabbitMQ) PublishMessage(ctx context.Context, routingKey string, message contracts.AmqpMessage) error {
-	log.Printf("publishing message with routing key: %s", routingKey)
+func (r *RabbitMQ) publishConfirmed(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) error {
+	for {
+		publisher, err := r.publishingChannel(ctx)
+		if err != nil {
+			return err
+		}
 
-	msgJson, err := json.Marshal(message)
-	if err != nil {
-		return fmt.Errorf("failed to marshal message: %v", err)
-	}
-
-	return r.Ch.PublishWithContext(ctx,
-		TripExchange, // exchange
-		routingKey,   // routing key
-		false,        // mandatory
-		false,        // immediate
-		amqp.Publishing{
-			ContentType:  "text/plain",
-			Body:         msgJson,
-			DeliveryMode: amqp.Persistent,
-		},
-	)
+		confirmed, err := publisher.publish(ctx, exchange, routingKey, msg)
+		if errors.Is(err, amqp.ErrClosed) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		select {
+		case err := <-confirmed:
+			if errors.Is(err, amqp.ErrClosed) {
+				continue
+			}
+			return err
+		case <-time.After(publishConfirmTimeout):
+			return ErrConfirmTimeout
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 }
 
+// Close closes the connection right away, the messages being handled are
+// redelivered. See Shutdown to let them finish.
 func (r *RabbitMQ) Close() {
+	r.closeOnce.Do(func() {
+		close(r.done)
+		r.cancelHandlers()
+	})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if r.conn != nil {
 		if err := r.conn.Close(); err != nil {
 			return
 		}
 	}
-	if r.Ch != nil {
-		if err := r.Ch.Close(); err != nil {
-			return
-		}
-	}
 }
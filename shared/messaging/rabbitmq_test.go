@@ -0,0 +1,155 @@
+package messaging
+
+import (
+	"context"
+	"ride-sharing/shared/contracts"
+	"slices"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// rabbitTopology routes the trip events to a single queue without retry
+// queues, which the fake broker would not expire, and has the broadcast
+// exchange.
+var rabbitTopology = Topology{
+	Exchanges: []ExchangeSpec{
+		{Name: TripExchange, Kind: amqp.ExchangeTopic},
+		{Name: GatewayBroadcastExchange, Kind: amqp.ExchangeFanout},
+	},
+	Queues: []QueueSpec{{
+		Name:        "trips",
+		Exchange:    TripExchange,
+		RoutingKeys: []string{"trip.event.*"},
+	}},
+}
+
+func newTestRabbitMQ(t *testing.T, server *fakeAMQPServer) *RabbitMQ {
+	t.Helper()
+
+	rmq, err := NewRabbitMQ(server.uri(), "test", WithTopology(rabbitTopology))
+	if err != nil {
+		t.Fatalf("NewRabbitMQ() error = %v", err)
+	}
+	t.Cleanup(rmq.Close)
+
+	return rmq
+}
+
+func TestRabbitMQReconnects(t *testing.T) {
+	// restored once the client is closed, cleanups run last to first
+	config := reconnectConfig
+	t.Cleanup(func() { reconnectConfig = config })
+	reconnectConfig.InitialWait = 10 * time.Millisecond
+
+	server := newFakeAMQPServer(t)
+	rmq := newTestRabbitMQ(t, server)
+
+	received := make(chan amqp.Delivery, 10)
+	err := rmq.ConsumeMessages("trips", func(ctx context.Context, msg amqp.Delivery) error {
+		received <- msg
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ConsumeMessages() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := rmq.PublishMessage(ctx, contracts.TripEventCreated, contracts.AmqpMessage{OwnerID: "rider-1"}); err != nil {
+		t.Fatalf("PublishMessage() error = %v", err)
+	}
+	if msg := receive(t, received); msg.RoutingKey != contracts.TripEventCreated {
+		t.Fatalf("received %s, want %s", msg.RoutingKey, contracts.TripEventCreated)
+	}
+	server.waitFor("the ack", func() bool { return server.acked == 1 })
+
+	// the broker restarts
+	server.dropConnections()
+
+	// publishing waits for the connection to be back
+	if err := rmq.PublishMessage(ctx, contracts.TripEventCreated, contracts.AmqpMessage{OwnerID: "rider-2"}); err != nil {
+		t.Fatalf("PublishMessage() after the connection dropped error = %v", err)
+	}
+	if msg := receive(t, received); msg.RoutingKey != contracts.TripEventCreated {
+		t.Fatalf("received %s after reconnecting, want %s", msg.RoutingKey, contracts.TripEventCreated)
+	}
+	server.waitFor("the second ack", func() bool { return server.acked == 2 })
+
+	if connections, _ := server.state(); connections != 2 {
+		t.Errorf("%d connections, want 2", connections)
+	}
+	for _, call := range []string{
+		"exchange.declare " + TripExchange,
+		"queue.declare trips",
+		"queue.bind trips " + TripExchange + " trip.event.*",
+		"basic.consume trips",
+	} {
+		if n := server.count(call); n != 2 {
+			t.Errorf("%s received %d times, want once per connection", call, n)
+		}
+	}
+}
+
+func TestRabbitMQShutdownDrains(t *testing.T) {
+	server := newFakeAMQPServer(t)
+	rmq := newTestRabbitMQ(t, server)
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	handled := make(chan error, 2)
+	err := rmq.ConsumeMessages("trips", func(ctx context.Context, msg amqp.Delivery) error {
+		started <- struct{}{}
+		<-release
+		handled <- ctx.Err()
+		return nil
+	}, WithConcurrency(1))
+	if err != nil {
+		t.Fatalf("ConsumeMessages() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// the prefetch of 1 leaves the second message in the queue
+	for range 2 {
+		if err := rmq.PublishMessage(ctx, contracts.TripEventCreated, contracts.AmqpMessage{}); err != nil {
+			t.Fatalf("PublishMessage() error = %v", err)
+		}
+	}
+	receive(t, started)
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- rmq.Shutdown(ctx) }()
+	server.waitFor("the consumer cancelled", func() bool { return slices.Contains(server.calls, "basic.cancel trips") })
+
+	select {
+	case err := <-shutdown:
+		t.Fatalf("Shutdown() = %v before the message being handled was", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if err := receive(t, handled); err != nil {
+		t.Errorf("context of the handler in flight error = %v", err)
+	}
+	if err := receive(t, shutdown); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	select {
+	case <-started:
+		t.Error("a message was handled after Shutdown")
+	default:
+	}
+	server.waitFor("the ack of the message in flight", func() bool { return server.acked == 1 })
+	if n := server.ready("trips"); n != 1 {
+		t.Errorf("%d messages left in the queue, want 1", n)
+	}
+
+	if err := rmq.ConsumeMessages("trips", func(context.Context, amqp.Delivery) error { return nil }); err == nil {
+		t.Error("ConsumeMessages() after Shutdown error = nil")
+	}
+}
@@ -0,0 +1,278 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// A message whose handler fails is published to the retry queue of its
+// attempt, whose TTL delays it before it expires back into the consumed queue.
+// After the attempts of the RetryPolicy of the queue the message is rejected:
+// the queue dead-letters it through DeadLetterExchange to its parking queue,
+// where it stays until it is inspected and replayed.
+//
+//	q --fails--> q.retry.<attempt> --TTL--> q
+//	q --fails MaxAttempts times--> dead_letter --> q.parking
+const (
+	DeadLetterExchange = "dead_letter"
+
+	// attempts made to handle a message before it is parked, by default
+	MaxDeliveryAttempts = 5
+	// delay of the first retry by default, doubled for every following one
+	RetryBaseDelay = 2 * time.Second
+
+	// RetryCountHeader counts the failed attempts to handle a message.
+	RetryCountHeader = "x-retry-count"
+	// OriginalRoutingKeyHeader keeps the routing key of a message retried or
+	// replayed through the default exchange, where the queue name replaces it.
+	OriginalRoutingKeyHeader = "x-original-routing-key"
+)
+
+func RetryQueueName(queue string, attempt int) string {
+	return fmt.Sprintf("%s.retry.%d", queue, attempt)
+}
+
+func ParkingQueueName(queue string) string {
+	return queue + ".parking"
+}
+
+// declareRetryQueues declares the retry queues of queue and its parking queue,
+// bound to deadLetterExchange. Retry queues declared before with another TTL
+// are refused by the broker, see MigrateQueues.
+func declareRetryQueues(ch *amqp.Channel, queue string, policy RetryPolicy, deadLetterExchange string) error {
+	for attempt := 1; attempt < policy.MaxAttempts; attempt++ {
+		_, err := ch.QueueDeclare(
+			RetryQueueName(queue, attempt), //name
+			true,                           //durable
+			false,                          //delete when unused
+			false,                          //exclusive
+			false,                          //no-wait
+			retryQueueArguments(queue, policy, attempt),
+		)
+		if isPreconditionFailed(err) {
+			return fmt.Errorf("failed to declare retry queue of %s, declared before with another delay: migrate it with tools/migrate-queues: %v", queue, err)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to declare retry queue of %s: %v", queue, err)
+		}
+	}
+
+	parking, err := ch.QueueDeclare(
+		ParkingQueueName(queue), //name
+		true,                    //durable
+		false,                   //delete when unused
+		false,                   //exclusive
+		false,                   //no-wait
+		nil,                     //arguments
+	)
+	if err != nil {
+		return fmt.Errorf("failed to declare parking queue of %s: %v", queue, err)
+	}
+
+	err = ch.QueueBind(
+		parking.Name,       //queue name
+		queue,              //routing key
+		deadLetterExchange, //exchange
+		false,              //no-wait
+		nil,                //arguments
+	)
+	if err != nil {
+		return fmt.Errorf("failed to bind queue to %s : %v", parking.Name, err)
+	}
+
+	return nil
+}
+
+// queueArguments are the arguments of a queue: with a retry policy, the
+// messages it rejects for good are dead-lettered to its parking queue.
+func queueArguments(spec QueueSpec, deadLetterExchange string) amqp.Table {
+	if spec.Retry == nil {
+		return nil
+	}
+
+	return amqp.Table{
+		"x-dead-letter-exchange":    deadLetterExchange,
+		"x-dead-letter-routing-key": spec.Name,
+	}
+}
+
+// retryQueueArguments are the arguments of the retry queue of an attempt,
+// whose messages expire back into queue after the delay of the attempt.
+func retryQueueArguments(queue string, policy RetryPolicy, attempt int) amqp.Table {
+	return amqp.Table{
+		"x-message-ttl":             policy.retryDelay(attempt).Milliseconds(),
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": queue,
+	}
+}
+
+// retryOrPark handles a message whose handler failed: it is retried after a
+// delay until it failed policy.MaxAttempts times, then parked. Without a
+// policy it is parked right away, or dropped by queues without a parking
+// queue.
+func (r *RabbitMQ) retryOrPark(ctx context.Context, queue string, msg amqp.Delivery, policy *RetryPolicy) {
+	attempt := retryCount(msg) + 1
+
+	if policy == nil || attempt >= policy.MaxAttempts {
+		log.Printf("rejecting message of %s after %d attempts", queue, attempt)
+		if err := msg.Nack(false, false); err != nil {
+			log.Printf("ERROR: failed to nack message %v", err)
+		}
+		return
+	}
+
+	retry := amqp.Publishing{
+		Headers:      copyHeaders(msg.Headers),
+		MessageId:    msg.MessageId,
+		ContentType:  msg.ContentType,
+		Body:         msg.Body,
+		DeliveryMode: amqp.Persistent,
+	}
+	retry.Headers[RetryCountHeader] = int32(attempt)
+	retry.Headers[OriginalRoutingKeyHeader] = msg.RoutingKey
+
+	if err := r.publishConfirmed(ctx, "", RetryQueueName(queue, attempt), retry); err != nil {
+		// parking the message is better than redelivering it right away
+		log.Printf("failed to schedule the retry of a message of %s, parking it: %v", queue, err)
+		if err := msg.Nack(false, false); err != nil {
+			log.Printf("ERROR: failed to nack message %v", err)
+		}
+		return
+	}
+
+	log.Printf("retrying message of %s in %v (attempt %d/%d)", queue, policy.retryDelay(attempt), attempt+1, policy.MaxAttempts)
+	if err := msg.Ack(false); err != nil {
+		log.Printf("ERROR: failed to ack message %v", err)
+	}
+}
+
+// ParkedMessages returns up to limit messages of the parking queue of queue,
+// leaving them parked.
+func (r *RabbitMQ) ParkedMessages(queue string, limit int) ([]amqp.Delivery, error) {
+	var parked []amqp.Delivery
+
+	err := r.withChannel(func(ch *amqp.Channel) error {
+		for len(parked) < limit {
+			msg, ok, err := ch.Get(ParkingQueueName(queue), false)
+			if err != nil {
+				return fmt.Errorf("failed to read parking queue of %s: %v", queue, err)
+			}
+			if !ok {
+				break
+			}
+
+			msg.RoutingKey = originalRoutingKey(msg)
+			parked = append(parked, msg)
+		}
+
+		// closing the channel requeues the messages as well, this keeps the
+		// parking queue intact when the caller reads it again right away
+		if len(parked) > 0 {
+			return parked[len(parked)-1].Nack(true, true)
+		}
+		return nil
+	})
+
+	return parked, err
+}
+
+// ReplayParked moves up to limit parked messages of queue back to queue with
+// a fresh attempt count and returns how many were replayed.
+func (r *RabbitMQ) ReplayParked(ctx context.Context, queue string, limit int) (int, error) {
+	replayed := 0
+
+	err := r.withChannel(func(ch *amqp.Channel) error {
+		for replayed < limit {
+			msg, ok, err := ch.Get(ParkingQueueName(queue), false)
+			if err != nil {
+				return fmt.Errorf("failed to read parking queue of %s: %v", queue, err)
+			}
+			if !ok {
+				return nil
+			}
+
+			replay := amqp.Publishing{
+				Headers:      copyHeaders(msg.Headers),
+				MessageId:    msg.MessageId,
+				ContentType:  msg.ContentType,
+				Body:         msg.Body,
+				DeliveryMode: amqp.Persistent,
+			}
+			replay.Headers[OriginalRoutingKeyHeader] = originalRoutingKey(msg)
+			delete(replay.Headers, RetryCountHeader)
+			delete(replay.Headers, "x-death")
+
+			if err := r.publishConfirmed(ctx, "", queue, replay); err != nil {
+				msg.Nack(false, true)
+				return fmt.Errorf("failed to replay a message of %s: %v", queue, err)
+			}
+			if err := msg.Ack(false); err != nil {
+				return fmt.Errorf("failed to ack a replayed message of %s: %v", queue, err)
+			}
+
+			replayed++
+		}
+
+		return nil
+	})
+
+	return replayed, err
+}
+
+// withChannel runs fn on a channel of its own, closed afterwards.
+func (r *RabbitMQ) withChannel(fn func(ch *amqp.Channel) error) error {
+	r.mu.Lock()
+	conn := r.conn
+	r.mu.Unlock()
+
+	if conn == nil {
+		return fmt.Errorf("rabbitmq not connected")
+	}
+
+	return withConnectionChannel(conn, fn)
+}
+
+func retryCount(msg amqp.Delivery) int {
+	switch count := msg.Headers[RetryCountHeader].(type) {
+	case int32:
+		return int(count)
+	case int64:
+		return int(count)
+	case int:
+		return count
+	}
+	return 0
+}
+
+// originalRoutingKey is the routing key the message was first published with,
+// retries and dead-lettering replace it with queue names.
+func originalRoutingKey(msg amqp.Delivery) string {
+	if key, ok := msg.Headers[OriginalRoutingKeyHeader].(string); ok {
+		return key
+	}
+
+	// dead-lettered before its first retry, e.g. by a queue length limit
+	if deaths, ok := msg.Headers["x-death"].([]any); ok && len(deaths) > 0 {
+		if death, ok := deaths[len(deaths)-1].(amqp.Table); ok {
+			if keys, ok := death["routing-keys"].([]any); ok && len(keys) > 0 {
+				if key, ok := keys[0].(string); ok {
+					return key
+				}
+			}
+		}
+	}
+
+	return msg.RoutingKey
+}
+
+func copyHeaders(headers amqp.Table) amqp.Table {
+	copied := make(amqp.Table, len(headers)+2)
+	for k, v := range headers {
+		copied[k] = v
+	}
+	return copied
+}
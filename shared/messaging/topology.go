@@ -0,0 +1,286 @@
+package messaging
+
+import (
+	"fmt"
+	"ride-sharing/shared/contracts"
+	"slices"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Topology describes the exchanges and queues of the services: which
+// messages each queue receives, who consumes and publishes them and how
+// failed messages are retried. Clients declare it when they connect, see
+// WithTopology, and tools/topology-diagram draws it.
+type Topology struct {
+	Exchanges []ExchangeSpec
+	Queues    []QueueSpec
+	// the messages each service publishes
+	Publishers []PublisherSpec
+	// exchange routing the messages rejected for good to the parking queues
+	DeadLetterExchange string
+}
+
+type ExchangeSpec struct {
+	Name string
+	// topic, fanout or direct
+	Kind string
+}
+
+type QueueSpec struct {
+	Name     string
+	Exchange string
+	// binding patterns of the queue on Exchange
+	RoutingKeys []string
+	// services consuming the queue
+	Consumers []string
+	// nil for queues without retry queues, whose failed messages are dropped
+	Retry *RetryPolicy
+	// declared by each replica of the consumers under a name of its own, see
+	// DeclareBroadcastQueue, and not when the topology is declared
+	PerReplica bool
+}
+
+type PublisherSpec struct {
+	Service  string
+	Exchange string
+	// nil for a publisher forwarding messages of any routing key
+	RoutingKeys []string
+}
+
+// RetryPolicy of a queue: a message whose handler fails is published to the
+// retry queue of its attempt, whose TTL delays it before it expires back into
+// the queue, until it is parked after MaxAttempts attempts.
+type RetryPolicy struct {
+	MaxAttempts int
+	// delay of the first retry, doubled for every following one
+	BaseDelay time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: MaxDeliveryAttempts,
+	BaseDelay:   RetryBaseDelay,
+}
+
+// retryDelay is the time a message waits before its attempt+1th delivery.
+func (p RetryPolicy) retryDelay(attempt int) time.Duration {
+	return p.BaseDelay << (attempt - 1)
+}
+
+// service names, also the producers of the events they publish
+const (
+	APIGatewayService = "api-gateway"
+	DriverService     = "driver-service"
+	TripService       = "trip-service"
+)
+
+// DefaultTopology is the topology of the ride-sharing services.
+var DefaultTopology = Topology{
+	Exchanges: []ExchangeSpec{
+		{Name: TripExchange, Kind: amqp.ExchangeTopic},
+		{Name: PaymentExchange, Kind: amqp.ExchangeTopic},
+		{Name: GatewayBroadcastExchange, Kind: amqp.ExchangeFanout},
+		{Name: DeadLetterExchange, Kind: amqp.ExchangeDirect},
+	},
+	Queues: []QueueSpec{
+		{
+			Name:        FindAvailableDriversQueue,
+			Exchange:    TripExchange,
+			RoutingKeys: []string{contracts.TripEventCreated, contracts.TripEventDriverNotInterested},
+			Consumers:   []string{DriverService},
+			Retry:       &DefaultRetryPolicy,
+		},
+		{
+			Name:        DriverOfferOutcomesQueue,
+			Exchange:    TripExchange,
+			RoutingKeys: []string{contracts.DriverCmdTripAccept, contracts.DriverCmdTripDecline, contracts.DriverCmdTripCancel},
+			Consumers:   []string{DriverService},
+			Retry:       &DefaultRetryPolicy,
+		},
+		{
+			// a location is outdated by the next one, failed updates are not
+			// retried
+			Name:        DriverLocationQueue,
+			Exchange:    TripExchange,
+			RoutingKeys: []string{contracts.DriverCmdLocation},
+			Consumers:   []string{DriverService},
+		},
+		{
+			Name:        NotifyNewTripQueue,
+			Exchange:    TripExchange,
+			RoutingKeys: []string{contracts.TripEventCreated},
+			Consumers:   []string{APIGatewayService},
+			Retry:       &DefaultRetryPolicy,
+		},
+		{
+			Name:        NotifyDriverAssignmentQueue,
+			Exchange:    TripExchange,
+			RoutingKeys: []string{contracts.TripEventDriverAssigned},
+			Consumers:   []string{APIGatewayService},
+			Retry:       &DefaultRetryPolicy,
+		},
+		{
+			Name:        NotifyDriverNoDriversFoundQueue,
+			Exchange:    TripExchange,
+			RoutingKeys: []string{contracts.TripEventNoDriversFound},
+			Consumers:   []string{APIGatewayService},
+			Retry:       &DefaultRetryPolicy,
+		},
+		{
+			Name:        DriverCmdTripRequestQueue,
+			Exchange:    TripExchange,
+			RoutingKeys: []string{contracts.DriverCmdTripRequest},
+			Consumers:   []string{APIGatewayService},
+			Retry:       &DefaultRetryPolicy,
+		},
+		{
+			Name:        NotifyPaymentStatusQueue,
+			Exchange:    PaymentExchange,
+			RoutingKeys: []string{contracts.PaymentEventSessionCreated},
+			Consumers:   []string{APIGatewayService},
+			Retry:       &DefaultRetryPolicy,
+		},
+		{
+			Name:       GatewayBroadcastExchange + ".*",
+			Exchange:   GatewayBroadcastExchange,
+			Consumers:  []string{APIGatewayService},
+			PerReplica: true,
+		},
+	},
+	Publishers: []PublisherSpec{
+		{
+			Service:     TripService,
+			Exchange:    TripExchange,
+			RoutingKeys: []string{contracts.TripEventCreated},
+		},
+		{
+			Service:     DriverService,
+			Exchange:    TripExchange,
+			RoutingKeys: []string{contracts.TripEventNoDriversFound, contracts.DriverCmdTripRequest},
+		},
+		{
+			Service:  APIGatewayService,
+			Exchange: TripExchange,
+			RoutingKeys: []string{
+				contracts.DriverCmdTripAccept, contracts.DriverCmdTripDecline, contracts.DriverCmdTripCancel,
+				contracts.DriverCmdLocation,
+			},
+		},
+		{
+			Service:  APIGatewayService,
+			Exchange: GatewayBroadcastExchange,
+		},
+	},
+	DeadLetterExchange: DeadLetterExchange,
+}
+
+// For returns the part of the topology service needs: the queues it consumes,
+// and the queues receiving what it publishes so that its messages are not
+// unroutable when it starts before their consumers.
+func (t Topology) For(service string) Topology {
+	partial := Topology{DeadLetterExchange: t.DeadLetterExchange}
+
+	var published []PublisherSpec
+	for _, p := range t.Publishers {
+		if p.Service == service {
+			published = append(published, p)
+			partial.Publishers = append(partial.Publishers, p)
+		}
+	}
+
+	exchanges := make(map[string]bool)
+	for _, q := range t.Queues {
+		if !slices.Contains(q.Consumers, service) && !receivesAny(q, published) {
+			continue
+		}
+
+		partial.Queues = append(partial.Queues, q)
+		exchanges[q.Exchange] = true
+		if q.Retry != nil {
+			exchanges[t.DeadLetterExchange] = true
+		}
+	}
+	for _, p := range published {
+		exchanges[p.Exchange] = true
+	}
+
+	for _, e := range t.Exchanges {
+		if exchanges[e.Name] {
+			partial.Exchanges = append(partial.Exchanges, e)
+		}
+	}
+
+	return partial
+}
+
+// receivesAny tells whether q is bound to receive a message of publishers.
+func receivesAny(q QueueSpec, publishers []PublisherSpec) bool {
+	for _, p := range publishers {
+		if p.Exchange != q.Exchange {
+			continue
+		}
+		// a fanout exchange or a publisher of any routing key
+		if p.RoutingKeys == nil || q.RoutingKeys == nil {
+			return true
+		}
+		for _, key := range p.RoutingKeys {
+			for _, pattern := range q.RoutingKeys {
+				if topicMatches(pattern, key) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// retryPolicy returns the retry policy of the queue named name, nil for the
+// queues without retry queues.
+func (t Topology) retryPolicy(name string) *RetryPolicy {
+	for _, q := range t.Queues {
+		if q.Name == name {
+			return q.Retry
+		}
+	}
+	return nil
+}
+
+// declareTopology declares the exchanges of t, then its queues with their
+// retry and parking queues.
+func declareTopology(ch *amqp.Channel, t Topology) error {
+	if err := declareExchanges(ch, t); err != nil {
+		return err
+	}
+
+	for _, q := range t.Queues {
+		if q.PerReplica {
+			continue
+		}
+		if err := declareAndBindQueue(ch, q, t.DeadLetterExchange); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func declareExchanges(ch *amqp.Channel, t Topology) error {
+	for _, e := range t.Exchanges {
+		err := ch.ExchangeDeclare(
+			e.Name, //name
+			e.Kind, //type
+			true,   //durable
+			false,  //auto-deleted
+			false,  //internal
+			false,  //no-wait
+			nil,    //arguments
+		)
+		if err != nil {
+			return fmt.Errorf("failed to declare exchange %v : %v", e.Name, err)
+		}
+	}
+
+	return nil
+}
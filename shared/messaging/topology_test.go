@@ -0,0 +1,108 @@
+package messaging
+
+import (
+	"context"
+	"reflect"
+	"ride-sharing/shared/contracts"
+	"slices"
+	"testing"
+)
+
+func TestTopologyFor(t *testing.T) {
+	tests := []struct {
+		service       string
+		wantExchanges []string
+		wantQueues    []string
+	}{
+		{
+			service:       TripService,
+			wantExchanges: []string{TripExchange, DeadLetterExchange},
+			// the created events it publishes
+			wantQueues: []string{FindAvailableDriversQueue, NotifyNewTripQueue},
+		},
+		{
+			service:       DriverService,
+			wantExchanges: []string{TripExchange, DeadLetterExchange},
+			wantQueues: []string{
+				// consumed
+				FindAvailableDriversQueue, DriverOfferOutcomesQueue, DriverLocationQueue,
+				// published to
+				NotifyDriverNoDriversFoundQueue, DriverCmdTripRequestQueue,
+			},
+		},
+		{
+			service:       APIGatewayService,
+			wantExchanges: []string{TripExchange, PaymentExchange, GatewayBroadcastExchange, DeadLetterExchange},
+			wantQueues: []string{
+				// published to
+				DriverOfferOutcomesQueue, DriverLocationQueue,
+				// consumed
+				NotifyNewTripQueue, NotifyDriverAssignmentQueue, NotifyDriverNoDriversFoundQueue,
+				DriverCmdTripRequestQueue, NotifyPaymentStatusQueue, GatewayBroadcastExchange + ".*",
+			},
+		},
+		{
+			service: "unknown-service",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.service, func(t *testing.T) {
+			got := DefaultTopology.For(tt.service)
+
+			var exchanges, queues []string
+			for _, e := range got.Exchanges {
+				exchanges = append(exchanges, e.Name)
+			}
+			for _, q := range got.Queues {
+				queues = append(queues, q.Name)
+
+				// bound as in the whole topology
+				i := slices.IndexFunc(DefaultTopology.Queues, func(spec QueueSpec) bool { return spec.Name == q.Name })
+				if !reflect.DeepEqual(q, DefaultTopology.Queues[i]) {
+					t.Errorf("queue %s = %+v, want %+v", q.Name, q, DefaultTopology.Queues[i])
+				}
+			}
+
+			if !slices.Equal(exchanges, tt.wantExchanges) {
+				t.Errorf("exchanges = %v, want %v", exchanges, tt.wantExchanges)
+			}
+			if !slices.Equal(queues, tt.wantQueues) {
+				t.Errorf("queues = %v, want %v", queues, tt.wantQueues)
+			}
+			for _, p := range got.Publishers {
+				if p.Service != tt.service {
+					t.Errorf("publishers include those of %s", p.Service)
+				}
+			}
+			if got.DeadLetterExchange != DeadLetterExchange {
+				t.Errorf("DeadLetterExchange = %q, want %q", got.DeadLetterExchange, DeadLetterExchange)
+			}
+		})
+	}
+}
+
+// TestTopologyForRoutesPublishedMessages publishes what each service does on a
+// broker declaring the topology of the service only: no message may be
+// unroutable.
+func TestTopologyForRoutesPublishedMessages(t *testing.T) {
+	published := map[string][]string{
+		TripService:   {contracts.TripEventCreated},
+		DriverService: {contracts.TripEventNoDriversFound, contracts.DriverCmdTripRequest},
+		APIGatewayService: {
+			contracts.DriverCmdTripAccept, contracts.DriverCmdTripDecline, contracts.DriverCmdTripCancel,
+			contracts.DriverCmdLocation,
+		},
+	}
+
+	for service, routingKeys := range published {
+		broker := NewMemoryBroker(service, WithTopology(DefaultTopology.For(service)))
+		defer broker.Close()
+
+		for _, key := range routingKeys {
+			if err := broker.PublishMessage(context.Background(), key, contracts.AmqpMessage{}); err != nil {
+				t.Errorf("%s: PublishMessage(%s) error = %v", service, key, err)
+			}
+		}
+	}
+}
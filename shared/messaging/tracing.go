@@ -0,0 +1,87 @@
+package messaging
+
+import (
+	"context"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "ride-sharing/shared/messaging"
+
+// headerCarrier carries the trace context in the headers of a message, as
+// the traceparent and tracestate headers of W3C Trace Context.
+type headerCarrier amqp.Table
+
+func (c headerCarrier) Get(key string) string {
+	value, _ := c[key].(string)
+	return value
+}
+
+func (c headerCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// startPublishSpan starts the producer span of a message and returns headers
+// with its context injected, for the consumer span to continue the trace. A
+// message forwarded without a span in ctx continues the trace of its headers.
+func startPublishSpan(ctx context.Context, exchange, routingKey, messageID string, headers amqp.Table) (trace.Span, amqp.Table) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, headerCarrier(headers))
+	}
+
+	ctx, span := otel.Tracer(tracerName).Start(ctx, routingKey+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemRabbitmq,
+			semconv.MessagingOperationTypePublish,
+			semconv.MessagingDestinationName(exchange),
+			semconv.MessagingRabbitmqDestinationRoutingKey(routingKey),
+			semconv.MessagingMessageID(messageID),
+		),
+	)
+
+	headers = copyHeaders(headers)
+	otel.GetTextMapPropagator().Inject(ctx, headerCarrier(headers))
+
+	return span, headers
+}
+
+// startConsumeSpan starts the consumer span of a message handled from queue,
+// a child of the producer span of the message.
+func startConsumeSpan(ctx context.Context, queue string, msg amqp.Delivery) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, headerCarrier(msg.Headers))
+
+	return otel.Tracer(tracerName).Start(ctx, queue+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemRabbitmq,
+			semconv.MessagingOperationTypeDeliver,
+			semconv.MessagingDestinationName(msg.Exchange),
+			semconv.MessagingRabbitmqDestinationRoutingKey(msg.RoutingKey),
+			semconv.MessagingMessageID(msg.MessageId),
+			attribute.String("messaging.rabbitmq.queue", queue),
+			attribute.Int("messaging.rabbitmq.retry_count", retryCount(msg)),
+		),
+	)
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
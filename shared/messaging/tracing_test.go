@@ -0,0 +1,140 @@
+package messaging
+
+import (
+	"context"
+	"ride-sharing/shared/contracts"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans records the spans started during the test and propagates their
+// context as W3C Trace Context, like shared/tracing sets the services up.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+		provider.Shutdown(context.Background())
+	})
+
+	return recorder
+}
+
+func TestHeaderCarrierRoundTrip(t *testing.T) {
+	recordSpans(t)
+
+	ctx, span := otel.Tracer(tracerName).Start(context.Background(), "publisher")
+	defer span.End()
+
+	headers := amqp.Table{"x-retry-count": int32(1)}
+	otel.GetTextMapPropagator().Inject(ctx, headerCarrier(headers))
+	if _, ok := headers["traceparent"].(string); !ok {
+		t.Fatalf("headers = %v, want a traceparent", headers)
+	}
+	if headers["x-retry-count"] != int32(1) {
+		t.Errorf("headers = %v, injecting dropped the other headers", headers)
+	}
+
+	got := trace.SpanContextFromContext(otel.GetTextMapPropagator().Extract(context.Background(), headerCarrier(headers)))
+	if want := span.SpanContext(); got.TraceID() != want.TraceID() || got.SpanID() != want.SpanID() || !got.IsRemote() {
+		t.Errorf("extracted span context %v, want the remote %v", got, want)
+	}
+}
+
+type tracedDelivery struct {
+	span trace.SpanContext
+	msg  amqp.Delivery
+}
+
+// TestTraceContinuesToConsumers checks the consumer span of a message is a
+// child of its producer span, and that a message broadcast to the gateway
+// replicas stays in the trace.
+func TestTraceContinuesToConsumers(t *testing.T) {
+	brokers := map[string]func(t *testing.T) Broker{
+		"memory": func(t *testing.T) Broker {
+			broker := NewMemoryBroker("test", WithTopology(rabbitTopology))
+			t.Cleanup(broker.Close)
+			return broker
+		},
+		"rabbitmq": func(t *testing.T) Broker {
+			return newTestRabbitMQ(t, newFakeAMQPServer(t))
+		},
+	}
+
+	for name, newBroker := range brokers {
+		t.Run(name, func(t *testing.T) {
+			recorder := recordSpans(t)
+			broker := newBroker(t)
+
+			consumed := make(chan tracedDelivery, 1)
+			broadcast := make(chan tracedDelivery, 1)
+			consume := func(queue string, to chan tracedDelivery) {
+				err := broker.ConsumeMessages(queue, func(ctx context.Context, msg amqp.Delivery) error {
+					to <- tracedDelivery{span: trace.SpanContextFromContext(ctx), msg: msg}
+					return nil
+				})
+				if err != nil {
+					t.Fatalf("ConsumeMessages(%s) error = %v", queue, err)
+				}
+			}
+			consume("trips", consumed)
+			broadcastQueue, err := broker.DeclareBroadcastQueue()
+			if err != nil {
+				t.Fatalf("DeclareBroadcastQueue() error = %v", err)
+			}
+			consume(broadcastQueue, broadcast)
+
+			ctx, request := otel.Tracer(tracerName).Start(context.Background(), "request")
+			if err := broker.PublishMessage(ctx, contracts.TripEventCreated, contracts.AmqpMessage{}); err != nil {
+				t.Fatalf("PublishMessage() error = %v", err)
+			}
+			request.End()
+			traceID := request.SpanContext().TraceID()
+
+			got := receive(t, consumed)
+			publish, process := startedSpan(recorder, contracts.TripEventCreated+" publish"), startedSpan(recorder, "trips process")
+			if publish == nil || process == nil {
+				t.Fatal("publish or process span not recorded")
+			}
+			if got.span.SpanID() != process.SpanContext().SpanID() {
+				t.Errorf("handler context carries span %v, want the process span %v", got.span.SpanID(), process.SpanContext().SpanID())
+			}
+			if process.Parent().SpanID() != publish.SpanContext().SpanID() || process.SpanContext().TraceID() != traceID {
+				t.Errorf("process span parent = %v in trace %v, want the publish span %v in trace %v",
+					process.Parent().SpanID(), process.SpanContext().TraceID(), publish.SpanContext().SpanID(), traceID)
+			}
+
+			// without the context of the handler, the trace only goes along
+			// in the headers
+			if err := broker.BroadcastDelivery(context.Background(), got.msg); err != nil {
+				t.Fatalf("BroadcastDelivery() error = %v", err)
+			}
+			if got := receive(t, broadcast); got.span.TraceID() != traceID {
+				t.Errorf("broadcast handled in trace %v, want %v", got.span.TraceID(), traceID)
+			}
+		})
+	}
+}
+
+// startedSpan returns the span named name, nil if none was started.
+func startedSpan(recorder *tracetest.SpanRecorder, name string) sdktrace.ReadWriteSpan {
+	for _, span := range recorder.Started() {
+		if span.Name() == name {
+			return span
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+/*
+Package metrics keeps the counters of the services and serves them on /metrics
+in the Prometheus text format, so they can be scraped without pulling a
+metrics SDK into every service.
+*/
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	registryMu sync.Mutex
+	registry   []*Counter
+)
+
+// Counter is a monotonic count per value of a single label.
+type Counter struct {
+	name  string
+	help  string
+	label string
+
+	mu     sync.Mutex
+	values map[string]int64
+}
+
+// NewCounter registers a counter served by Handler. Counters are meant to be
+// package variables, registering a name twice panics.
+func NewCounter(name, help, label string) *Counter {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for _, c := range registry {
+		if c.name == name {
+			panic(fmt.Sprintf("metrics: counter %s registered twice", name))
+		}
+	}
+
+	c := &Counter{
+		name:   name,
+		help:   help,
+		label:  label,
+		values: make(map[string]int64),
+	}
+	registry = append(registry, c)
+
+	return c
+}
+
+// Inc adds one to the count of labelValue and returns it.
+func (c *Counter) Inc(labelValue string) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.values[labelValue]++
+	return c.values[labelValue]
+}
+
+// Values returns the counts per label value since the process started.
+func (c *Counter) Values() map[string]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	values := make(map[string]int64, len(c.values))
+	for value, count := range c.values {
+		values[value] = count
+	}
+
+	return values
+}
+
+func (c *Counter) write(w io.Writer) {
+	values := c.Values()
+	labelValues := make([]string, 0, len(values))
+	for value := range values {
+		labelValues = append(labelValues, value)
+	}
+	slices.Sort(labelValues)
+
+	fmt.Fprintf(w, "# HELP %s %s\n", c.name, helpEscaper.Replace(c.help))
+	fmt.Fprintf(w, "# TYPE %s counter\n", c.name)
+	for _, value := range labelValues {
+		fmt.Fprintf(w, "%s{%s=\"%s\"} %d\n", c.name, c.label, labelEscaper.Replace(value), values[value])
+	}
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// Handler serves every registered counter.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		registryMu.Lock()
+		counters := slices.Clone(registry)
+		registryMu.Unlock()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		for _, c := range counters {
+			c.write(w)
+		}
+	})
+}
+
+// ListenAndServe serves Handler on addr for the services without an HTTP
+// server of their own. It only returns on failure.
+func ListenAndServe(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", Handler())
+
+	log.Printf("serving metrics on %s", addr)
+	return http.ListenAndServe(addr, mux)
+}
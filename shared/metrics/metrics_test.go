@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// counters register once per process, tests may run several times
+var (
+	testCounter        = NewCounter("test_counter_total", "Counted things.", "kind")
+	testHandlerCounter = NewCounter("test_handler_total", "Lines\nof help.", "key")
+)
+
+func TestCounter(t *testing.T) {
+	c := testCounter
+	before := c.Values()["a"]
+
+	c.Inc("a")
+	if got := c.Inc("a"); got != before+2 {
+		t.Errorf("Inc(a) = %d, want %d", got, before+2)
+	}
+
+	values := c.Values()
+	values["a"] = 0
+	if c.Values()["a"] != before+2 {
+		t.Error("Values() shares its map with the counter")
+	}
+}
+
+func TestNewCounterTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewCounter() of a registered name did not panic")
+		}
+	}()
+	NewCounter("test_counter_total", "", "kind")
+}
+
+func TestHandler(t *testing.T) {
+	c := testHandlerCounter
+	c.Inc("trip.event.created")
+	c.Inc(`say "hi"`)
+	values := c.Values()
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	want := strings.Join([]string{
+		`# HELP test_handler_total Lines\nof help.`,
+		`# TYPE test_handler_total counter`,
+		fmt.Sprintf(`test_handler_total{key="say \"hi\""} %d`, values[`say "hi"`]),
+		fmt.Sprintf(`test_handler_total{key="trip.event.created"} %d`, values["trip.event.created"]),
+	}, "\n") + "\n"
+	if body := rec.Body.String(); !strings.Contains(body, want) {
+		t.Errorf("Handler() served\n%s\nwant it to contain\n%s", body, want)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %q, want text/plain", ct)
+	}
+}
@@ -117,6 +117,810 @@ func (x *RegisterDriverResponse) GetDriver() *Driver {
 	return nil
 }
 
+type HeartbeatRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DriverID      string                 `protobuf:"bytes,1,opt,name=driverID,proto3" json:"driverID,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HeartbeatRequest) Reset() {
+	*x = HeartbeatRequest{}
+	mi := &file_driver_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HeartbeatRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatRequest) ProtoMessage() {}
+
+func (x *HeartbeatRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatRequest.ProtoReflect.Descriptor instead.
+func (*HeartbeatRequest) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *HeartbeatRequest) GetDriverID() string {
+	if x != nil {
+		return x.DriverID
+	}
+	return ""
+}
+
+type HeartbeatResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	LastSeenAt    int64                  `protobuf:"varint,1,opt,name=lastSeenAt,proto3" json:"lastSeenAt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HeartbeatResponse) Reset() {
+	*x = HeartbeatResponse{}
+	mi := &file_driver_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HeartbeatResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatResponse) ProtoMessage() {}
+
+func (x *HeartbeatResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatResponse.ProtoReflect.Descriptor instead.
+func (*HeartbeatResponse) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *HeartbeatResponse) GetLastSeenAt() int64 {
+	if x != nil {
+		return x.LastSeenAt
+	}
+	return 0
+}
+
+type DriverProfileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Profile       *DriverProfile         `protobuf:"bytes,1,opt,name=profile,proto3" json:"profile,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DriverProfileRequest) Reset() {
+	*x = DriverProfileRequest{}
+	mi := &file_driver_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DriverProfileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DriverProfileRequest) ProtoMessage() {}
+
+func (x *DriverProfileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DriverProfileRequest.ProtoReflect.Descriptor instead.
+func (*DriverProfileRequest) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *DriverProfileRequest) GetProfile() *DriverProfile {
+	if x != nil {
+		return x.Profile
+	}
+	return nil
+}
+
+type GetDriverProfileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DriverID      string                 `protobuf:"bytes,1,opt,name=driverID,proto3" json:"driverID,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDriverProfileRequest) Reset() {
+	*x = GetDriverProfileRequest{}
+	mi := &file_driver_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDriverProfileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDriverProfileRequest) ProtoMessage() {}
+
+func (x *GetDriverProfileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDriverProfileRequest.ProtoReflect.Descriptor instead.
+func (*GetDriverProfileRequest) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetDriverProfileRequest) GetDriverID() string {
+	if x != nil {
+		return x.DriverID
+	}
+	return ""
+}
+
+type DriverProfileResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Profile       *DriverProfile         `protobuf:"bytes,1,opt,name=profile,proto3" json:"profile,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DriverProfileResponse) Reset() {
+	*x = DriverProfileResponse{}
+	mi := &file_driver_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DriverProfileResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DriverProfileResponse) ProtoMessage() {}
+
+func (x *DriverProfileResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DriverProfileResponse.ProtoReflect.Descriptor instead.
+func (*DriverProfileResponse) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DriverProfileResponse) GetProfile() *DriverProfile {
+	if x != nil {
+		return x.Profile
+	}
+	return nil
+}
+
+type DriverProfile struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name           string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	ProfilePicture string                 `protobuf:"bytes,3,opt,name=profilePicture,proto3" json:"profilePicture,omitempty"`
+	PhoneNumber    string                 `protobuf:"bytes,4,opt,name=phoneNumber,proto3" json:"phoneNumber,omitempty"`
+	LicenseNumber  string                 `protobuf:"bytes,5,opt,name=licenseNumber,proto3" json:"licenseNumber,omitempty"`
+	Vehicles       []*Vehicle             `protobuf:"bytes,6,rep,name=vehicles,proto3" json:"vehicles,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *DriverProfile) Reset() {
+	*x = DriverProfile{}
+	mi := &file_driver_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DriverProfile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DriverProfile) ProtoMessage() {}
+
+func (x *DriverProfile) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DriverProfile.ProtoReflect.Descriptor instead.
+func (*DriverProfile) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DriverProfile) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *DriverProfile) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *DriverProfile) GetProfilePicture() string {
+	if x != nil {
+		return x.ProfilePicture
+	}
+	return ""
+}
+
+func (x *DriverProfile) GetPhoneNumber() string {
+	if x != nil {
+		return x.PhoneNumber
+	}
+	return ""
+}
+
+func (x *DriverProfile) GetLicenseNumber() string {
+	if x != nil {
+		return x.LicenseNumber
+	}
+	return ""
+}
+
+func (x *DriverProfile) GetVehicles() []*Vehicle {
+	if x != nil {
+		return x.Vehicles
+	}
+	return nil
+}
+
+type Vehicle struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Plate         string                 `protobuf:"bytes,1,opt,name=plate,proto3" json:"plate,omitempty"`
+	Make          string                 `protobuf:"bytes,2,opt,name=make,proto3" json:"make,omitempty"`
+	Model         string                 `protobuf:"bytes,3,opt,name=model,proto3" json:"model,omitempty"`
+	Color         string                 `protobuf:"bytes,4,opt,name=color,proto3" json:"color,omitempty"`
+	Seats         int32                  `protobuf:"varint,5,opt,name=seats,proto3" json:"seats,omitempty"`
+	PackageSlugs  []string               `protobuf:"bytes,6,rep,name=packageSlugs,proto3" json:"packageSlugs,omitempty"`
+	Approved      bool                   `protobuf:"varint,7,opt,name=approved,proto3" json:"approved,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Vehicle) Reset() {
+	*x = Vehicle{}
+	mi := &file_driver_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Vehicle) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Vehicle) ProtoMessage() {}
+
+func (x *Vehicle) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Vehicle.ProtoReflect.Descriptor instead.
+func (*Vehicle) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Vehicle) GetPlate() string {
+	if x != nil {
+		return x.Plate
+	}
+	return ""
+}
+
+func (x *Vehicle) GetMake() string {
+	if x != nil {
+		return x.Make
+	}
+	return ""
+}
+
+func (x *Vehicle) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *Vehicle) GetColor() string {
+	if x != nil {
+		return x.Color
+	}
+	return ""
+}
+
+func (x *Vehicle) GetSeats() int32 {
+	if x != nil {
+		return x.Seats
+	}
+	return 0
+}
+
+func (x *Vehicle) GetPackageSlugs() []string {
+	if x != nil {
+		return x.PackageSlugs
+	}
+	return nil
+}
+
+func (x *Vehicle) GetApproved() bool {
+	if x != nil {
+		return x.Approved
+	}
+	return false
+}
+
+type RateDriverRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DriverID      string                 `protobuf:"bytes,1,opt,name=driverID,proto3" json:"driverID,omitempty"`
+	TripID        string                 `protobuf:"bytes,2,opt,name=tripID,proto3" json:"tripID,omitempty"`
+	RiderID       string                 `protobuf:"bytes,3,opt,name=riderID,proto3" json:"riderID,omitempty"`
+	Rating        int32                  `protobuf:"varint,4,opt,name=rating,proto3" json:"rating,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RateDriverRequest) Reset() {
+	*x = RateDriverRequest{}
+	mi := &file_driver_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RateDriverRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RateDriverRequest) ProtoMessage() {}
+
+func (x *RateDriverRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RateDriverRequest.ProtoReflect.Descriptor instead.
+func (*RateDriverRequest) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *RateDriverRequest) GetDriverID() string {
+	if x != nil {
+		return x.DriverID
+	}
+	return ""
+}
+
+func (x *RateDriverRequest) GetTripID() string {
+	if x != nil {
+		return x.TripID
+	}
+	return ""
+}
+
+func (x *RateDriverRequest) GetRiderID() string {
+	if x != nil {
+		return x.RiderID
+	}
+	return ""
+}
+
+func (x *RateDriverRequest) GetRating() int32 {
+	if x != nil {
+		return x.Rating
+	}
+	return 0
+}
+
+type GetDriverStatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DriverID      string                 `protobuf:"bytes,1,opt,name=driverID,proto3" json:"driverID,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDriverStatsRequest) Reset() {
+	*x = GetDriverStatsRequest{}
+	mi := &file_driver_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDriverStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDriverStatsRequest) ProtoMessage() {}
+
+func (x *GetDriverStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDriverStatsRequest.ProtoReflect.Descriptor instead.
+func (*GetDriverStatsRequest) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetDriverStatsRequest) GetDriverID() string {
+	if x != nil {
+		return x.DriverID
+	}
+	return ""
+}
+
+type DriverStatsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Stats         *DriverStats           `protobuf:"bytes,1,opt,name=stats,proto3" json:"stats,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DriverStatsResponse) Reset() {
+	*x = DriverStatsResponse{}
+	mi := &file_driver_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DriverStatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DriverStatsResponse) ProtoMessage() {}
+
+func (x *DriverStatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DriverStatsResponse.ProtoReflect.Descriptor instead.
+func (*DriverStatsResponse) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *DriverStatsResponse) GetStats() *DriverStats {
+	if x != nil {
+		return x.Stats
+	}
+	return nil
+}
+
+type DriverStats struct {
+	state                  protoimpl.MessageState `protogen:"open.v1"`
+	DriverID               string                 `protobuf:"bytes,1,opt,name=driverID,proto3" json:"driverID,omitempty"`
+	AverageRating          float64                `protobuf:"fixed64,2,opt,name=averageRating,proto3" json:"averageRating,omitempty"`
+	RatingCount            int32                  `protobuf:"varint,3,opt,name=ratingCount,proto3" json:"ratingCount,omitempty"`
+	Offers                 int32                  `protobuf:"varint,4,opt,name=offers,proto3" json:"offers,omitempty"`
+	Accepted               int32                  `protobuf:"varint,5,opt,name=accepted,proto3" json:"accepted,omitempty"`
+	Declined               int32                  `protobuf:"varint,6,opt,name=declined,proto3" json:"declined,omitempty"`
+	Cancelled              int32                  `protobuf:"varint,7,opt,name=cancelled,proto3" json:"cancelled,omitempty"`
+	AcceptanceRate         float64                `protobuf:"fixed64,8,opt,name=acceptanceRate,proto3" json:"acceptanceRate,omitempty"`
+	CancellationRate       float64                `protobuf:"fixed64,9,opt,name=cancellationRate,proto3" json:"cancellationRate,omitempty"`
+	AverageResponseSeconds float64                `protobuf:"fixed64,10,opt,name=averageResponseSeconds,proto3" json:"averageResponseSeconds,omitempty"`
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *DriverStats) Reset() {
+	*x = DriverStats{}
+	mi := &file_driver_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DriverStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DriverStats) ProtoMessage() {}
+
+func (x *DriverStats) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DriverStats.ProtoReflect.Descriptor instead.
+func (*DriverStats) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *DriverStats) GetDriverID() string {
+	if x != nil {
+		return x.DriverID
+	}
+	return ""
+}
+
+func (x *DriverStats) GetAverageRating() float64 {
+	if x != nil {
+		return x.AverageRating
+	}
+	return 0
+}
+
+func (x *DriverStats) GetRatingCount() int32 {
+	if x != nil {
+		return x.RatingCount
+	}
+	return 0
+}
+
+func (x *DriverStats) GetOffers() int32 {
+	if x != nil {
+		return x.Offers
+	}
+	return 0
+}
+
+func (x *DriverStats) GetAccepted() int32 {
+	if x != nil {
+		return x.Accepted
+	}
+	return 0
+}
+
+func (x *DriverStats) GetDeclined() int32 {
+	if x != nil {
+		return x.Declined
+	}
+	return 0
+}
+
+func (x *DriverStats) GetCancelled() int32 {
+	if x != nil {
+		return x.Cancelled
+	}
+	return 0
+}
+
+func (x *DriverStats) GetAcceptanceRate() float64 {
+	if x != nil {
+		return x.AcceptanceRate
+	}
+	return 0
+}
+
+func (x *DriverStats) GetCancellationRate() float64 {
+	if x != nil {
+		return x.CancellationRate
+	}
+	return 0
+}
+
+func (x *DriverStats) GetAverageResponseSeconds() float64 {
+	if x != nil {
+		return x.AverageResponseSeconds
+	}
+	return 0
+}
+
+type DestinationPreferenceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DriverID      string                 `protobuf:"bytes,1,opt,name=driverID,proto3" json:"driverID,omitempty"`
+	Enabled       bool                   `protobuf:"varint,2,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	Destination   *Location              `protobuf:"bytes,3,opt,name=destination,proto3" json:"destination,omitempty"`
+	MaxDetourKm   float64                `protobuf:"fixed64,4,opt,name=maxDetourKm,proto3" json:"maxDetourKm,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DestinationPreferenceRequest) Reset() {
+	*x = DestinationPreferenceRequest{}
+	mi := &file_driver_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DestinationPreferenceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DestinationPreferenceRequest) ProtoMessage() {}
+
+func (x *DestinationPreferenceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DestinationPreferenceRequest.ProtoReflect.Descriptor instead.
+func (*DestinationPreferenceRequest) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *DestinationPreferenceRequest) GetDriverID() string {
+	if x != nil {
+		return x.DriverID
+	}
+	return ""
+}
+
+func (x *DestinationPreferenceRequest) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+func (x *DestinationPreferenceRequest) GetDestination() *Location {
+	if x != nil {
+		return x.Destination
+	}
+	return nil
+}
+
+func (x *DestinationPreferenceRequest) GetMaxDetourKm() float64 {
+	if x != nil {
+		return x.MaxDetourKm
+	}
+	return 0
+}
+
+type DestinationPreferenceResponse struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Enabled            bool                   `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	Destination        *Location              `protobuf:"bytes,2,opt,name=destination,proto3" json:"destination,omitempty"`
+	MaxDetourKm        float64                `protobuf:"fixed64,3,opt,name=maxDetourKm,proto3" json:"maxDetourKm,omitempty"`
+	RemainingUsesToday int32                  `protobuf:"varint,4,opt,name=remainingUsesToday,proto3" json:"remainingUsesToday,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *DestinationPreferenceResponse) Reset() {
+	*x = DestinationPreferenceResponse{}
+	mi := &file_driver_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DestinationPreferenceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DestinationPreferenceResponse) ProtoMessage() {}
+
+func (x *DestinationPreferenceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DestinationPreferenceResponse.ProtoReflect.Descriptor instead.
+func (*DestinationPreferenceResponse) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *DestinationPreferenceResponse) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+func (x *DestinationPreferenceResponse) GetDestination() *Location {
+	if x != nil {
+		return x.Destination
+	}
+	return nil
+}
+
+func (x *DestinationPreferenceResponse) GetMaxDetourKm() float64 {
+	if x != nil {
+		return x.MaxDetourKm
+	}
+	return 0
+}
+
+func (x *DestinationPreferenceResponse) GetRemainingUsesToday() int32 {
+	if x != nil {
+		return x.RemainingUsesToday
+	}
+	return 0
+}
+
 type Driver struct {
 	state          protoimpl.MessageState `protogen:"open.v1"`
 	Id             string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
@@ -126,13 +930,14 @@ type Driver struct {
 	Geohash        string                 `protobuf:"bytes,5,opt,name=geohash,proto3" json:"geohash,omitempty"`
 	PackageSlug    string                 `protobuf:"bytes,6,opt,name=packageSlug,proto3" json:"packageSlug,omitempty"`
 	Location       *Location              `protobuf:"bytes,7,opt,name=location,proto3" json:"location,omitempty"`
+	LastSeenAt     int64                  `protobuf:"varint,8,opt,name=lastSeenAt,proto3" json:"lastSeenAt,omitempty"`
 	unknownFields  protoimpl.UnknownFields
 	sizeCache      protoimpl.SizeCache
 }
 
 func (x *Driver) Reset() {
 	*x = Driver{}
-	mi := &file_driver_proto_msgTypes[2]
+	mi := &file_driver_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -144,7 +949,7 @@ func (x *Driver) String() string {
 func (*Driver) ProtoMessage() {}
 
 func (x *Driver) ProtoReflect() protoreflect.Message {
-	mi := &file_driver_proto_msgTypes[2]
+	mi := &file_driver_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -157,7 +962,7 @@ func (x *Driver) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Driver.ProtoReflect.Descriptor instead.
 func (*Driver) Descriptor() ([]byte, []int) {
-	return file_driver_proto_rawDescGZIP(), []int{2}
+	return file_driver_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *Driver) GetId() string {
@@ -209,6 +1014,13 @@ func (x *Driver) GetLocation() *Location {
 	return nil
 }
 
+func (x *Driver) GetLastSeenAt() int64 {
+	if x != nil {
+		return x.LastSeenAt
+	}
+	return 0
+}
+
 type Location struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Latitude      float64                `protobuf:"fixed64,1,opt,name=latitude,proto3" json:"latitude,omitempty"`
@@ -219,7 +1031,7 @@ type Location struct {
 
 func (x *Location) Reset() {
 	*x = Location{}
-	mi := &file_driver_proto_msgTypes[3]
+	mi := &file_driver_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -231,7 +1043,7 @@ func (x *Location) String() string {
 func (*Location) ProtoMessage() {}
 
 func (x *Location) ProtoReflect() protoreflect.Message {
-	mi := &file_driver_proto_msgTypes[3]
+	mi := &file_driver_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -244,7 +1056,7 @@ func (x *Location) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Location.ProtoReflect.Descriptor instead.
 func (*Location) Descriptor() ([]byte, []int) {
-	return file_driver_proto_rawDescGZIP(), []int{3}
+	return file_driver_proto_rawDescGZIP(), []int{16}
 }
 
 func (x *Location) GetLatitude() float64 {
@@ -261,6 +1073,122 @@ func (x *Location) GetLongitude() float64 {
 	return 0
 }
 
+// DriverTripResponse is the event of a driver accepting, declining or
+// cancelling a trip, published as protobuf.
+type DriverTripResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Driver        *Driver                `protobuf:"bytes,1,opt,name=driver,proto3" json:"driver,omitempty"`
+	TripID        string                 `protobuf:"bytes,2,opt,name=tripID,proto3" json:"tripID,omitempty"`
+	RiderID       string                 `protobuf:"bytes,3,opt,name=riderID,proto3" json:"riderID,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DriverTripResponse) Reset() {
+	*x = DriverTripResponse{}
+	mi := &file_driver_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DriverTripResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DriverTripResponse) ProtoMessage() {}
+
+func (x *DriverTripResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DriverTripResponse.ProtoReflect.Descriptor instead.
+func (*DriverTripResponse) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *DriverTripResponse) GetDriver() *Driver {
+	if x != nil {
+		return x.Driver
+	}
+	return nil
+}
+
+func (x *DriverTripResponse) GetTripID() string {
+	if x != nil {
+		return x.TripID
+	}
+	return ""
+}
+
+func (x *DriverTripResponse) GetRiderID() string {
+	if x != nil {
+		return x.RiderID
+	}
+	return ""
+}
+
+// DriverLocationUpdate is the event of a driver reporting its location,
+// published as protobuf.
+type DriverLocationUpdate struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Location      *Location              `protobuf:"bytes,1,opt,name=location,proto3" json:"location,omitempty"`
+	Geohash       string                 `protobuf:"bytes,2,opt,name=geohash,proto3" json:"geohash,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DriverLocationUpdate) Reset() {
+	*x = DriverLocationUpdate{}
+	mi := &file_driver_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DriverLocationUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DriverLocationUpdate) ProtoMessage() {}
+
+func (x *DriverLocationUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DriverLocationUpdate.ProtoReflect.Descriptor instead.
+func (*DriverLocationUpdate) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *DriverLocationUpdate) GetLocation() *Location {
+	if x != nil {
+		return x.Location
+	}
+	return nil
+}
+
+func (x *DriverLocationUpdate) GetGeohash() string {
+	if x != nil {
+		return x.Geohash
+	}
+	return ""
+}
+
 var File_driver_proto protoreflect.FileDescriptor
 
 const file_driver_proto_rawDesc = "" +
@@ -270,7 +1198,65 @@ const file_driver_proto_rawDesc = "" +
 	"\bdriverID\x18\x01 \x01(\tR\bdriverID\x12 \n" +
 	"\vpackageSlug\x18\x02 \x01(\tR\vpackageSlug\"@\n" +
 	"\x16RegisterDriverResponse\x12&\n" +
-	"\x06driver\x18\x01 \x01(\v2\x0e.driver.DriverR\x06driver\"\xda\x01\n" +
+	"\x06driver\x18\x01 \x01(\v2\x0e.driver.DriverR\x06driver\".\n" +
+	"\x10HeartbeatRequest\x12\x1a\n" +
+	"\bdriverID\x18\x01 \x01(\tR\bdriverID\"3\n" +
+	"\x11HeartbeatResponse\x12\x1e\n" +
+	"\n" +
+	"lastSeenAt\x18\x01 \x01(\x03R\n" +
+	"lastSeenAt\"G\n" +
+	"\x14DriverProfileRequest\x12/\n" +
+	"\aprofile\x18\x01 \x01(\v2\x15.driver.DriverProfileR\aprofile\"5\n" +
+	"\x17GetDriverProfileRequest\x12\x1a\n" +
+	"\bdriverID\x18\x01 \x01(\tR\bdriverID\"H\n" +
+	"\x15DriverProfileResponse\x12/\n" +
+	"\aprofile\x18\x01 \x01(\v2\x15.driver.DriverProfileR\aprofile\"\xd0\x01\n" +
+	"\rDriverProfile\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12&\n" +
+	"\x0eprofilePicture\x18\x03 \x01(\tR\x0eprofilePicture\x12 \n" +
+	"\vphoneNumber\x18\x04 \x01(\tR\vphoneNumber\x12$\n" +
+	"\rlicenseNumber\x18\x05 \x01(\tR\rlicenseNumber\x12+\n" +
+	"\bvehicles\x18\x06 \x03(\v2\x0f.driver.VehicleR\bvehicles\"\xb5\x01\n" +
+	"\aVehicle\x12\x14\n" +
+	"\x05plate\x18\x01 \x01(\tR\x05plate\x12\x12\n" +
+	"\x04make\x18\x02 \x01(\tR\x04make\x12\x14\n" +
+	"\x05model\x18\x03 \x01(\tR\x05model\x12\x14\n" +
+	"\x05color\x18\x04 \x01(\tR\x05color\x12\x14\n" +
+	"\x05seats\x18\x05 \x01(\x05R\x05seats\x12\"\n" +
+	"\fpackageSlugs\x18\x06 \x03(\tR\fpackageSlugs\x12\x1a\n" +
+	"\bapproved\x18\a \x01(\bR\bapproved\"y\n" +
+	"\x11RateDriverRequest\x12\x1a\n" +
+	"\bdriverID\x18\x01 \x01(\tR\bdriverID\x12\x16\n" +
+	"\x06tripID\x18\x02 \x01(\tR\x06tripID\x12\x18\n" +
+	"\ariderID\x18\x03 \x01(\tR\ariderID\x12\x16\n" +
+	"\x06rating\x18\x04 \x01(\x05R\x06rating\"3\n" +
+	"\x15GetDriverStatsRequest\x12\x1a\n" +
+	"\bdriverID\x18\x01 \x01(\tR\bdriverID\"@\n" +
+	"\x13DriverStatsResponse\x12)\n" +
+	"\x05stats\x18\x01 \x01(\v2\x13.driver.DriverStatsR\x05stats\"\xeb\x02\n" +
+	"\vDriverStats\x12\x1a\n" +
+	"\bdriverID\x18\x01 \x01(\tR\bdriverID\x12$\n" +
+	"\raverageRating\x18\x02 \x01(\x01R\raverageRating\x12 \n" +
+	"\vratingCount\x18\x03 \x01(\x05R\vratingCount\x12\x16\n" +
+	"\x06offers\x18\x04 \x01(\x05R\x06offers\x12\x1a\n" +
+	"\baccepted\x18\x05 \x01(\x05R\baccepted\x12\x1a\n" +
+	"\bdeclined\x18\x06 \x01(\x05R\bdeclined\x12\x1c\n" +
+	"\tcancelled\x18\a \x01(\x05R\tcancelled\x12&\n" +
+	"\x0eacceptanceRate\x18\b \x01(\x01R\x0eacceptanceRate\x12*\n" +
+	"\x10cancellationRate\x18\t \x01(\x01R\x10cancellationRate\x126\n" +
+	"\x16averageResponseSeconds\x18\n" +
+	" \x01(\x01R\x16averageResponseSeconds\"\xaa\x01\n" +
+	"\x1cDestinationPreferenceRequest\x12\x1a\n" +
+	"\bdriverID\x18\x01 \x01(\tR\bdriverID\x12\x18\n" +
+	"\aenabled\x18\x02 \x01(\bR\aenabled\x122\n" +
+	"\vdestination\x18\x03 \x01(\v2\x10.driver.LocationR\vdestination\x12 \n" +
+	"\vmaxDetourKm\x18\x04 \x01(\x01R\vmaxDetourKm\"\xbf\x01\n" +
+	"\x1dDestinationPreferenceResponse\x12\x18\n" +
+	"\aenabled\x18\x01 \x01(\bR\aenabled\x122\n" +
+	"\vdestination\x18\x02 \x01(\v2\x10.driver.LocationR\vdestination\x12 \n" +
+	"\vmaxDetourKm\x18\x03 \x01(\x01R\vmaxDetourKm\x12.\n" +
+	"\x12remainingUsesToday\x18\x04 \x01(\x05R\x12remainingUsesToday\"\xfa\x01\n" +
 	"\x06Driver\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
 	"\x04name\x18\x02 \x01(\tR\x04name\x12&\n" +
@@ -278,13 +1264,32 @@ const file_driver_proto_rawDesc = "" +
 	"\bcarPlate\x18\x04 \x01(\tR\bcarPlate\x12\x18\n" +
 	"\ageohash\x18\x05 \x01(\tR\ageohash\x12 \n" +
 	"\vpackageSlug\x18\x06 \x01(\tR\vpackageSlug\x12,\n" +
-	"\blocation\x18\a \x01(\v2\x10.driver.LocationR\blocation\"D\n" +
+	"\blocation\x18\a \x01(\v2\x10.driver.LocationR\blocation\x12\x1e\n" +
+	"\n" +
+	"lastSeenAt\x18\b \x01(\x03R\n" +
+	"lastSeenAt\"D\n" +
 	"\bLocation\x12\x1a\n" +
 	"\blatitude\x18\x01 \x01(\x01R\blatitude\x12\x1c\n" +
-	"\tlongitude\x18\x02 \x01(\x01R\tlongitude2\xb3\x01\n" +
+	"\tlongitude\x18\x02 \x01(\x01R\tlongitude\"n\n" +
+	"\x12DriverTripResponse\x12&\n" +
+	"\x06driver\x18\x01 \x01(\v2\x0e.driver.DriverR\x06driver\x12\x16\n" +
+	"\x06tripID\x18\x02 \x01(\tR\x06tripID\x12\x18\n" +
+	"\ariderID\x18\x03 \x01(\tR\ariderID\"^\n" +
+	"\x14DriverLocationUpdate\x12,\n" +
+	"\blocation\x18\x01 \x01(\v2\x10.driver.LocationR\blocation\x12\x18\n" +
+	"\ageohash\x18\x02 \x01(\tR\ageohash2\xc5\x06\n" +
 	"\rDriverService\x12O\n" +
 	"\x0eRegisterDriver\x12\x1d.driver.RegisterDriverRequest\x1a\x1e.driver.RegisterDriverResponse\x12Q\n" +
-	"\x10UnregisterDriver\x12\x1d.driver.RegisterDriverRequest\x1a\x1e.driver.RegisterDriverResponseB\x1cZ\x1ashared/proto/driver;driverb\x06proto3"
+	"\x10UnregisterDriver\x12\x1d.driver.RegisterDriverRequest\x1a\x1e.driver.RegisterDriverResponse\x12@\n" +
+	"\tHeartbeat\x12\x18.driver.HeartbeatRequest\x1a\x19.driver.HeartbeatResponse\x12R\n" +
+	"\x13CreateDriverProfile\x12\x1c.driver.DriverProfileRequest\x1a\x1d.driver.DriverProfileResponse\x12R\n" +
+	"\x10GetDriverProfile\x12\x1f.driver.GetDriverProfileRequest\x1a\x1d.driver.DriverProfileResponse\x12R\n" +
+	"\x13UpdateDriverProfile\x12\x1c.driver.DriverProfileRequest\x1a\x1d.driver.DriverProfileResponse\x12U\n" +
+	"\x13DeleteDriverProfile\x12\x1f.driver.GetDriverProfileRequest\x1a\x1d.driver.DriverProfileResponse\x12D\n" +
+	"\n" +
+	"RateDriver\x12\x19.driver.RateDriverRequest\x1a\x1b.driver.DriverStatsResponse\x12L\n" +
+	"\x0eGetDriverStats\x12\x1d.driver.GetDriverStatsRequest\x1a\x1b.driver.DriverStatsResponse\x12g\n" +
+	"\x18SetDestinationPreference\x12$.driver.DestinationPreferenceRequest\x1a%.driver.DestinationPreferenceResponseB\x1cZ\x1ashared/proto/driver;driverb\x06proto3"
 
 var (
 	file_driver_proto_rawDescOnce sync.Once
@@ -298,25 +1303,64 @@ func file_driver_proto_rawDescGZIP() []byte {
 	return file_driver_proto_rawDescData
 }
 
-var file_driver_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_driver_proto_msgTypes = make([]protoimpl.MessageInfo, 19)
 var file_driver_proto_goTypes = []any{
-	(*RegisterDriverRequest)(nil),  // 0: driver.RegisterDriverRequest
-	(*RegisterDriverResponse)(nil), // 1: driver.RegisterDriverResponse
-	(*Driver)(nil),                 // 2: driver.Driver
-	(*Location)(nil),               // 3: driver.Location
+	(*RegisterDriverRequest)(nil),         // 0: driver.RegisterDriverRequest
+	(*RegisterDriverResponse)(nil),        // 1: driver.RegisterDriverResponse
+	(*HeartbeatRequest)(nil),              // 2: driver.HeartbeatRequest
+	(*HeartbeatResponse)(nil),             // 3: driver.HeartbeatResponse
+	(*DriverProfileRequest)(nil),          // 4: driver.DriverProfileRequest
+	(*GetDriverProfileRequest)(nil),       // 5: driver.GetDriverProfileRequest
+	(*DriverProfileResponse)(nil),         // 6: driver.DriverProfileResponse
+	(*DriverProfile)(nil),                 // 7: driver.DriverProfile
+	(*Vehicle)(nil),                       // 8: driver.Vehicle
+	(*RateDriverRequest)(nil),             // 9: driver.RateDriverRequest
+	(*GetDriverStatsRequest)(nil),         // 10: driver.GetDriverStatsRequest
+	(*DriverStatsResponse)(nil),           // 11: driver.DriverStatsResponse
+	(*DriverStats)(nil),                   // 12: driver.DriverStats
+	(*DestinationPreferenceRequest)(nil),  // 13: driver.DestinationPreferenceRequest
+	(*DestinationPreferenceResponse)(nil), // 14: driver.DestinationPreferenceResponse
+	(*Driver)(nil),                        // 15: driver.Driver
+	(*Location)(nil),                      // 16: driver.Location
+	(*DriverTripResponse)(nil),            // 17: driver.DriverTripResponse
+	(*DriverLocationUpdate)(nil),          // 18: driver.DriverLocationUpdate
 }
 var file_driver_proto_depIdxs = []int32{
-	2, // 0: driver.RegisterDriverResponse.driver:type_name -> driver.Driver
-	3, // 1: driver.Driver.location:type_name -> driver.Location
-	0, // 2: driver.DriverService.RegisterDriver:input_type -> driver.RegisterDriverRequest
-	0, // 3: driver.DriverService.UnregisterDriver:input_type -> driver.RegisterDriverRequest
-	1, // 4: driver.DriverService.RegisterDriver:output_type -> driver.RegisterDriverResponse
-	1, // 5: driver.DriverService.UnregisterDriver:output_type -> driver.RegisterDriverResponse
-	4, // [4:6] is the sub-list for method output_type
-	2, // [2:4] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	15, // 0: driver.RegisterDriverResponse.driver:type_name -> driver.Driver
+	7,  // 1: driver.DriverProfileRequest.profile:type_name -> driver.DriverProfile
+	7,  // 2: driver.DriverProfileResponse.profile:type_name -> driver.DriverProfile
+	8,  // 3: driver.DriverProfile.vehicles:type_name -> driver.Vehicle
+	12, // 4: driver.DriverStatsResponse.stats:type_name -> driver.DriverStats
+	16, // 5: driver.DestinationPreferenceRequest.destination:type_name -> driver.Location
+	16, // 6: driver.DestinationPreferenceResponse.destination:type_name -> driver.Location
+	16, // 7: driver.Driver.location:type_name -> driver.Location
+	15, // 8: driver.DriverTripResponse.driver:type_name -> driver.Driver
+	16, // 9: driver.DriverLocationUpdate.location:type_name -> driver.Location
+	0,  // 10: driver.DriverService.RegisterDriver:input_type -> driver.RegisterDriverRequest
+	0,  // 11: driver.DriverService.UnregisterDriver:input_type -> driver.RegisterDriverRequest
+	2,  // 12: driver.DriverService.Heartbeat:input_type -> driver.HeartbeatRequest
+	4,  // 13: driver.DriverService.CreateDriverProfile:input_type -> driver.DriverProfileRequest
+	5,  // 14: driver.DriverService.GetDriverProfile:input_type -> driver.GetDriverProfileRequest
+	4,  // 15: driver.DriverService.UpdateDriverProfile:input_type -> driver.DriverProfileRequest
+	5,  // 16: driver.DriverService.DeleteDriverProfile:input_type -> driver.GetDriverProfileRequest
+	9,  // 17: driver.DriverService.RateDriver:input_type -> driver.RateDriverRequest
+	10, // 18: driver.DriverService.GetDriverStats:input_type -> driver.GetDriverStatsRequest
+	13, // 19: driver.DriverService.SetDestinationPreference:input_type -> driver.DestinationPreferenceRequest
+	1,  // 20: driver.DriverService.RegisterDriver:output_type -> driver.RegisterDriverResponse
+	1,  // 21: driver.DriverService.UnregisterDriver:output_type -> driver.RegisterDriverResponse
+	3,  // 22: driver.DriverService.Heartbeat:output_type -> driver.HeartbeatResponse
+	6,  // 23: driver.DriverService.CreateDriverProfile:output_type -> driver.DriverProfileResponse
+	6,  // 24: driver.DriverService.GetDriverProfile:output_type -> driver.DriverProfileResponse
+	6,  // 25: driver.DriverService.UpdateDriverProfile:output_type -> driver.DriverProfileResponse
+	6,  // 26: driver.DriverService.DeleteDriverProfile:output_type -> driver.DriverProfileResponse
+	11, // 27: driver.DriverService.RateDriver:output_type -> driver.DriverStatsResponse
+	11, // 28: driver.DriverService.GetDriverStats:output_type -> driver.DriverStatsResponse
+	14, // 29: driver.DriverService.SetDestinationPreference:output_type -> driver.DestinationPreferenceResponse
+	20, // [20:30] is the sub-list for method output_type
+	10, // [10:20] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
 }
 
 func init() { file_driver_proto_init() }
@@ -330,7 +1374,7 @@ func file_driver_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_driver_proto_rawDesc), len(file_driver_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   4,
+			NumMessages:   19,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
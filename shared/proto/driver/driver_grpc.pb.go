@@ -19,8 +19,16 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	DriverService_RegisterDriver_FullMethodName   = "/driver.DriverService/RegisterDriver"
-	DriverService_UnregisterDriver_FullMethodName = "/driver.DriverService/UnregisterDriver"
+	DriverService_RegisterDriver_FullMethodName           = "/driver.DriverService/RegisterDriver"
+	DriverService_UnregisterDriver_FullMethodName         = "/driver.DriverService/UnregisterDriver"
+	DriverService_Heartbeat_FullMethodName                = "/driver.DriverService/Heartbeat"
+	DriverService_CreateDriverProfile_FullMethodName      = "/driver.DriverService/CreateDriverProfile"
+	DriverService_GetDriverProfile_FullMethodName         = "/driver.DriverService/GetDriverProfile"
+	DriverService_UpdateDriverProfile_FullMethodName      = "/driver.DriverService/UpdateDriverProfile"
+	DriverService_DeleteDriverProfile_FullMethodName      = "/driver.DriverService/DeleteDriverProfile"
+	DriverService_RateDriver_FullMethodName               = "/driver.DriverService/RateDriver"
+	DriverService_GetDriverStats_FullMethodName           = "/driver.DriverService/GetDriverStats"
+	DriverService_SetDestinationPreference_FullMethodName = "/driver.DriverService/SetDestinationPreference"
 )
 
 // DriverServiceClient is the client API for DriverService service.
@@ -29,6 +37,14 @@ const (
 type DriverServiceClient interface {
 	RegisterDriver(ctx context.Context, in *RegisterDriverRequest, opts ...grpc.CallOption) (*RegisterDriverResponse, error)
 	UnregisterDriver(ctx context.Context, in *RegisterDriverRequest, opts ...grpc.CallOption) (*RegisterDriverResponse, error)
+	Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error)
+	CreateDriverProfile(ctx context.Context, in *DriverProfileRequest, opts ...grpc.CallOption) (*DriverProfileResponse, error)
+	GetDriverProfile(ctx context.Context, in *GetDriverProfileRequest, opts ...grpc.CallOption) (*DriverProfileResponse, error)
+	UpdateDriverProfile(ctx context.Context, in *DriverProfileRequest, opts ...grpc.CallOption) (*DriverProfileResponse, error)
+	DeleteDriverProfile(ctx context.Context, in *GetDriverProfileRequest, opts ...grpc.CallOption) (*DriverProfileResponse, error)
+	RateDriver(ctx context.Context, in *RateDriverRequest, opts ...grpc.CallOption) (*DriverStatsResponse, error)
+	GetDriverStats(ctx context.Context, in *GetDriverStatsRequest, opts ...grpc.CallOption) (*DriverStatsResponse, error)
+	SetDestinationPreference(ctx context.Context, in *DestinationPreferenceRequest, opts ...grpc.CallOption) (*DestinationPreferenceResponse, error)
 }
 
 type driverServiceClient struct {
@@ -59,12 +75,100 @@ func (c *driverServiceClient) UnregisterDriver(ctx context.Context, in *Register
 	return out, nil
 }
 
+func (c *driverServiceClient) Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HeartbeatResponse)
+	err := c.cc.Invoke(ctx, DriverService_Heartbeat_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *driverServiceClient) CreateDriverProfile(ctx context.Context, in *DriverProfileRequest, opts ...grpc.CallOption) (*DriverProfileResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DriverProfileResponse)
+	err := c.cc.Invoke(ctx, DriverService_CreateDriverProfile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *driverServiceClient) GetDriverProfile(ctx context.Context, in *GetDriverProfileRequest, opts ...grpc.CallOption) (*DriverProfileResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DriverProfileResponse)
+	err := c.cc.Invoke(ctx, DriverService_GetDriverProfile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *driverServiceClient) UpdateDriverProfile(ctx context.Context, in *DriverProfileRequest, opts ...grpc.CallOption) (*DriverProfileResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DriverProfileResponse)
+	err := c.cc.Invoke(ctx, DriverService_UpdateDriverProfile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *driverServiceClient) DeleteDriverProfile(ctx context.Context, in *GetDriverProfileRequest, opts ...grpc.CallOption) (*DriverProfileResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DriverProfileResponse)
+	err := c.cc.Invoke(ctx, DriverService_DeleteDriverProfile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *driverServiceClient) RateDriver(ctx context.Context, in *RateDriverRequest, opts ...grpc.CallOption) (*DriverStatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DriverStatsResponse)
+	err := c.cc.Invoke(ctx, DriverService_RateDriver_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *driverServiceClient) GetDriverStats(ctx context.Context, in *GetDriverStatsRequest, opts ...grpc.CallOption) (*DriverStatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DriverStatsResponse)
+	err := c.cc.Invoke(ctx, DriverService_GetDriverStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *driverServiceClient) SetDestinationPreference(ctx context.Context, in *DestinationPreferenceRequest, opts ...grpc.CallOption) (*DestinationPreferenceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DestinationPreferenceResponse)
+	err := c.cc.Invoke(ctx, DriverService_SetDestinationPreference_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // DriverServiceServer is the server API for DriverService service.
 // All implementations must embed UnimplementedDriverServiceServer
 // for forward compatibility.
 type DriverServiceServer interface {
 	RegisterDriver(context.Context, *RegisterDriverRequest) (*RegisterDriverResponse, error)
 	UnregisterDriver(context.Context, *RegisterDriverRequest) (*RegisterDriverResponse, error)
+	Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error)
+	CreateDriverProfile(context.Context, *DriverProfileRequest) (*DriverProfileResponse, error)
+	GetDriverProfile(context.Context, *GetDriverProfileRequest) (*DriverProfileResponse, error)
+	UpdateDriverProfile(context.Context, *DriverProfileRequest) (*DriverProfileResponse, error)
+	DeleteDriverProfile(context.Context, *GetDriverProfileRequest) (*DriverProfileResponse, error)
+	RateDriver(context.Context, *RateDriverRequest) (*DriverStatsResponse, error)
+	GetDriverStats(context.Context, *GetDriverStatsRequest) (*DriverStatsResponse, error)
+	SetDestinationPreference(context.Context, *DestinationPreferenceRequest) (*DestinationPreferenceResponse, error)
 	mustEmbedUnimplementedDriverServiceServer()
 }
 
@@ -81,6 +185,30 @@ func (UnimplementedDriverServiceServer) RegisterDriver(context.Context, *Registe
 func (UnimplementedDriverServiceServer) UnregisterDriver(context.Context, *RegisterDriverRequest) (*RegisterDriverResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UnregisterDriver not implemented")
 }
+func (UnimplementedDriverServiceServer) Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Heartbeat not implemented")
+}
+func (UnimplementedDriverServiceServer) CreateDriverProfile(context.Context, *DriverProfileRequest) (*DriverProfileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateDriverProfile not implemented")
+}
+func (UnimplementedDriverServiceServer) GetDriverProfile(context.Context, *GetDriverProfileRequest) (*DriverProfileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDriverProfile not implemented")
+}
+func (UnimplementedDriverServiceServer) UpdateDriverProfile(context.Context, *DriverProfileRequest) (*DriverProfileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateDriverProfile not implemented")
+}
+func (UnimplementedDriverServiceServer) DeleteDriverProfile(context.Context, *GetDriverProfileRequest) (*DriverProfileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteDriverProfile not implemented")
+}
+func (UnimplementedDriverServiceServer) RateDriver(context.Context, *RateDriverRequest) (*DriverStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RateDriver not implemented")
+}
+func (UnimplementedDriverServiceServer) GetDriverStats(context.Context, *GetDriverStatsRequest) (*DriverStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDriverStats not implemented")
+}
+func (UnimplementedDriverServiceServer) SetDestinationPreference(context.Context, *DestinationPreferenceRequest) (*DestinationPreferenceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetDestinationPreference not implemented")
+}
 func (UnimplementedDriverServiceServer) mustEmbedUnimplementedDriverServiceServer() {}
 func (UnimplementedDriverServiceServer) testEmbeddedByValue()                       {}
 
@@ -138,6 +266,150 @@ func _DriverService_UnregisterDriver_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DriverService_Heartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HeartbeatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DriverServiceServer).Heartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DriverService_Heartbeat_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DriverServiceServer).Heartbeat(ctx, req.(*HeartbeatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DriverService_CreateDriverProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DriverProfileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DriverServiceServer).CreateDriverProfile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DriverService_CreateDriverProfile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DriverServiceServer).CreateDriverProfile(ctx, req.(*DriverProfileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DriverService_GetDriverProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDriverProfileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DriverServiceServer).GetDriverProfile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DriverService_GetDriverProfile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DriverServiceServer).GetDriverProfile(ctx, req.(*GetDriverProfileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DriverService_UpdateDriverProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DriverProfileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DriverServiceServer).UpdateDriverProfile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DriverService_UpdateDriverProfile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DriverServiceServer).UpdateDriverProfile(ctx, req.(*DriverProfileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DriverService_DeleteDriverProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDriverProfileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DriverServiceServer).DeleteDriverProfile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DriverService_DeleteDriverProfile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DriverServiceServer).DeleteDriverProfile(ctx, req.(*GetDriverProfileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DriverService_RateDriver_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RateDriverRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DriverServiceServer).RateDriver(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DriverService_RateDriver_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DriverServiceServer).RateDriver(ctx, req.(*RateDriverRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DriverService_GetDriverStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDriverStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DriverServiceServer).GetDriverStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DriverService_GetDriverStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DriverServiceServer).GetDriverStats(ctx, req.(*GetDriverStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DriverService_SetDestinationPreference_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DestinationPreferenceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DriverServiceServer).SetDestinationPreference(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DriverService_SetDestinationPreference_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DriverServiceServer).SetDestinationPreference(ctx, req.(*DestinationPreferenceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // DriverService_ServiceDesc is the grpc.ServiceDesc for DriverService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -153,6 +425,38 @@ var DriverService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UnregisterDriver",
 			Handler:    _DriverService_UnregisterDriver_Handler,
 		},
+		{
+			MethodName: "Heartbeat",
+			Handler:    _DriverService_Heartbeat_Handler,
+		},
+		{
+			MethodName: "CreateDriverProfile",
+			Handler:    _DriverService_CreateDriverProfile_Handler,
+		},
+		{
+			MethodName: "GetDriverProfile",
+			Handler:    _DriverService_GetDriverProfile_Handler,
+		},
+		{
+			MethodName: "UpdateDriverProfile",
+			Handler:    _DriverService_UpdateDriverProfile_Handler,
+		},
+		{
+			MethodName: "DeleteDriverProfile",
+			Handler:    _DriverService_DeleteDriverProfile_Handler,
+		},
+		{
+			MethodName: "RateDriver",
+			Handler:    _DriverService_RateDriver_Handler,
+		},
+		{
+			MethodName: "GetDriverStats",
+			Handler:    _DriverService_GetDriverStats_Handler,
+		},
+		{
+			MethodName: "SetDestinationPreference",
+			Handler:    _DriverService_SetDestinationPreference_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "driver.proto",
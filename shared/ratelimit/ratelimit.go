@@ -0,0 +1,120 @@
+/*
+Package ratelimit implements token bucket rate limiting over a pluggable store,
+so that replicas can share their buckets.
+*/
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit lets Burst requests through at once, then Rate requests per second.
+// The zero Limit disables limiting.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l Limit) Enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%g/s:%d", l.Rate, l.Burst)
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// time until a token is available again, zero when allowed
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets. Take must be atomic per key: replicas sharing a
+// store (Redis, a database...) must not hand out the same token twice.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// ParseLimit reads a limit written as <count>/<unit>[:<burst>], the unit being
+// s, m or h, e.g. "10/m:5". The burst defaults to count, "off" disables the
+// limit.
+func ParseLimit(value string) (Limit, error) {
+	if value == "off" {
+		return Limit{}, nil
+	}
+
+	rate, burst, hasBurst := strings.Cut(value, ":")
+
+	countValue, unit, ok := strings.Cut(rate, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid limit %q, expected <count>/<unit>[:<burst>]", value)
+	}
+
+	count, err := strconv.Atoi(countValue)
+	if err != nil || count <= 0 {
+		return Limit{}, fmt.Errorf("invalid count in limit %q", value)
+	}
+
+	var per time.Duration
+	switch unit {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		return Limit{}, fmt.Errorf("invalid unit in limit %q", value)
+	}
+
+	limit := Limit{Rate: float64(count) / per.Seconds(), Burst: count}
+	if hasBurst {
+		limit.Burst, err = strconv.Atoi(burst)
+		if err != nil || limit.Burst <= 0 {
+			return Limit{}, fmt.Errorf("invalid burst in limit %q", value)
+		}
+	}
+
+	return limit, nil
+}
+
+// Bucket is the state of a token bucket. Shared stores persist it and apply
+// Take atomically, e.g. with a compare-and-swap on Updated.
+type Bucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+// NewBucket returns a full bucket.
+func NewBucket(limit Limit, now time.Time) Bucket {
+	return Bucket{Tokens: float64(limit.Burst), Updated: now}
+}
+
+// Take refills the bucket for the time elapsed since its last update and
+// takes a token if one is available.
+func (b *Bucket) Take(limit Limit, now time.Time) Result {
+	if elapsed := now.Sub(b.Updated).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(float64(limit.Burst), b.Tokens+elapsed*limit.Rate)
+	}
+	b.Updated = now
+
+	if b.Tokens < 1 {
+		wait := (1 - b.Tokens) / limit.Rate
+		return Result{RetryAfter: time.Duration(wait * float64(time.Second))}
+	}
+
+	b.Tokens--
+	return Result{Allowed: true, Remaining: int(b.Tokens)}
+}
+
+// Full reports whether the bucket has refilled and can be forgotten.
+func (b *Bucket) Full(limit Limit, now time.Time) bool {
+	return b.Tokens+now.Sub(b.Updated).Seconds()*limit.Rate >= float64(limit.Burst)
+}
@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Limit
+		wantErr bool
+	}{
+		{value: "10/s", want: Limit{Rate: 10, Burst: 10}},
+		{value: "60/m", want: Limit{Rate: 1, Burst: 60}},
+		{value: "10/m:5", want: Limit{Rate: 10.0 / 60, Burst: 5}},
+		{value: "3600/h:1", want: Limit{Rate: 1, Burst: 1}},
+		{value: "off", want: Limit{}},
+		{value: "10", wantErr: true},
+		{value: "10/d", wantErr: true},
+		{value: "0/s", wantErr: true},
+		{value: "-1/s", wantErr: true},
+		{value: "ten/s", wantErr: true},
+		{value: "10/s:0", wantErr: true},
+		{value: "10/s:x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLimit(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseLimit(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+
+	if off, _ := ParseLimit("off"); off.Enabled() {
+		t.Error("ParseLimit(off) is enabled")
+	}
+}
+
+func TestBucketTake(t *testing.T) {
+	limit := Limit{Rate: 2, Burst: 3}
+	now := time.Now()
+	bucket := NewBucket(limit, now)
+
+	// the burst goes through at once
+	for i := range 3 {
+		result := bucket.Take(limit, now)
+		if !result.Allowed || result.Remaining != 2-i {
+			t.Fatalf("Take() %d = %+v, want allowed with %d remaining", i+1, result, 2-i)
+		}
+	}
+
+	result := bucket.Take(limit, now)
+	if result.Allowed || result.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("Take() of an empty bucket = %+v, want denied for 500ms", result)
+	}
+
+	// a token every 1/Rate seconds
+	now = now.Add(250 * time.Millisecond)
+	if result := bucket.Take(limit, now); result.Allowed || result.RetryAfter != 250*time.Millisecond {
+		t.Fatalf("Take() after 250ms = %+v, want denied for 250ms", result)
+	}
+	now = now.Add(250 * time.Millisecond)
+	if result := bucket.Take(limit, now); !result.Allowed {
+		t.Fatalf("Take() after 500ms = %+v, want allowed", result)
+	}
+
+	// refilling stops at the burst
+	now = now.Add(time.Hour)
+	if !bucket.Full(limit, now) {
+		t.Error("Full() after an hour = false")
+	}
+	if result := bucket.Take(limit, now); result.Remaining != 2 {
+		t.Errorf("Take() after an hour = %+v, want 2 remaining", result)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	limit := Limit{Rate: 1, Burst: 1}
+	now := time.Now()
+
+	if result, _ := store.Take(ctx, "user:1", limit, now); !result.Allowed {
+		t.Fatal("Take(user:1) = denied")
+	}
+	if result, _ := store.Take(ctx, "user:1", limit, now); result.Allowed {
+		t.Fatal("Take(user:1) again = allowed")
+	}
+	// keys have buckets of their own
+	if result, _ := store.Take(ctx, "user:2", limit, now); !result.Allowed {
+		t.Fatal("Take(user:2) = denied")
+	}
+
+	// buckets that refilled are forgotten
+	now = now.Add(sweepInterval)
+	if result, _ := store.Take(ctx, "user:1", limit, now); !result.Allowed {
+		t.Fatal("Take(user:1) after refilling = denied")
+	}
+	if _, ok := store.buckets["user:2"]; ok {
+		t.Error("full bucket of user:2 kept after a sweep")
+	}
+}
@@ -0,0 +1,51 @@
+/*
+Package tracing sets up OpenTelemetry for the services: the W3C trace context
+is propagated over HTTP, gRPC and AMQP, and spans are exported to Jaeger when
+JAEGER_ENDPOINT is set.
+*/
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"ride-sharing/shared/env"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Init installs the global tracer provider and propagator for serviceName and
+// returns the function flushing the spans left on shutdown. Without a Jaeger
+// endpoint spans are still created, so the trace context keeps flowing
+// through the service, but nothing is exported.
+func Init(serviceName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(serviceName),
+		)),
+	}
+
+	// e.g. http://jaeger:14268/api/traces
+	if endpoint := env.GetString("JAEGER_ENDPOINT", ""); endpoint != "" {
+		exporter, err := jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(endpoint)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the jaeger exporter: %v", err)
+		}
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+
+	provider := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
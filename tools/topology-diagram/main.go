@@ -0,0 +1,206 @@
+// Command topology-diagram prints the messaging topology as a mermaid graph
+// in the style of docs/architecture: the exchanges, queues, event types and
+// services, with the events routed to each queue, the exchanges each service
+// publishes to and the services consuming each queue.
+//
+//	go run ./tools/topology-diagram -dead-letters > docs/architecture/rabbitmq-flow-v2.md
+//	go run ./tools/topology-diagram -service driver-service -dead-letters
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"ride-sharing/shared/messaging"
+)
+
+// names and node IDs of the services in the existing diagrams
+var serviceNodes = map[string][2]string{
+	messaging.TripService:       {"TS", "Trip Service"},
+	messaging.DriverService:     {"DS", "Driver Service"},
+	messaging.APIGatewayService: {"AG", "API Gateway"},
+}
+
+func main() {
+	service := flag.String("service", "", "only draw the part of the topology this service declares")
+	deadLetters := flag.Bool("dead-letters", false, "draw the retry and parking queues")
+	flag.Parse()
+
+	topology := messaging.DefaultTopology
+	if *service != "" {
+		topology = topology.For(*service)
+	}
+
+	writeDiagram(os.Stdout, topology, *deadLetters)
+}
+
+func writeDiagram(w io.Writer, t messaging.Topology, deadLetters bool) {
+	var events, services []string
+	for _, q := range t.Queues {
+		events = appendNew(events, q.RoutingKeys...)
+	}
+	for _, p := range t.Publishers {
+		services = appendNew(services, p.Service)
+		events = appendNew(events, p.RoutingKeys...)
+	}
+	for _, q := range t.Queues {
+		services = appendNew(services, q.Consumers...)
+	}
+
+	queueIDs := make(map[string]string)
+	for i, q := range t.Queues {
+		queueIDs[q.Name] = fmt.Sprintf("Q%d", i+1)
+	}
+
+	fmt.Fprintln(w, "```mermaid")
+	fmt.Fprintln(w, "graph TD")
+
+	fmt.Fprintln(w, "    subgraph Exchanges")
+	for _, e := range t.Exchanges {
+		fmt.Fprintf(w, "        %s[%s]\n", exchangeID(e.Name), exchangeLabel(e))
+	}
+	fmt.Fprintln(w, "    end")
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "    subgraph Queues")
+	for _, q := range t.Queues {
+		fmt.Fprintf(w, "        %s[%s]\n", queueIDs[q.Name], q.Name)
+	}
+	fmt.Fprintln(w, "    end")
+
+	if deadLetters {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "    subgraph Retries[Retry and Parking Queues]")
+		for _, q := range t.Queues {
+			if q.Retry == nil {
+				continue
+			}
+			id := queueIDs[q.Name]
+			fmt.Fprintf(w, "        %sR[%s]\n", id, retryQueuesLabel(q))
+			fmt.Fprintf(w, "        %sP[%s]\n", id, messaging.ParkingQueueName(q.Name))
+		}
+		fmt.Fprintln(w, "    end")
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "    subgraph Events[Event Types]")
+	for i, event := range events {
+		fmt.Fprintf(w, "        E%d[%s]\n", i+1, event)
+	}
+	fmt.Fprintln(w, "    end")
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "    subgraph Services")
+	for _, service := range services {
+		id, label := serviceNode(service)
+		fmt.Fprintf(w, "        %s[%s]\n", id, label)
+	}
+	fmt.Fprintln(w, "    end")
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "    %% Event Flow")
+	for _, q := range t.Queues {
+		for _, key := range q.RoutingKeys {
+			fmt.Fprintf(w, "    E%d --> %s\n", slices.Index(events, key)+1, queueIDs[q.Name])
+		}
+	}
+	for _, q := range t.Queues {
+		// fanout queues receive every message of their exchange
+		if len(q.RoutingKeys) == 0 {
+			fmt.Fprintf(w, "    %s --> %s\n", exchangeID(q.Exchange), queueIDs[q.Name])
+		}
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "    %% Service Interactions")
+	for _, p := range t.Publishers {
+		id, _ := serviceNode(p.Service)
+		fmt.Fprintf(w, "    %s --> %s\n", id, exchangeID(p.Exchange))
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "    %% Queue to Service Flow")
+	for _, q := range t.Queues {
+		for _, consumer := range q.Consumers {
+			id, _ := serviceNode(consumer)
+			fmt.Fprintf(w, "    %s --> %s\n", queueIDs[q.Name], id)
+		}
+	}
+
+	if deadLetters {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "    %% Retries and Dead Letters")
+		dlx := exchangeID(t.DeadLetterExchange)
+		for _, q := range t.Queues {
+			if q.Retry == nil {
+				continue
+			}
+			id := queueIDs[q.Name]
+			fmt.Fprintf(w, "    %s -.->|failed| %sR\n", id, id)
+			fmt.Fprintf(w, "    %sR -.->|TTL| %s\n", id, id)
+			fmt.Fprintf(w, "    %s -.->|%d attempts| %s\n", id, q.Retry.MaxAttempts, dlx)
+			fmt.Fprintf(w, "    %s --> %sP\n", dlx, id)
+		}
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "    style Exchanges fill:#e6b3ff,stroke:#333,stroke-width:2px")
+	fmt.Fprintln(w, "    style Services fill:#80b3ff,stroke:#333,stroke-width:2px")
+	fmt.Fprintln(w, "    style Events fill:#ffb366,stroke:#333,stroke-width:2px")
+	fmt.Fprintln(w, "    style Queues fill:#85e085,stroke:#333,stroke-width:2px")
+	if deadLetters {
+		fmt.Fprintln(w, "    style Retries fill:#ff9999,stroke:#333,stroke-width:2px")
+	}
+	fmt.Fprintln(w, "```")
+}
+
+func appendNew(list []string, values ...string) []string {
+	for _, v := range values {
+		if !slices.Contains(list, v) {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// exchangeID is made of the initials of the exchange, e.g. TE for trip.
+func exchangeID(name string) string {
+	var id strings.Builder
+	for _, word := range strings.Split(name, "_") {
+		if word != "" {
+			id.WriteString(strings.ToUpper(word[:1]))
+		}
+	}
+	return id.String() + "E"
+}
+
+func exchangeLabel(e messaging.ExchangeSpec) string {
+	return fmt.Sprintf("%s Exchange<br/>%s", title(e.Name), e.Kind)
+}
+
+func retryQueuesLabel(q messaging.QueueSpec) string {
+	return fmt.Sprintf("%s .. %d<br/>%v, doubling",
+		messaging.RetryQueueName(q.Name, 1),
+		q.Retry.MaxAttempts-1,
+		q.Retry.BaseDelay,
+	)
+}
+
+func serviceNode(service string) (string, string) {
+	if node, ok := serviceNodes[service]; ok {
+		return node[0], node[1]
+	}
+	return strings.ToUpper(strings.ReplaceAll(service, "-", "_")), title(service)
+}
+
+func title(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' })
+	for i, word := range words {
+		words[i] = strings.ToUpper(word[:1]) + word[1:]
+	}
+	return strings.Join(words, " ")
+}
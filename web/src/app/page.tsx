@@ -11,6 +11,8 @@ import { useState, Suspense } from "react";
 import { useSearchParams, useRouter } from 'next/navigation';
 import { CarPackageSlug } from '../types';
 import { DriverPackageSelector } from '../components/DriverPackageSelector';
+import { LoginForm } from '../components/LoginForm';
+import { loadSession, Session } from '../lib/auth';
 
 // Dynamically import components that use Leaflet
 const DriverMap = dynamic(() => import("../components/DriverMap").then(mod => mod.DriverMap), { ssr: false })
@@ -35,9 +37,16 @@ function HomeContent() {
   const searchParams = useSearchParams()
   const payment = searchParams.get("payment")
   const [packageSlug, setPackageSlug] = useState<CarPackageSlug | null>(null)
+  const [session, setSession] = useState<Session | null>(null)
 
   const handleClick = (userType: "driver" | "rider") => {
     setUserType(userType)
+
+    // reuse the session of the tab when it is for the same role
+    const stored = loadSession()
+    if (stored?.user.role === userType) {
+      setSession(stored)
+    }
   }
 
   if (payment === 'success') {
@@ -93,15 +102,19 @@ function HomeContent() {
         </div>
       )}
 
-      {userType === "driver" && packageSlug && (
-        <DriverMap packageSlug={packageSlug} />
+      {userType !== null && !session && (
+        <LoginForm role={userType} onLogin={setSession} />
+      )}
+
+      {userType === "driver" && session && packageSlug && (
+        <DriverMap packageSlug={packageSlug} session={session} />
       )}
 
-      {userType === "driver" && !packageSlug && (
+      {userType === "driver" && session && !packageSlug && (
         <DriverPackageSelector onSelect={setPackageSlug} />
       )}
 
-      {userType === "rider" && <RiderMap />}
+      {userType === "rider" && session && <RiderMap session={session} />}
     </main>
   );
 }
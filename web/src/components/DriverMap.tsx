@@ -12,6 +12,7 @@ import * as Geohash from 'ngeohash';
 import { RoutingControl } from "./RoutingControl";
 import { DriverCard } from "./DriverCard";
 import { TripEvents } from "../contracts";
+import { Session } from "../lib/auth";
 
 const START_LOCATION: Coordinate = {
   latitude: 37.7749,
@@ -36,9 +37,9 @@ const destinationMarker = new L.Icon({
   iconAnchor: [20, 40], // Anchor point
 });
 
-export const DriverMap = ({ packageSlug }: { packageSlug: CarPackageSlug }) => {
+export const DriverMap = ({ packageSlug, session }: { packageSlug: CarPackageSlug, session: Session }) => {
   const mapRef = useRef<L.Map>(null)
-  const userID = useMemo(() => crypto.randomUUID(), [])
+  const userID = session.user.id
   const [riderLocation, setRiderLocation] = useState<Coordinate>(START_LOCATION)
 
   const driverGeohash = useMemo(() =>
@@ -57,6 +58,7 @@ export const DriverMap = ({ packageSlug }: { packageSlug: CarPackageSlug }) => {
     location: riderLocation,
     geohash: driverGeohash,
     userID,
+    accessToken: session.accessToken,
     packageSlug,
   })
 
@@ -0,0 +1,98 @@
+import { FormEvent, useState } from 'react'
+import { Button } from './ui/button'
+import { UserRole } from '../contracts'
+import { login, Session, signup } from '../lib/auth'
+
+interface LoginFormProps {
+  role: UserRole
+  onLogin: (session: Session) => void
+}
+
+const inputClassName = "w-full rounded-lg border border-gray-200 px-3 py-2 text-sm focus:outline-none focus:ring-2 focus:ring-primary"
+
+export function LoginForm({ role, onLogin }: LoginFormProps) {
+  const [mode, setMode] = useState<"login" | "signup">("login")
+  const [email, setEmail] = useState("")
+  const [password, setPassword] = useState("")
+  const [name, setName] = useState("")
+  const [error, setError] = useState<string | null>(null)
+  const [submitting, setSubmitting] = useState(false)
+
+  const handleSubmit = async (e: FormEvent) => {
+    e.preventDefault()
+    setError(null)
+    setSubmitting(true)
+
+    try {
+      const session = mode === "signup"
+        ? await signup({ email, password, name, role })
+        : await login({ email, password })
+
+      if (session.user.role !== role) {
+        setError(`This account is a ${session.user.role} account`)
+        return
+      }
+
+      onLogin(session)
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Something went wrong")
+    } finally {
+      setSubmitting(false)
+    }
+  }
+
+  return (
+    <div className="flex items-center justify-center min-h-screen">
+      <div className="bg-white w-full h-full sm:h-auto sm:rounded-2xl sm:shadow-lg sm:max-w-md sm:mx-4 p-4 sm:p-6">
+        <h2 className="text-lg sm:text-xl font-semibold mb-2">
+          {mode === "signup" ? `Sign up as a ${role}` : `Log in as a ${role}`}
+        </h2>
+        <p className="text-sm text-gray-500 mb-6">
+          {mode === "signup" ? "Create an account to continue" : "Use the account you signed up with"}
+        </p>
+        <form className="space-y-3" onSubmit={handleSubmit}>
+          {mode === "signup" && (
+            <input
+              className={inputClassName}
+              placeholder="Name"
+              value={name}
+              onChange={(e) => setName(e.target.value)}
+            />
+          )}
+          <input
+            className={inputClassName}
+            type="email"
+            placeholder="Email"
+            autoComplete="email"
+            required
+            value={email}
+            onChange={(e) => setEmail(e.target.value)}
+          />
+          <input
+            className={inputClassName}
+            type="password"
+            placeholder="Password"
+            autoComplete={mode === "signup" ? "new-password" : "current-password"}
+            minLength={mode === "signup" ? 8 : undefined}
+            required
+            value={password}
+            onChange={(e) => setPassword(e.target.value)}
+          />
+          {error && <p className="text-sm text-red-500">{error}</p>}
+          <Button className="w-full" type="submit" disabled={submitting}>
+            {mode === "signup" ? "Sign up" : "Log in"}
+          </Button>
+        </form>
+        <button
+          className="mt-4 text-sm text-gray-500 hover:text-gray-900"
+          onClick={() => {
+            setMode(mode === "signup" ? "login" : "signup")
+            setError(null)
+          }}
+        >
+          {mode === "signup" ? "Already have an account? Log in" : "No account yet? Sign up"}
+        </button>
+      </div>
+    </div>
+  )
+}
@@ -5,12 +5,12 @@ import { useRiderStreamConnection } from '../hooks/useRiderStreamConnection';
 import { MapContainer, Marker, Popup, Rectangle, TileLayer } from 'react-leaflet'
 import L from 'leaflet';
 import { getGeohashBounds } from '../utils/geohash';
-import { useMemo, useRef, useState } from 'react';
+import { useRef, useState } from 'react';
 import { MapClickHandler } from './MapClickHandler';
 import { Button } from './ui/button';
 import { RouteFare, RequestRideProps, TripPreview, HTTPTripStartResponse } from "../types";
 import { RoutingControl } from "./RoutingControl";
-import { API_URL } from '../constants';
+import { authFetch, Session } from '../lib/auth';
 import { RiderTripOverview } from './RiderTripOverview';
 import { BackendEndpoints, HTTPTripPreviewRequestPayload, HTTPTripPreviewResponse, HTTPTripStartRequestPayload } from '../contracts';
 
@@ -27,15 +27,16 @@ const driverMarker = new L.Icon({
 });
 
 interface RiderMapProps {
+    session: Session;
     onRouteSelected?: (distance: number) => void;
 }
 
-export default function RiderMap({ onRouteSelected }: RiderMapProps) {
+export default function RiderMap({ session, onRouteSelected }: RiderMapProps) {
     const [trip, setTrip] = useState<TripPreview | null>(null)
     const [selectedCarPackage] = useState<RouteFare | null>(null)
     const [destination, setDestination] = useState<[number, number] | null>(null)
     const mapRef = useRef<L.Map>(null)
-    const userID = useMemo(() => crypto.randomUUID(), [])
+    const userID = session.user.id
     const debounceTimeoutRef = useRef<NodeJS.Timeout | null>(null);
 
     const location = {
@@ -50,7 +51,7 @@ export default function RiderMap({ onRouteSelected }: RiderMapProps) {
         assignedDriver,
         paymentSession,
         resetTripStatus
-    } = useRiderStreamConnection(location, userID);
+    } = useRiderStreamConnection(location, userID, session.accessToken);
 
     console.log(tripStatus)
 
@@ -91,7 +92,6 @@ export default function RiderMap({ onRouteSelected }: RiderMapProps) {
     const requestRidePreview = async (props: RequestRideProps): Promise<HTTPTripPreviewResponse> => {
         const { pickup, destination } = props
         const payload = {
-            userID: userID,
             pickup: {
                 latitude: pickup[0],
                 longitude: pickup[1],
@@ -102,7 +102,7 @@ export default function RiderMap({ onRouteSelected }: RiderMapProps) {
             },
         } as HTTPTripPreviewRequestPayload
 
-        const response = await fetch(`${API_URL}${BackendEndpoints.PREVIEW_TRIP}`, {
+        const response = await authFetch(BackendEndpoints.PREVIEW_TRIP, {
             method: 'POST',
             body: JSON.stringify(payload),
         })
@@ -113,7 +113,6 @@ export default function RiderMap({ onRouteSelected }: RiderMapProps) {
     const handleStartTrip = async (fare: RouteFare) => {
         const payload = {
             rideFareID: fare.id,
-            userID: userID,
         } as HTTPTripStartRequestPayload
 
         if (!fare.id) {
@@ -121,7 +120,7 @@ export default function RiderMap({ onRouteSelected }: RiderMapProps) {
             return
         }
 
-        const response = await fetch(`${API_URL}${BackendEndpoints.START_TRIP}`, {
+        const response = await authFetch(BackendEndpoints.START_TRIP, {
             method: 'POST',
             body: JSON.stringify(payload),
         })
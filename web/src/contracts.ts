@@ -3,6 +3,9 @@ import { Coordinate, Driver, Route, RouteFare, Trip } from "./types";
 
 // These are the endpoints the API Gateway must have for the frontend to work correctly
 export enum BackendEndpoints {
+  AUTH_SIGNUP = "/auth/signup",
+  AUTH_LOGIN = "/auth/login",
+  AUTH_REFRESH = "/auth/refresh",
   PREVIEW_TRIP = "/trip/preview",
   START_TRIP = "/trip/start",
   WS_DRIVERS = "/drivers",
@@ -20,6 +23,7 @@ export enum TripEvents {
   DriverTripAccept = "driver.cmd.trip_accept",
   DriverTripDecline = "driver.cmd.trip_decline",
   DriverRegister = "driver.cmd.register",
+  DriverDestination = "driver.cmd.destination",
   PaymentSessionCreated = "payment.event.session_created",
 }
 
@@ -34,7 +38,7 @@ export type ServerWsMessage =
   | NoDriversFoundRequest;
 
 // Messages sent from the client to the server via the websocket
-export type ClientWsMessage = DriverResponseToTripResponse
+export type ClientWsMessage = DriverResponseToTripResponse | DriverDestinationRequest
 
 interface TripCreatedRequest {
   type: TripEvents.Created;
@@ -85,22 +89,95 @@ interface DriverResponseToTripResponse {
   };
 }
 
+// Turns the driver's "heading home" filter on or off
+interface DriverDestinationRequest {
+  type: TripEvents.DriverDestination;
+  data: {
+    enabled: boolean;
+    destination: Coordinate;
+    maxDetourKm: number;
+  };
+}
+
 export interface HTTPTripPreviewResponse {
   route: Route;
   rideFares: RouteFare[];
 }
 
+// The user of the trip calls is taken from the access token
 export interface HTTPTripStartRequestPayload {
   rideFareID: string;
-  userID: string;
 }
 
 export interface HTTPTripPreviewRequestPayload {
-  userID: string;
   pickup: Coordinate;
   destination: Coordinate;
 }
 
+export type UserRole = "rider" | "driver";
+
+export interface HTTPAuthSignupRequestPayload {
+  email: string;
+  password: string;
+  name: string;
+  role: UserRole;
+}
+
+export interface HTTPAuthLoginRequestPayload {
+  email: string;
+  password: string;
+}
+
+export interface HTTPAuthRefreshRequestPayload {
+  refreshToken: string;
+}
+
+export interface HTTPAuthUser {
+  id: string;
+  email: string;
+  name: string;
+  role: UserRole | "admin";
+}
+
+export interface HTTPAuthResponse {
+  accessToken: string;
+  refreshToken: string;
+  tokenType: "Bearer";
+  // lifetime of the access token in seconds
+  expiresIn: number;
+  user: HTTPAuthUser;
+}
+
+// Stable machine-readable codes of HTTPAPIError, also used by websocket "error" messages
+export type APIErrorCode =
+  | "malformed_request"
+  | "invalid_argument"
+  | "unauthenticated"
+  | "permission_denied"
+  | "not_found"
+  | "already_exists"
+  | "failed_precondition"
+  | "resource_exhausted"
+  | "canceled"
+  | "unimplemented"
+  | "unavailable"
+  | "timeout"
+  | "internal";
+
+export interface HTTPAPIError {
+  code: APIErrorCode;
+  message: string;
+  details?: {
+    field: string;
+    message: string;
+  }[];
+}
+
+// Every failed HTTP call answers with this body
+export interface HTTPErrorResponse {
+  error: HTTPAPIError;
+}
+
 export function isValidTripEvent(event: string): event is TripEvents {
   return Object.values(TripEvents).includes(event as TripEvents);
 }
@@ -10,6 +10,8 @@ interface useDriverConnectionProps {
   };
   geohash: string;
   userID: string;
+  // sent as ?token= since browsers cannot set headers on websockets
+  accessToken: string;
   packageSlug: CarPackageSlug;
 }
 
@@ -17,6 +19,7 @@ export const useDriverStreamConnection = ({
   location,
   geohash,
   userID,
+  accessToken,
   packageSlug
 }: useDriverConnectionProps) => {
   const [requestedTrip, setRequestedTrip] = useState<Trip | null>(null)
@@ -28,7 +31,7 @@ export const useDriverStreamConnection = ({
   useEffect(() => {
     if (!userID) return;
 
-    const websocket = new WebSocket(`${WEBSOCKET_URL}${BackendEndpoints.WS_DRIVERS}?userID=${userID}&packageSlug=${packageSlug}`);
+    const websocket = new WebSocket(`${WEBSOCKET_URL}${BackendEndpoints.WS_DRIVERS}?token=${encodeURIComponent(accessToken)}&packageSlug=${packageSlug}`);
     setWs(websocket);
 
     websocket.onopen = () => {
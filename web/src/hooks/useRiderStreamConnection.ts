@@ -4,7 +4,8 @@ import { Trip } from '../types';
 import { Driver, Coordinate } from '../types';
 import { PaymentEventSessionCreatedData, TripEvents, ServerWsMessage, isValidWsMessage, BackendEndpoints } from '../contracts';
 
-export function useRiderStreamConnection(location: Coordinate, userID: string) {
+// The access token is sent as ?token= since browsers cannot set headers on websockets
+export function useRiderStreamConnection(location: Coordinate, userID: string, accessToken: string) {
   const [drivers, setDrivers] = useState<Driver[]>([]);
   const [tripStatus, setTripStatus] = useState<TripEvents | null>(null);
   const [paymentSession, setPaymentSession] = useState<PaymentEventSessionCreatedData | null>(null);
@@ -14,7 +15,7 @@ export function useRiderStreamConnection(location: Coordinate, userID: string) {
   useEffect(() => {
     if (!userID) return;
 
-    const ws = new WebSocket(`${WEBSOCKET_URL}${BackendEndpoints.WS_RIDERS}?userID=${userID}`);
+    const ws = new WebSocket(`${WEBSOCKET_URL}${BackendEndpoints.WS_RIDERS}?token=${encodeURIComponent(accessToken)}`);
 
     ws.onopen = () => {
       // Send initial location
@@ -0,0 +1,124 @@
+import { API_URL } from "../constants";
+import {
+  BackendEndpoints,
+  HTTPAuthLoginRequestPayload,
+  HTTPAuthResponse,
+  HTTPAuthSignupRequestPayload,
+  HTTPAuthUser,
+  HTTPErrorResponse,
+} from "../contracts";
+
+// Kept per tab, so that a rider and a driver can be tested side by side
+const SESSION_KEY = "ride-sharing.session";
+// Refresh the access token a bit before it expires
+const EXPIRY_MARGIN_MS = 30_000;
+
+export interface Session {
+  accessToken: string;
+  refreshToken: string;
+  // epoch milliseconds
+  expiresAt: number;
+  user: HTTPAuthUser;
+}
+
+export class AuthError extends Error {}
+
+export function loadSession(): Session | null {
+  const stored = sessionStorage.getItem(SESSION_KEY);
+  if (!stored) return null;
+
+  try {
+    return JSON.parse(stored) as Session;
+  } catch {
+    return null;
+  }
+}
+
+export function clearSession() {
+  sessionStorage.removeItem(SESSION_KEY);
+}
+
+async function requestSession(endpoint: BackendEndpoints, payload: object): Promise<Session> {
+  const response = await fetch(`${API_URL}${endpoint}`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(payload),
+  });
+
+  if (!response.ok) {
+    const body = await response.json().catch(() => null) as HTTPErrorResponse | null;
+    throw new AuthError(body?.error?.message ?? `Request failed with status ${response.status}`);
+  }
+
+  const { data } = await response.json() as { data: HTTPAuthResponse };
+  const session: Session = {
+    accessToken: data.accessToken,
+    refreshToken: data.refreshToken,
+    expiresAt: Date.now() + data.expiresIn * 1000,
+    user: data.user,
+  };
+
+  sessionStorage.setItem(SESSION_KEY, JSON.stringify(session));
+  return session;
+}
+
+export const signup = (payload: HTTPAuthSignupRequestPayload) => requestSession(BackendEndpoints.AUTH_SIGNUP, payload);
+
+export const login = (payload: HTTPAuthLoginRequestPayload) => requestSession(BackendEndpoints.AUTH_LOGIN, payload);
+
+let refreshing: Promise<Session> | null = null;
+
+// Refresh tokens are rotated and presenting one twice logs the user out of every
+// session, so concurrent callers share the same refresh.
+export function refreshSession(session: Session): Promise<Session> {
+  if (!refreshing) {
+    refreshing = requestSession(BackendEndpoints.AUTH_REFRESH, { refreshToken: session.refreshToken })
+      .catch((err) => {
+        clearSession();
+        throw err;
+      })
+      .finally(() => {
+        refreshing = null;
+      });
+  }
+
+  return refreshing;
+}
+
+// Returns a valid access token, refreshing the session when it is about to expire
+export async function getAccessToken(): Promise<string> {
+  const session = loadSession();
+  if (!session) {
+    throw new AuthError('Not logged in');
+  }
+
+  if (Date.now() < session.expiresAt - EXPIRY_MARGIN_MS) {
+    return session.accessToken;
+  }
+
+  return (await refreshSession(session)).accessToken;
+}
+
+// Calls the API Gateway with the bearer token of the session
+export async function authFetch(endpoint: BackendEndpoints, init: RequestInit = {}): Promise<Response> {
+  const send = (token: string) => {
+    const headers = new Headers(init.headers);
+    headers.set('Content-Type', 'application/json');
+    headers.set('Authorization', `Bearer ${token}`);
+
+    return fetch(`${API_URL}${endpoint}`, { ...init, headers });
+  };
+
+  const response = await send(await getAccessToken());
+  if (response.status !== 401) {
+    return response;
+  }
+
+  // the token may have been signed with a key the gateway rotated out, retry once
+  const session = loadSession();
+  if (!session) {
+    return response;
+  }
+
+  return send((await refreshSession(session)).accessToken);
+}